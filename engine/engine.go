@@ -2,6 +2,7 @@ package engine
 
 import (
 	"path/filepath"
+	"time"
 
 	"github.com/Alwin18/nalarSQL/engine/executor"
 	"github.com/Alwin18/nalarSQL/engine/parser"
@@ -14,32 +15,133 @@ type Engine struct {
 	stor *storage.Store
 	pl   *planner.Planner
 	ex   *executor.Executor
+	sess *executor.Session // of the statements run with ExecSQL
+
+	listeners *listeners // sessions waiting for notifications
 }
 
-// NewEngine opens/creates data dir
-func NewEngine(dataDir string) (*Engine, error) {
-	st, err := storage.NewStore(filepath.Clean(dataDir))
+// Option configures how NewEngine opens the data directory
+type Option func(*storage.Options)
+
+// ReadOnly opens an existing data directory for queries only. Any number
+// of processes can read a directory no process writes to.
+func ReadOnly() Option {
+	return func(o *storage.Options) { o.ReadOnly = true }
+}
+
+// BusyTimeout makes NewEngine wait up to d for other processes to release
+// the data directory instead of failing with storage.ErrLocked at once
+func BusyTimeout(d time.Duration) Option {
+	return func(o *storage.Options) { o.BusyTimeout = d }
+}
+
+// CacheSize sets the memory budget in bytes of the buffer pool that keeps
+// pages of table files in memory; storage.DefaultCacheSize by default
+func CacheSize(bytes int64) Option {
+	return func(o *storage.Options) { o.CacheSize = bytes }
+}
+
+// NewEngine opens/creates data dir. A process opening it for writing has
+// it to itself until Close.
+func NewEngine(dataDir string, opts ...Option) (*Engine, error) {
+	var o storage.Options
+	for _, opt := range opts {
+		opt(&o)
+	}
+	st, err := storage.OpenStore(filepath.Clean(dataDir), o)
 	if err != nil {
 		return nil, err
 	}
-	pl := planner.NewPlanner(st)
 	exec := executor.NewExecutor(st)
-	return &Engine{stor: st, pl: pl, ex: exec}, nil
+	pl := planner.NewPlanner(st, exec)
+	l := newListeners()
+	exec.SetNotifier(l)
+	return &Engine{stor: st, pl: pl, ex: exec, sess: executor.NewSession(), listeners: l}, nil
 }
 
+// Close closes the open sessions and the store
 func (e *Engine) Close() error {
+	e.listeners.mu.Lock()
+	sessions := make([]*Session, 0, len(e.listeners.sessions))
+	for s := range e.listeners.sessions {
+		sessions = append(sessions, s)
+	}
+	e.listeners.mu.Unlock()
+	for _, s := range sessions {
+		s.Close()
+	}
 	return e.stor.Close()
 }
 
-// ExecSQL parses, plans and executes a single SQL statement (MVP)
+// RegisterFunc makes the Go function fn callable from SQL as name. See
+// executor.Executor.RegisterFunc for the supported signatures; deterministic
+// functions with constant arguments are evaluated once at plan time.
+func (e *Engine) RegisterFunc(name string, fn any, deterministic bool) error {
+	return e.ex.RegisterFunc(name, fn, deterministic)
+}
+
+// RegisterAggregate makes an aggregate function available as name; factory
+// returns a fresh state with Step and Done methods for every group
+func (e *Engine) RegisterAggregate(name string, factory any) error {
+	return e.ex.RegisterAggregate(name, factory)
+}
+
+// SetRecursionLimit sets how many rounds a WITH RECURSIVE query may take
+// before it fails; n <= 0 restores executor.DefaultRecursionLimit
+func (e *Engine) SetRecursionLimit(n int) {
+	e.ex.SetRecursionLimit(n)
+}
+
+// SetDistinctMemory sets how many distinct rows SELECT DISTINCT and UNION
+// keep in memory before spilling to temporary files; n <= 0 restores
+// executor.DefaultDistinctMemory
+func (e *Engine) SetDistinctMemory(n int) {
+	e.ex.SetDistinctMemory(n)
+}
+
+// Subscribe streams the row changes made to table, or to every table when
+// table is "", after the change numbered fromLSN; 0 replays the whole
+// change log. See storage.Store.Subscribe.
+func (e *Engine) Subscribe(table string, fromLSN uint64) (*storage.Subscription, error) {
+	return e.stor.Subscribe(table, fromLSN)
+}
+
+// LastLSN returns the number of the last row change, to subscribe from
+// the current state on
+func (e *Engine) LastLSN() uint64 {
+	return e.stor.LastLSN()
+}
+
+// TrimChanges discards the row changes up to and including upTo from the
+// change log once every subscriber has handled them
+func (e *Engine) TrimChanges(upTo uint64) error {
+	return e.stor.TrimChanges(upTo)
+}
+
+// CacheStats returns the hit and miss counters of the buffer pool, to tune
+// its size with CacheSize
+func (e *Engine) CacheStats() storage.CacheStats {
+	return e.stor.CacheStats()
+}
+
+// SetAutoVacuum sets how often table files changed since they were last
+// vacuumed are compacted in the background; d <= 0 turns it off. It runs
+// every storage.DefaultAutoVacuumInterval by default.
+func (e *Engine) SetAutoVacuum(d time.Duration) {
+	e.stor.SetAutoVacuum(d)
+}
+
+// ExecSQL parses, plans and executes a single SQL statement (MVP). The
+// statements run with it share one session apart from those of
+// NewSession; LISTEN needs a session of its own.
 func (e *Engine) ExecSQL(sql string) (any, error) {
+	return e.exec(sql, nil)
+}
+
+func (e *Engine) plan(sql string) (planner.Plan, error) {
 	stmt, err := parser.Parse(sql)
 	if err != nil {
 		return nil, err
 	}
-	plan, err := e.pl.Plan(stmt)
-	if err != nil {
-		return nil, err
-	}
-	return e.ex.Execute(plan)
+	return e.pl.Plan(stmt)
 }
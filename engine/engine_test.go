@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func openTest(t *testing.T) *Engine {
+	t.Helper()
+	e, err := NewEngine(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { e.Close() })
+	return e
+}
+
+func mustExec(t *testing.T, e *Engine, sql string) any {
+	t.Helper()
+	res, err := e.ExecSQL(sql)
+	if err != nil {
+		t.Fatalf("%s: %v", sql, err)
+	}
+	return res
+}
+
+func TestReturningUnaliasedExpressions(t *testing.T) {
+	e := openTest(t)
+	mustExec(t, e, "CREATE TABLE t (id INT PRIMARY KEY)")
+	got := mustExec(t, e, "INSERT INTO t (id) VALUES (5) RETURNING id*2, id+1")
+	want := []map[string]any{{"?column?": int64(10), "?column?_2": int64(6)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestSelectFunctionColumns(t *testing.T) {
+	e := openTest(t)
+	mustExec(t, e, "CREATE TABLE t (id INT PRIMARY KEY, name TEXT)")
+	mustExec(t, e, "INSERT INTO t (id, name) VALUES (1, 'ab')")
+	got := mustExec(t, e, "SELECT UPPER(name), LENGTH(name), LOWER(name), UPPER(name || 'c') FROM t")
+	want := []map[string]any{{"upper": "AB", "length": int64(2), "lower": "ab", "upper_2": "ABC"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestGroupByRejectsUngroupedColumns(t *testing.T) {
+	e := openTest(t)
+	mustExec(t, e, "CREATE TABLE t (id INT PRIMARY KEY, a INT, s TEXT)")
+	mustExec(t, e, "INSERT INTO t (id, a, s) VALUES (1, 1, 'x'), (2, 2, 'x')")
+	for _, sql := range []string{
+		"SELECT a FROM t GROUP BY s",
+		"SELECT s, a + 1 FROM t GROUP BY s",
+		"SELECT s FROM t GROUP BY s HAVING a > 1",
+		"SELECT s FROM t GROUP BY s ORDER BY a",
+		"SELECT a, COUNT(*) FROM t",
+	} {
+		if _, err := e.ExecSQL(sql); err == nil || !strings.Contains(err.Error(), "must appear in the GROUP BY clause") {
+			t.Errorf("%s: err = %v", sql, err)
+		}
+	}
+	got := mustExec(t, e, "SELECT t.s, UPPER(s), SUM(a) AS total FROM t GROUP BY s ORDER BY total")
+	want := []map[string]any{{"s": "x", "upper": "X", "total": int64(3)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
+
+func TestRecursiveUnionDropsDuplicates(t *testing.T) {
+	e := openTest(t)
+	mustExec(t, e, "CREATE TABLE edge (a INT, b INT)")
+	mustExec(t, e, "INSERT INTO edge (a, b) VALUES (1, 2), (2, 3), (3, 1), (1, 2)")
+	// the graph has a cycle, which only UNION stops at
+	got := mustExec(t, e, "WITH RECURSIVE r AS (SELECT 1 AS n UNION SELECT b FROM edge WHERE a IN (SELECT n FROM r)) SELECT n FROM r ORDER BY n")
+	want := []map[string]any{{"n": int64(1)}, {"n": int64(2)}, {"n": int64(3)}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if _, err := e.ExecSQL("WITH RECURSIVE r AS (SELECT 1 AS n UNION ALL SELECT b FROM edge WHERE a IN (SELECT n FROM r)) SELECT n FROM r"); err == nil {
+		t.Fatal("UNION ALL over a cycle finished")
+	}
+}
+
+func TestCurrvalPerSession(t *testing.T) {
+	e := openTest(t)
+	mustExec(t, e, "CREATE SEQUENCE s")
+	a, b := e.NewSession(), e.NewSession()
+	defer a.Close()
+	defer b.Close()
+	if _, err := a.ExecSQL("SELECT nextval('s')"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.ExecSQL("SELECT nextval('s')"); err != nil {
+		t.Fatal(err)
+	}
+	got, err := a.ExecSQL("SELECT currval('s')")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []map[string]any{{"currval": int64(1)}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("currval of the first session = %v, want %v", got, want)
+	}
+	if _, err := e.ExecSQL("SELECT currval('s')"); err == nil {
+		t.Fatal("currval defined in a session that never called nextval")
+	}
+}
+
+func TestTriggerErrorUndoesStatement(t *testing.T) {
+	e := openTest(t)
+	mustExec(t, e, "CREATE TABLE p (id INT PRIMARY KEY)")
+	mustExec(t, e, "CREATE TABLE log (id INT PRIMARY KEY)")
+	mustExec(t, e, "INSERT INTO log (id) VALUES (1)")
+	mustExec(t, e, "CREATE TRIGGER p_log AFTER INSERT ON p FOR EACH ROW BEGIN INSERT INTO log (id) VALUES (NEW.id); END")
+	mustExec(t, e, "INSERT INTO p (id) VALUES (2)")
+
+	// the trigger hits the duplicate key after the row went into p
+	lsn := e.LastLSN()
+	if _, err := e.ExecSQL("INSERT INTO p (id) VALUES (1)"); err == nil {
+		t.Fatal("insert succeeded despite the failing trigger")
+	}
+	if e.LastLSN() != lsn {
+		t.Fatalf("the failed insert logged changes up to LSN %d", e.LastLSN())
+	}
+	if got, want := mustExec(t, e, "SELECT id FROM p ORDER BY id"), []map[string]any{{"id": int64(2)}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("p = %v, want %v", got, want)
+	}
+	if got, want := mustExec(t, e, "SELECT id FROM log ORDER BY id"), []map[string]any{{"id": int64(1)}, {"id": int64(2)}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("log = %v, want %v", got, want)
+	}
+
+	// a trigger firing itself until it runs too deep
+	mustExec(t, e, "CREATE TABLE r (n INT)")
+	mustExec(t, e, "CREATE TRIGGER r_again AFTER INSERT ON r FOR EACH ROW BEGIN INSERT INTO r (n) VALUES (NEW.n + 1); END")
+	if _, err := e.ExecSQL("INSERT INTO r (n) VALUES (1)"); err == nil {
+		t.Fatal("endless trigger recursion succeeded")
+	}
+	if got := mustExec(t, e, "SELECT COUNT(*) FROM r"); !reflect.DeepEqual(got, []map[string]any{{"count": int64(0)}}) {
+		t.Fatalf("r holds %v rows after the failed insert", got)
+	}
+	mustExec(t, e, "INSERT INTO p (id) VALUES (3)")
+}
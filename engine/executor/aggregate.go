@@ -0,0 +1,366 @@
+package executor
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// aggregator accumulates the rows of one group
+type aggregator interface {
+	Step(args []any) error
+	Done() (any, error)
+}
+
+// aggregateFunc is an entry of the aggregate registry
+type aggregateFunc struct {
+	minArgs int
+	maxArgs int  // -1 for variadic
+	star    bool // accepts (*)
+	new     func() aggregator
+}
+
+// builtinAggregates holds the aggregates available to every executor
+var builtinAggregates = map[string]aggregateFunc{
+	"COUNT": {minArgs: 1, maxArgs: 1, star: true, new: func() aggregator { return &countAgg{} }},
+	"SUM":   {minArgs: 1, maxArgs: 1, new: func() aggregator { return &sumAgg{} }},
+	"AVG":   {minArgs: 1, maxArgs: 1, new: func() aggregator { return &avgAgg{} }},
+	"MIN":   {minArgs: 1, maxArgs: 1, new: func() aggregator { return &extremeAgg{want: -1} }},
+	"MAX":   {minArgs: 1, maxArgs: 1, new: func() aggregator { return &extremeAgg{want: 1} }},
+}
+
+// countAgg counts non-NULL arguments, or rows for COUNT(*)
+type countAgg struct{ n int64 }
+
+func (a *countAgg) Step(args []any) error {
+	if len(args) == 0 || args[0] != nil {
+		a.n++
+	}
+	return nil
+}
+
+func (a *countAgg) Done() (any, error) { return a.n, nil }
+
+// sumAgg adds with the + operator, so integer input gives an integer sum
+type sumAgg struct{ sum any }
+
+func (a *sumAgg) Step(args []any) error {
+	switch {
+	case args[0] == nil:
+		return nil
+	case a.sum == nil:
+		if _, ok := toFloat(args[0]); !ok {
+			if _, ok := args[0].(Interval); !ok {
+				return fmt.Errorf("cannot sum %s", typeName(args[0]))
+			}
+		}
+		a.sum = args[0]
+		return nil
+	}
+	var err error
+	a.sum, err = arithmetic("+", a.sum, args[0])
+	return err
+}
+
+func (a *sumAgg) Done() (any, error) { return a.sum, nil }
+
+type avgAgg struct {
+	sum float64
+	n   int64
+}
+
+func (a *avgAgg) Step(args []any) error {
+	if args[0] == nil {
+		return nil
+	}
+	f, err := numberArg(args, 0)
+	if err != nil {
+		return err
+	}
+	a.sum += f
+	a.n++
+	return nil
+}
+
+func (a *avgAgg) Done() (any, error) {
+	if a.n == 0 {
+		return nil, nil
+	}
+	return a.sum / float64(a.n), nil
+}
+
+// extremeAgg keeps the smallest (want -1) or largest (want 1) value
+type extremeAgg struct {
+	want int
+	best any
+}
+
+func (a *extremeAgg) Step(args []any) error {
+	if args[0] == nil {
+		return nil
+	}
+	if a.best == nil {
+		a.best = args[0]
+		return nil
+	}
+	c, err := compare(args[0], a.best)
+	if err != nil {
+		return err
+	}
+	if c == a.want {
+		a.best = args[0]
+	}
+	return nil
+}
+
+func (a *extremeAgg) Done() (any, error) { return a.best, nil }
+
+// distinctAgg feeds every distinct argument list to the aggregate once,
+// for calls like COUNT(DISTINCT x)
+type distinctAgg struct {
+	agg  aggregator
+	seen map[string]bool
+}
+
+func (a *distinctAgg) Step(args []any) error {
+	k := groupKey(args)
+	if a.seen[k] {
+		return nil
+	}
+	a.seen[k] = true
+	return a.agg.Step(args)
+}
+
+func (a *distinctAgg) Done() (any, error) { return a.agg.Done() }
+
+// aggregateCalls collects the aggregate calls of the select list, HAVING
+// and ORDER BY; arguments of an aggregate are not searched, nesting is reported
+// when they are evaluated
+func (e *Executor) aggregateCalls(stmt *parser.SelectStmt) []*parser.FuncCall {
+	var calls []*parser.FuncCall
+	var walk func(x parser.Expr)
+	walk = func(x parser.Expr) {
+		// a window call is computed after grouping, but its arguments may
+		// contain aggregates
+		if n, ok := x.(*parser.FuncCall); ok && n.Over == nil {
+			if _, ok := e.funcs.aggregate(n.Name); ok {
+				calls = append(calls, n)
+				return
+			}
+		}
+		for _, sub := range parser.Subexprs(x) {
+			walk(*sub)
+		}
+	}
+	for _, it := range stmt.Items {
+		if !it.Star {
+			walk(it.Expr)
+		}
+	}
+	if stmt.Having != nil {
+		walk(stmt.Having)
+	}
+	for _, it := range stmt.OrderBy {
+		if it.Column == 0 {
+			walk(it.Expr)
+		}
+	}
+	return calls
+}
+
+// checkAggregateCall validates the argument count of a call of f
+func checkAggregateCall(c *parser.FuncCall, f aggregateFunc) error {
+	name := strings.ToLower(c.Name)
+	if c.Star && !f.star {
+		return fmt.Errorf("function %s(*) does not exist", name)
+	}
+	if n := len(c.Args); !c.Star && (n < f.minArgs || (f.maxArgs >= 0 && n > f.maxArgs)) {
+		return fmt.Errorf("function %s: wrong number of arguments (%d)", name, n)
+	}
+	return nil
+}
+
+// group is the state of one GROUP BY group
+type group struct {
+	first map[string]any // representative row for non-aggregated columns
+	aggs  []aggregator
+}
+
+// aggregateRows groups rows by the GROUP BY expressions, runs every
+// aggregate call over each group and filters groups by HAVING. It returns a
+// representative row and an evaluation environment holding the aggregate
+// results for every group. Without GROUP BY all rows form a single group, which
+// exists even when there are no rows.
+func (e *Executor) aggregateRows(q *query, stmt *parser.SelectStmt, calls []*parser.FuncCall, meta storage.TableMeta, rows []map[string]any, outer *env) ([]map[string]any, []*env, error) {
+	if err := checkGrouped(stmt, calls, meta); err != nil {
+		return nil, nil, err
+	}
+	specs := make([]aggregateFunc, len(calls))
+	for i, c := range calls {
+		f, _ := e.funcs.aggregate(c.Name)
+		if err := checkAggregateCall(c, f); err != nil {
+			return nil, nil, err
+		}
+		specs[i] = f
+	}
+	newGroup := func(first map[string]any) *group {
+		g := &group{first: first, aggs: make([]aggregator, len(specs))}
+		for i, f := range specs {
+			g.aggs[i] = f.new()
+			if calls[i].Distinct {
+				g.aggs[i] = &distinctAgg{agg: g.aggs[i], seen: map[string]bool{}}
+			}
+		}
+		return g
+	}
+
+	groups := map[string]*group{}
+	var order []*group
+	for _, row := range rows {
+		en := q.env(meta.Name, row, outer)
+		keys, err := e.evalList(stmt.GroupBy, en)
+		if err != nil {
+			return nil, nil, err
+		}
+		k := groupKey(keys)
+		g, ok := groups[k]
+		if !ok {
+			g = newGroup(row)
+			groups[k] = g
+			order = append(order, g)
+		}
+		for i, c := range calls {
+			args, err := e.evalList(c.Args, en)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := g.aggs[i].Step(args); err != nil {
+				return nil, nil, fmt.Errorf("function %s: %w", strings.ToLower(c.Name), err)
+			}
+		}
+	}
+	if len(order) == 0 && len(stmt.GroupBy) == 0 {
+		order = append(order, newGroup(map[string]any{}))
+	}
+
+	var kept []map[string]any
+	var envs []*env
+	for _, g := range order {
+		en := q.env(meta.Name, g.first, outer)
+		en.aggs = make(map[*parser.FuncCall]any, len(calls))
+		for i, c := range calls {
+			v, err := g.aggs[i].Done()
+			if err != nil {
+				return nil, nil, fmt.Errorf("function %s: %w", strings.ToLower(c.Name), err)
+			}
+			en.aggs[c] = v
+		}
+		ok, err := e.match(stmt.Having, en)
+		if err != nil {
+			return nil, nil, err
+		}
+		if ok {
+			kept = append(kept, g.first)
+			envs = append(envs, en)
+		}
+	}
+	return kept, envs, nil
+}
+
+// checkGrouped rejects a column of the select list, HAVING or ORDER BY
+// that is outside every aggregate call and GROUP BY expression, since a
+// group has no single value for it
+func checkGrouped(stmt *parser.SelectStmt, calls []*parser.FuncCall, meta storage.TableMeta) error {
+	aggs := make(map[*parser.FuncCall]bool, len(calls))
+	for _, c := range calls {
+		aggs[c] = true
+	}
+	grouped := func(x parser.Expr) bool {
+		for _, g := range stmt.GroupBy {
+			if sameColumn(g, x) || reflect.DeepEqual(g, x) {
+				return true
+			}
+		}
+		return false
+	}
+	var check func(x parser.Expr) error
+	check = func(x parser.Expr) error {
+		if n, ok := x.(*parser.FuncCall); ok && aggs[n] || grouped(x) {
+			return nil
+		}
+		if ref, ok := x.(*parser.ColumnRef); ok && ref.Level == 0 {
+			return fmt.Errorf("column %s must appear in the GROUP BY clause or be used in an aggregate function", ref.Name)
+		}
+		for _, sub := range parser.Subexprs(x) {
+			if err := check(*sub); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, it := range stmt.Items {
+		if !it.Star {
+			if err := check(it.Expr); err != nil {
+				return err
+			}
+			continue
+		}
+		for _, c := range meta.Columns {
+			if err := check(&parser.ColumnRef{Name: c.Name}); err != nil {
+				return err
+			}
+		}
+	}
+	if stmt.Having != nil {
+		if err := check(stmt.Having); err != nil {
+			return err
+		}
+	}
+	for _, it := range stmt.OrderBy {
+		if it.Column == 0 {
+			if err := check(it.Expr); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// sameColumn reports whether x and y reference the same column of the
+// query, written with or without its table name
+func sameColumn(x, y parser.Expr) bool {
+	a, ok1 := x.(*parser.ColumnRef)
+	b, ok2 := y.(*parser.ColumnRef)
+	return ok1 && ok2 && a.Name == b.Name && a.Level == b.Level &&
+		(a.Table == "" || b.Table == "" || a.Table == b.Table)
+}
+
+// groupKey encodes GROUP BY values so that equal values, including NULLs,
+// share a key
+func groupKey(vals []any) string {
+	var b strings.Builder
+	for _, v := range vals {
+		switch x := v.(type) {
+		case nil:
+			b.WriteString("null")
+		case int64:
+			b.WriteString("n:" + strconv.FormatInt(x, 10))
+		case float64:
+			if i, ok := toInt(x); ok {
+				b.WriteString("n:" + strconv.FormatInt(i, 10))
+			} else {
+				b.WriteString("n:" + strconv.FormatFloat(x, 'g', -1, 64))
+			}
+		case string:
+			b.WriteString("s:" + x)
+		default:
+			fmt.Fprintf(&b, "%s:%v", typeName(v), v)
+		}
+		b.WriteByte(0)
+	}
+	return b.String()
+}
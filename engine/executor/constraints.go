@@ -0,0 +1,115 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// storedExpr parses an expression kept in a table header, a column default
+// or CHECK constraint, once per executor
+func (e *Executor) storedExpr(sql string) (parser.Expr, error) {
+	if x, ok := e.stored.Load(sql); ok {
+		return x.(parser.Expr), nil
+	}
+	x, err := parser.ParseExpr(sql)
+	if err != nil {
+		return nil, fmt.Errorf("stored expression %q: %w", sql, err)
+	}
+	e.stored.Store(sql, x)
+	return x, nil
+}
+
+// EvalCheck evaluates a CHECK constraint of meta for row; the storage calls
+// it for every row it writes. Unlike WHERE, a NULL result passes.
+func (e *Executor) EvalCheck(meta *storage.TableMeta, expr string, row map[string]any) (bool, error) {
+	v, err := e.evalStored(meta, expr, row)
+	if err != nil {
+		return false, err
+	}
+	b, ok, err := toBool(v)
+	if err != nil {
+		return false, err
+	}
+	return b || !ok, nil
+}
+
+// EvalGenerated evaluates the expression of a generated column of meta for
+// row; the storage calls it when a row is written or, for virtual
+// columns, read
+func (e *Executor) EvalGenerated(meta *storage.TableMeta, expr string, row map[string]any) (any, error) {
+	return e.evalStored(meta, expr, row)
+}
+
+// evalStored evaluates an expression of a table header for a row of meta
+func (e *Executor) evalStored(meta *storage.TableMeta, expr string, row map[string]any) (any, error) {
+	x, err := e.storedExpr(expr)
+	if err != nil {
+		return nil, err
+	}
+	return e.eval(x, &env{row: row, tables: map[string]map[string]any{meta.Name: row}})
+}
+
+// applyDefaults fills the columns of meta that an INSERT leaves out with
+// their defaults, evaluated for every row
+func (e *Executor) applyDefaults(q *query, meta storage.TableMeta, given []string, rows []map[string]any) error {
+	for _, c := range meta.Columns {
+		if c.Default == "" || slices.Contains(given, c.Name) {
+			continue
+		}
+		x, err := e.storedExpr(c.Default)
+		if err != nil {
+			return err
+		}
+		for _, row := range rows {
+			v, err := e.eval(x, &env{q: q})
+			if err != nil {
+				return fmt.Errorf("default of column %s: %w", c.Name, err)
+			}
+			row[c.Name] = v
+		}
+	}
+	return nil
+}
+
+// tableChecks names the CHECK constraints of a new table. Unnamed ones
+// are called <table>_<column>_check for a column constraint and
+// <table>_check otherwise, numbered when the name is taken.
+func tableChecks(stmt *parser.CreateTableStmt) []storage.CheckConstraint {
+	var checks []storage.CheckConstraint
+	taken := map[string]bool{}
+	for _, c := range stmt.Columns {
+		for _, d := range c.Checks {
+			if d.Name != "" {
+				taken[d.Name] = true
+			}
+		}
+	}
+	for _, d := range stmt.Checks {
+		if d.Name != "" {
+			taken[d.Name] = true
+		}
+	}
+	add := func(d parser.CheckDef, base string) {
+		name := d.Name
+		if name == "" {
+			name = base
+			for i := 1; taken[name]; i++ {
+				name = fmt.Sprintf("%s%d", base, i)
+			}
+		}
+		taken[name] = true
+		checks = append(checks, storage.CheckConstraint{Name: name, Expr: d.SQL})
+	}
+	for _, c := range stmt.Columns {
+		for _, d := range c.Checks {
+			add(d, stmt.TableName+"_"+c.Name+"_check")
+		}
+	}
+	for _, d := range stmt.Checks {
+		add(d, stmt.TableName+"_check")
+	}
+	return checks
+}
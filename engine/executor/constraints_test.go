@@ -0,0 +1,63 @@
+package executor
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+func TestDefaults(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExec(`CREATE TABLE items (
+		id INTEGER PRIMARY KEY,
+		name TEXT NOT NULL DEFAULT 'unnamed',
+		price REAL DEFAULT (1 + 1) * 2,
+		created TIMESTAMP DEFAULT NOW(),
+		note TEXT)`)
+	db.mustExec("INSERT INTO items (id) VALUES (1), (2)")
+	db.mustExec("INSERT INTO items (id, name, price) VALUES (3, 'x', NULL)")
+	db.expect("SELECT id, name, price, note FROM items",
+		row{"id": int64(1), "name": "unnamed", "price": int64(4), "note": nil},
+		row{"id": int64(2), "name": "unnamed", "price": int64(4), "note": nil},
+		row{"id": int64(3), "name": "x", "price": nil, "note": nil})
+	for _, r := range db.mustExec("SELECT created FROM items").([]map[string]any) {
+		if _, ok := r["created"].(storage.Timestamp); !ok {
+			t.Fatalf("created = %#v, want the insert time", r["created"])
+		}
+	}
+	db.expectColumn("SELECT column_default FROM nalar_columns WHERE table_name = 'items' AND column_name = 'name'",
+		"column_default", "'unnamed'")
+
+	db.expectErr("CREATE TABLE bad (a INT, b INT DEFAULT a + 1)")
+}
+
+func TestChecks(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExec(`CREATE TABLE items (
+		id INTEGER PRIMARY KEY,
+		name TEXT DEFAULT 'unnamed',
+		price REAL CHECK (price >= 0),
+		CONSTRAINT cheap CHECK (price < 1000 OR name <> 'unnamed'))`)
+	db.mustExec("INSERT INTO items (id, price) VALUES (1, 5)")
+	db.mustExec("INSERT INTO items (id, price) VALUES (2, NULL)") // NULL passes
+
+	for sql, constraint := range map[string]string{
+		"INSERT INTO items (id, price) VALUES (3, -1)":                                        "items_price_check",
+		"INSERT INTO items (id, price) VALUES (3, 2000)":                                      "cheap",
+		"UPDATE items SET price = price - 10 WHERE id = 1":                                    "items_price_check",
+		"INSERT INTO items (id, price) VALUES (3, 1), (4, -1)":                                "items_price_check",
+		"INSERT INTO items (id, name, price) VALUES (1, 'a', -5) ON CONFLICT (id) DO NOTHING": "items_price_check",
+	} {
+		err := db.expectErr(sql)
+		if !errors.Is(err, storage.ErrConstraintViolation) || !strings.Contains(err.Error(), constraint) {
+			t.Fatalf("%s: %v, want a violation of %s", sql, err, constraint)
+		}
+	}
+	db.mustExec("INSERT INTO items (id, name, price) VALUES (3, 'pricey', 2000)")
+	db.expectColumn("SELECT id FROM items", "id", int64(1), int64(2), int64(3))
+
+	db.expectErr("CREATE TABLE bad (a INT CHECK (a IN (SELECT 1)))")
+	db.expectErr("CREATE TABLE bad (a INT CHECK (b > 0))")
+}
@@ -0,0 +1,113 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+)
+
+// DefaultRecursionLimit is the number of times the recursive term of a
+// WITH RECURSIVE query may run before the query is aborted
+const DefaultRecursionLimit = 1000
+
+// ErrRecursionLimit is returned when a WITH RECURSIVE query does not reach
+// its fixpoint within the recursion limit
+var ErrRecursionLimit = errors.New("recursion limit exceeded")
+
+// SetRecursionLimit changes how many times the recursive term of a WITH
+// RECURSIVE query may run; n <= 0 restores the default
+func (e *Executor) SetRecursionLimit(n int) {
+	if n <= 0 {
+		n = DefaultRecursionLimit
+	}
+	e.recursionLimit.Store(int64(n))
+}
+
+// materialize computes a WITH query and keeps its rows for the statement.
+// A recursive query starts from the rows of its first term and runs the
+// term after UNION [ALL] against the rows produced by the previous round
+// until a round produces none. Under UNION a row equal to one produced
+// before is dropped, so a round that only repeats earlier rows ends the
+// query.
+func (e *Executor) materialize(q *query, cte *parser.CTE, outer *env) error {
+	cols, rows, err := e.selectRows(q, cte.Select, outer)
+	if err != nil {
+		return err
+	}
+	if len(cte.Columns) > 0 {
+		rows = renameColumns(rows, cols, cte.Columns)
+		cols = cte.Columns
+	}
+	var seen map[string]bool
+	if cte.Union != nil && !cte.UnionAll {
+		seen = map[string]bool{}
+		rows = newRows(rows, cols, seen)
+	}
+	all := rows
+	if cte.Union != nil {
+		limit := e.recursionLimit.Load()
+		for round := int64(1); ; round++ {
+			// the recursive term reads the previous round; cached
+			// subquery results may depend on it
+			q.ctes[cte] = &subResult{cols: cols, rows: rows}
+			if cte.Recursive {
+				clear(q.subs)
+			}
+			next, nextRows, err := e.selectRows(q, cte.Union, outer)
+			if err != nil {
+				return err
+			}
+			rows = renameColumns(nextRows, next, cols)
+			if seen != nil {
+				rows = newRows(rows, cols, seen)
+			}
+			all = append(all, rows...)
+			if !cte.Recursive || len(rows) == 0 {
+				break
+			}
+			if round >= limit {
+				return fmt.Errorf("%w: WITH RECURSIVE %s did not finish after %d rounds", ErrRecursionLimit, cte.Name, limit)
+			}
+		}
+	}
+	q.ctes[cte] = &subResult{cols: cols, rows: all}
+	return nil
+}
+
+// newRows returns the rows whose values of cols are not in seen yet and
+// adds them to it
+func newRows(rows []map[string]any, cols []string, seen map[string]bool) []map[string]any {
+	var out []map[string]any
+	vals := make([]any, len(cols))
+	for _, row := range rows {
+		for i, c := range cols {
+			vals[i] = row[c]
+		}
+		if k := groupKey(vals); !seen[k] {
+			seen[k] = true
+			out = append(out, row)
+		}
+	}
+	return out
+}
+
+// renameColumns maps rows keyed by from to rows keyed by to, by position
+func renameColumns(rows []map[string]any, from, to []string) []map[string]any {
+	same := true
+	for i := range from {
+		same = same && from[i] == to[i]
+	}
+	if same {
+		return rows
+	}
+	out := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		m := make(map[string]any, len(to))
+		for j, c := range from {
+			m[to[j]] = row[c]
+		}
+		out[i] = m
+	}
+	return out
+}
@@ -0,0 +1,324 @@
+package executor
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// DefaultDistinctMemory is how many distinct rows SELECT DISTINCT and UNION
+// keep in memory before their hash table spills to temporary files
+const DefaultDistinctMemory = 100000
+
+// spillPartitions is the number of files a spilled hash table is split into
+const spillPartitions = 16
+
+// SetDistinctMemory changes how many distinct rows are kept in memory
+// before deduplication spills to disk; n <= 0 restores the default
+func (e *Executor) SetDistinctMemory(n int) {
+	if n <= 0 {
+		n = DefaultDistinctMemory
+	}
+	e.distinctMemory.Store(int64(n))
+}
+
+// distinctRows removes duplicate rows, comparing the values of cols, and
+// keeps the first of every set of equal rows. envs, when given, holds the
+// evaluation environment of every row and is filtered alongside.
+//
+// Rows go into an in-memory hash table until it holds the distinct memory
+// limit of them. Then the table and all remaining rows are written, whole,
+// to partition files by hash and released from rows, which the call
+// consumes; each partition is deduplicated on its own and the result read
+// back from the files. A spilled result has no envs: DISTINCT and set
+// operations are only sorted by their result columns.
+func (e *Executor) distinctRows(cols []string, rows []map[string]any, envs []*env) ([]map[string]any, []*env, error) {
+	limit := int(e.distinctMemory.Load())
+	seen := make(map[string]bool)
+	var out []map[string]any
+	var pos []int // input position of every row of out
+	var outEnvs []*env
+	var sp *spill
+	vals := make([]any, len(cols))
+	for i, row := range rows {
+		for j, c := range cols {
+			vals[j] = row[c]
+		}
+		k := groupKey(vals)
+		if sp == nil {
+			if seen[k] {
+				continue
+			}
+			if len(seen) < limit {
+				seen[k] = true
+				out, pos = append(out, row), append(pos, i)
+				if envs != nil {
+					outEnvs = append(outEnvs, envs[i])
+				}
+				continue
+			}
+			var err error
+			if sp, err = newSpill(cols); err != nil {
+				return nil, nil, err
+			}
+			defer sp.close()
+			for j, r := range out {
+				if err := sp.add(groupKeyOf(cols, r), pos[j], r); err != nil {
+					return nil, nil, err
+				}
+				rows[pos[j]] = nil
+			}
+			seen, out, pos, outEnvs = nil, nil, nil, nil
+		}
+		if err := sp.add(k, i, row); err != nil {
+			return nil, nil, err
+		}
+		rows[i] = nil
+	}
+	if sp == nil {
+		if out == nil {
+			out = []map[string]any{}
+		}
+		if envs != nil && outEnvs == nil {
+			outEnvs = []*env{}
+		}
+		return out, outEnvs, nil
+	}
+	out, err := sp.distinct()
+	return out, nil, err
+}
+
+func groupKeyOf(cols []string, row map[string]any) string {
+	vals := make([]any, len(cols))
+	for j, c := range cols {
+		vals[j] = row[c]
+	}
+	return groupKey(vals)
+}
+
+// spill is a hash table of rows partitioned over temporary files. Every
+// entry holds the row's input position, its key and its values. Within a
+// partition the first entry of a key has its smallest position, since the
+// in-memory table is written out before any later row.
+type spill struct {
+	cols  []string
+	dir   string
+	files []*os.File
+	bufs  []*bufio.Writer
+}
+
+func newSpill(cols []string) (*spill, error) {
+	dir, err := os.MkdirTemp("", "nalar-distinct-")
+	if err != nil {
+		return nil, fmt.Errorf("distinct spill: %w", err)
+	}
+	s := &spill{cols: cols, dir: dir}
+	for p := 0; p < spillPartitions; p++ {
+		f, err := os.CreateTemp(dir, "part-")
+		if err != nil {
+			s.close()
+			return nil, fmt.Errorf("distinct spill: %w", err)
+		}
+		s.files = append(s.files, f)
+		s.bufs = append(s.bufs, bufio.NewWriter(f))
+	}
+	return s, nil
+}
+
+func (s *spill) add(key string, i int, row map[string]any) error {
+	h := fnv.New32a()
+	h.Write([]byte(key))
+	w := s.bufs[h.Sum32()%spillPartitions]
+	putUvarint(w, uint64(i))
+	putString(w, key)
+	for _, c := range s.cols {
+		if err := putValue(w, row[c]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// distinct reads the partitions back one at a time and returns the first
+// row of every key in input order
+func (s *spill) distinct() ([]map[string]any, error) {
+	type entry struct {
+		pos int
+		row map[string]any
+	}
+	var kept []entry
+	for p, f := range s.files {
+		if err := s.bufs[p].Flush(); err != nil {
+			return nil, fmt.Errorf("distinct spill: %w", err)
+		}
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, fmt.Errorf("distinct spill: %w", err)
+		}
+		r := bufio.NewReader(f)
+		seen := make(map[string]bool)
+		for {
+			i, err := binary.ReadUvarint(r)
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("distinct spill: %w", err)
+			}
+			key, err := readString(r)
+			if err != nil {
+				return nil, fmt.Errorf("distinct spill: %w", err)
+			}
+			row := make(map[string]any, len(s.cols))
+			for _, c := range s.cols {
+				if row[c], err = readValue(r); err != nil {
+					return nil, fmt.Errorf("distinct spill: %w", err)
+				}
+			}
+			if !seen[key] {
+				seen[key] = true
+				kept = append(kept, entry{int(i), row})
+			}
+		}
+	}
+	sort.Slice(kept, func(a, b int) bool { return kept[a].pos < kept[b].pos })
+	out := make([]map[string]any, len(kept))
+	for i, e := range kept {
+		out[i] = e.row
+	}
+	return out, nil
+}
+
+func (s *spill) close() {
+	for _, f := range s.files {
+		f.Close()
+	}
+	os.RemoveAll(s.dir)
+}
+
+// Spilled values are a type tag followed by the value
+const (
+	tagNull byte = iota
+	tagInt64
+	tagInt
+	tagFloat
+	tagString
+	tagBool
+	tagTimestamp
+	tagDate
+	tagInterval
+)
+
+func putUvarint(w *bufio.Writer, n uint64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutUvarint(b[:], n)])
+}
+
+func putVarint(w *bufio.Writer, n int64) {
+	var b [binary.MaxVarintLen64]byte
+	w.Write(b[:binary.PutVarint(b[:], n)])
+}
+
+func putString(w *bufio.Writer, s string) {
+	putUvarint(w, uint64(len(s)))
+	w.WriteString(s)
+}
+
+func putValue(w *bufio.Writer, v any) error {
+	switch x := v.(type) {
+	case nil:
+		w.WriteByte(tagNull)
+	case int64:
+		w.WriteByte(tagInt64)
+		putVarint(w, x)
+	case int:
+		w.WriteByte(tagInt)
+		putVarint(w, int64(x))
+	case float64:
+		w.WriteByte(tagFloat)
+		putUvarint(w, math.Float64bits(x))
+	case string:
+		w.WriteByte(tagString)
+		putString(w, x)
+	case bool:
+		w.WriteByte(tagBool)
+		if x {
+			w.WriteByte(1)
+		} else {
+			w.WriteByte(0)
+		}
+	case storage.Timestamp:
+		w.WriteByte(tagTimestamp)
+		putVarint(w, x.UnixMicro())
+	case storage.Date:
+		w.WriteByte(tagDate)
+		putVarint(w, x.Unix())
+	case Interval:
+		w.WriteByte(tagInterval)
+		putVarint(w, x.Months)
+		putVarint(w, x.Days)
+		putVarint(w, x.Micros)
+	default:
+		return fmt.Errorf("distinct spill: cannot store %s value", typeName(v))
+	}
+	return nil // write errors are kept by w and reported by Flush
+}
+
+func readString(r *bufio.Reader) (string, error) {
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", err
+	}
+	b := make([]byte, n)
+	if _, err := io.ReadFull(r, b); err != nil {
+		return "", err
+	}
+	return string(b), nil
+}
+
+func readValue(r *bufio.Reader) (any, error) {
+	tag, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	switch tag {
+	case tagNull:
+		return nil, nil
+	case tagInt64:
+		return binary.ReadVarint(r)
+	case tagInt:
+		n, err := binary.ReadVarint(r)
+		return int(n), err
+	case tagFloat:
+		bits, err := binary.ReadUvarint(r)
+		return math.Float64frombits(bits), err
+	case tagString:
+		return readString(r)
+	case tagBool:
+		b, err := r.ReadByte()
+		return b == 1, err
+	case tagTimestamp:
+		us, err := binary.ReadVarint(r)
+		return storage.NewTimestamp(time.UnixMicro(us)), err
+	case tagDate:
+		sec, err := binary.ReadVarint(r)
+		return storage.NewDate(time.Unix(sec, 0).UTC()), err
+	case tagInterval:
+		var iv Interval
+		var err error
+		for _, p := range []*int64{&iv.Months, &iv.Days, &iv.Micros} {
+			if *p, err = binary.ReadVarint(r); err != nil {
+				return nil, err
+			}
+		}
+		return iv, nil
+	}
+	return nil, fmt.Errorf("unknown value tag %d", tag)
+}
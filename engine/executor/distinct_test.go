@@ -0,0 +1,54 @@
+package executor
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDistinctSpill(t *testing.T) {
+	tmp := t.TempDir()
+	t.Setenv("TMPDIR", tmp)
+	db := newTestDB(t)
+	db.mustExec("CREATE TABLE t (id INT PRIMARY KEY, n INT, s TEXT, r REAL, b BOOLEAN, d DATE, ts TIMESTAMP)")
+	db.mustExec(`INSERT INTO t (id, n, s, r, b, d, ts) VALUES
+		(1, 1, 'a', 1.5, TRUE, '2024-01-02', '2024-01-02 03:04:05.123456'),
+		(2, 2, NULL, NULL, FALSE, NULL, NULL),
+		(3, 1, 'a', 1.5, TRUE, '2024-01-02', '2024-01-02 03:04:05.123456'),
+		(4, 3, 'c', -2.25, NULL, '1969-12-31', '1960-06-01 00:00:00'),
+		(5, 2, NULL, NULL, FALSE, NULL, NULL),
+		(6, 4, 'd', 0.1, TRUE, '2030-05-06', '2030-05-06 07:08:09'),
+		(7, 3, 'c', -2.25, NULL, '1969-12-31', '1960-06-01 00:00:00')`)
+
+	queries := []string{
+		"SELECT DISTINCT n, s, r, b, d, ts, INTERVAL '1 day' * n AS iv FROM t",
+		"SELECT DISTINCT n, s FROM t ORDER BY s DESC, n",
+		"SELECT n FROM t UNION SELECT n + 1 FROM t",
+		"SELECT DISTINCT n FROM t LIMIT 2 OFFSET 1",
+	}
+	want := make([]any, len(queries))
+	for i, q := range queries {
+		want[i] = db.mustExec(q)
+	}
+	db.ex.SetDistinctMemory(2)
+	for i, q := range queries {
+		if got := db.mustExec(q); !reflect.DeepEqual(got, want[i]) {
+			t.Errorf("%s spilled\ngot  %#v\nwant %#v", q, got, want[i])
+		}
+	}
+	db.ex.SetDistinctMemory(0)
+	if got := db.ex.distinctMemory.Load(); got != DefaultDistinctMemory {
+		t.Fatalf("distinct memory = %d after reset", got)
+	}
+
+	if len(want[0].([]map[string]any)) != 4 {
+		t.Fatalf("distinct rows = %v", want[0])
+	}
+	entries, err := os.ReadDir(tmp)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 0 {
+		t.Fatalf("spill files left in %s: %v", tmp, entries)
+	}
+}
@@ -0,0 +1,241 @@
+package executor
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// env resolves column references while evaluating an expression
+type env struct {
+	row    map[string]any            // unqualified references
+	tables map[string]map[string]any // qualified references, e.g. excluded.col
+	aggs   map[*parser.FuncCall]any  // results of aggregate and window calls
+	q      *query                    // statement being executed, runs subqueries
+	outer  *env                      // enclosing query, for correlated references
+}
+
+func (e *Executor) eval(x parser.Expr, en *env) (any, error) {
+	switch n := x.(type) {
+	case *parser.Literal:
+		return n.Value, nil
+	case *parser.ColumnRef:
+		return en.lookup(n)
+	case *parser.UnaryExpr:
+		v, err := e.eval(n.Expr, en)
+		if err != nil {
+			return nil, err
+		}
+		if n.Op == "NOT" {
+			b, ok, err := toBool(v)
+			if err != nil || !ok {
+				return nil, err
+			}
+			return !b, nil
+		}
+		if iv, ok := v.(Interval); ok {
+			return iv.negate(), nil
+		}
+		return arithmetic("-", int64(0), v)
+	case *parser.BinaryExpr:
+		if n.Op == "AND" || n.Op == "OR" {
+			return e.evalLogical(n, en)
+		}
+		l, err := e.eval(n.Left, en)
+		if err != nil {
+			return nil, err
+		}
+		r, err := e.eval(n.Right, en)
+		if err != nil {
+			return nil, err
+		}
+		switch n.Op {
+		case "||":
+			if l == nil || r == nil {
+				return nil, nil
+			}
+			return toText(l) + toText(r), nil
+		case "+", "-", "*", "/", "%":
+			return arithmetic(n.Op, l, r)
+		case "~", "~*", "!~", "!~*":
+			return regexMatch(n.Op, l, r)
+		default:
+			return comparison(n.Op, l, r)
+		}
+	case *parser.FuncCall:
+		if v, ok := en.aggs[n]; ok {
+			return v, nil
+		}
+		if n.Over != nil {
+			return nil, fmt.Errorf("window function %s is not allowed here", strings.ToLower(n.Name))
+		}
+		if _, ok := windowFuncs[n.Name]; ok {
+			return nil, fmt.Errorf("window function %s requires an OVER clause", strings.ToLower(n.Name))
+		}
+		if _, ok := e.funcs.aggregate(n.Name); ok {
+			return nil, fmt.Errorf("aggregate function %s is not allowed here", strings.ToLower(n.Name))
+		}
+		if n.Star {
+			return nil, fmt.Errorf("function %s(*) does not exist", strings.ToLower(n.Name))
+		}
+		if n.Distinct {
+			return nil, fmt.Errorf("DISTINCT specified, but %s is not an aggregate function", strings.ToLower(n.Name))
+		}
+		args, err := e.evalList(n.Args, en)
+		if err != nil {
+			return nil, err
+		}
+		if _, ok := sequenceFuncs[strings.ToUpper(n.Name)]; ok {
+			return e.callSequenceFunc(n.Name, args, en)
+		}
+		return e.callFunc(n.Name, args)
+	case *parser.CastExpr:
+		v, err := e.eval(n.Expr, en)
+		if err != nil {
+			return nil, err
+		}
+		return castValue(v, n.Type)
+	case *parser.LikeExpr:
+		return e.evalLike(n, en)
+	case *parser.InExpr:
+		return e.evalIn(n, en)
+	case *parser.SubqueryExpr:
+		return e.evalScalarSubquery(n, en)
+	case *parser.ExistsExpr:
+		return e.evalExists(n, en)
+	case *parser.BetweenExpr:
+		return e.evalBetween(n, en)
+	case *parser.IsNullExpr:
+		v, err := e.eval(n.Expr, en)
+		if err != nil {
+			return nil, err
+		}
+		return (v == nil) != n.Not, nil
+	case *parser.CaseExpr:
+		return e.evalCase(n, en)
+	default:
+		return nil, fmt.Errorf("executor: unsupported expression %T", x)
+	}
+}
+
+// evalLogical implements three-valued AND/OR with short-circuiting
+func (e *Executor) evalLogical(n *parser.BinaryExpr, en *env) (any, error) {
+	lv, err := e.eval(n.Left, en)
+	if err != nil {
+		return nil, err
+	}
+	l, lok, err := toBool(lv)
+	if err != nil {
+		return nil, err
+	}
+	if lok && l == (n.Op == "OR") {
+		return l, nil
+	}
+	rv, err := e.eval(n.Right, en)
+	if err != nil {
+		return nil, err
+	}
+	r, rok, err := toBool(rv)
+	if err != nil {
+		return nil, err
+	}
+	if rok && r == (n.Op == "OR") {
+		return r, nil
+	}
+	if !lok || !rok {
+		return nil, nil
+	}
+	return r, nil
+}
+
+// evalList evaluates each expression of xs
+// evalCase evaluates the WHEN arms in order and only the result of the
+// first that applies. A simple CASE compares with =, so a NULL operand
+// matches no arm.
+func (e *Executor) evalCase(n *parser.CaseExpr, en *env) (any, error) {
+	var operand any
+	if n.Operand != nil {
+		var err error
+		if operand, err = e.eval(n.Operand, en); err != nil {
+			return nil, err
+		}
+	}
+	for _, w := range n.Whens {
+		v, err := e.eval(w.Cond, en)
+		if err != nil {
+			return nil, err
+		}
+		if n.Operand != nil {
+			if v, err = comparison("=", operand, v); err != nil {
+				return nil, err
+			}
+		}
+		ok, _, err := toBool(v)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			return e.eval(w.Result, en)
+		}
+	}
+	if n.Else == nil {
+		return nil, nil
+	}
+	return e.eval(n.Else, en)
+}
+
+func (e *Executor) evalList(xs []parser.Expr, en *env) ([]any, error) {
+	vals := make([]any, len(xs))
+	for i, x := range xs {
+		v, err := e.eval(x, en)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	return vals, nil
+}
+
+func (en *env) lookup(ref *parser.ColumnRef) (any, error) {
+	for i := 0; i < ref.Level; i++ {
+		if en = en.outer; en == nil {
+			return nil, fmt.Errorf("column %s cannot be referenced here", ref.Name)
+		}
+	}
+	row := en.row
+	if ref.Table != "" {
+		var ok bool
+		if row, ok = en.tables[ref.Table]; !ok {
+			return nil, fmt.Errorf("missing FROM-clause entry for table %s", ref.Table)
+		}
+	}
+	if row == nil {
+		return nil, fmt.Errorf("column %s cannot be referenced here", ref.Name)
+	}
+	return row[ref.Name], nil
+}
+
+// match evaluates a WHERE condition; NULL counts as false
+func (e *Executor) match(where parser.Expr, en *env) (bool, error) {
+	if where == nil {
+		return true, nil
+	}
+	v, err := e.eval(where, en)
+	if err != nil {
+		return false, err
+	}
+	b, _, err := toBool(v)
+	return b, err
+}
+
+// rowFilter turns a WHERE condition into a storage row filter
+func (e *Executor) rowFilter(q *query, table string, where parser.Expr) storage.RowFilter {
+	if where == nil {
+		return nil
+	}
+	return func(row map[string]any) (bool, error) {
+		return e.match(where, q.env(table, row, q.outer))
+	}
+}
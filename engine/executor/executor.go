@@ -2,60 +2,489 @@ package executor
 
 import (
 	"fmt"
+	"strings"
+	"sync"
+	"sync/atomic"
 
+	"github.com/Alwin18/nalarSQL/engine/parser"
 	"github.com/Alwin18/nalarSQL/engine/planner"
 	"github.com/Alwin18/nalarSQL/engine/storage"
 )
 
 type Executor struct {
-	store *storage.Store
+	store          *storage.Store
+	funcs          *registry
+	recursionLimit atomic.Int64 // see SetRecursionLimit
+	distinctMemory atomic.Int64 // see SetDistinctMemory
+	stored         sync.Map     // parsed defaults and checks, see storedExpr
+	notifier       Notifier     // see SetNotifier
 }
 
 func NewExecutor(store *storage.Store) *Executor {
-	return &Executor{store: store}
+	e := &Executor{store: store, funcs: newRegistry()}
+	e.recursionLimit.Store(DefaultRecursionLimit)
+	e.distinctMemory.Store(DefaultDistinctMemory)
+	store.SetEvaluator(e)
+	return e
 }
 
-func (e *Executor) Execute(plan planner.Plan) (any, error) {
+// EvalConst evaluates an expression that references no columns
+func (e *Executor) EvalConst(x parser.Expr) (any, error) {
+	return e.eval(x, &env{})
+}
+
+// Execute runs a plan inside one storage transaction: a read-only one for
+// queries and a write transaction for everything else, so that subqueries
+// of a mutation read the table state the mutation starts from. VACUUM runs
+// outside of one so that it blocks readers only while swapping files.
+// sess holds the state kept between the statements of a connection; it
+// may be nil, and currval then fails.
+func (e *Executor) Execute(plan planner.Plan, sess *Session) (any, error) {
+	if p, ok := plan.(*planner.PlanVacuum); ok {
+		return e.execVacuum(p.Stmt)
+	}
+	run := e.store.Update
+	switch plan.(type) {
+	case *planner.PlanSelect, *planner.PlanShowTables, *planner.PlanDescribe, *planner.PlanNotify:
+		run = e.store.View
+	}
+	var res any
+	var notes []notification
+	err := run(func(tx *storage.Tx) error {
+		var err error
+		res, err = e.exec(&query{
+			tx:    tx,
+			subs:  map[*parser.SelectStmt]*subResult{},
+			ctes:  map[*parser.CTE]*subResult{},
+			notes: &notes,
+			sess:  sess,
+		}, plan)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	// only a committed transaction notifies its listeners
+	e.deliver(notes)
+	return res, nil
+}
+
+// execVacuum compacts table files and reports the bytes reclaimed for each
+func (e *Executor) execVacuum(stmt *parser.VacuumStmt) (any, error) {
+	results, err := e.store.Vacuum(stmt.Table)
+	if err != nil {
+		return nil, err
+	}
+	rows := make([]map[string]any, 0, len(results))
+	for _, r := range results {
+		rows = append(rows, map[string]any{
+			"table_name":      r.Table,
+			"bytes_before":    r.BytesBefore,
+			"bytes_after":     r.BytesAfter,
+			"bytes_reclaimed": r.Reclaimed(),
+		})
+	}
+	return rows, nil
+}
+
+func (e *Executor) exec(q *query, plan planner.Plan) (any, error) {
 	switch p := plan.(type) {
 	case *planner.PlanCreateTable:
-		cols := make([]storage.ColumnDefinition, len(p.Stmt.Columns))
-		for i, c := range p.Stmt.Columns {
-			cols[i] = storage.ColumnDefinition{Name: c.Name, Type: c.Type}
-		}
-		return nil, e.store.CreateTable(p.Stmt.TableName, cols)
+		return nil, e.execCreateTable(q, p.Stmt)
+	case *planner.PlanCreateIndex:
+		return nil, q.tx.CreateIndex(storage.IndexMeta{
+			Name: p.Stmt.Name, Table: p.Stmt.Table, Columns: p.Stmt.Columns, Unique: p.Stmt.Unique,
+		})
 	case *planner.PlanInsert:
-		row := map[string]any{}
-		for i, c := range p.Stmt.Columns {
-			if i < len(p.Stmt.Values) {
-				row[c] = p.Stmt.Values[i]
-			}
-		}
-		id, err := e.store.AppendRow(p.Stmt.Table, row)
-		if err != nil {
-			return nil, err
-		}
-		return map[string]any{"rowid": id}, nil
+		q.access = p.Access
+		return e.execInsert(q, p.Stmt, p.Triggers)
 	case *planner.PlanSelect:
-		rows, err := e.store.ScanTable(p.Stmt.Table)
+		q.access = p.Access
+		_, rows, err := e.selectRows(q, p.Stmt, q.outer)
 		if err != nil {
 			return nil, err
 		}
-		// naive: ignore projection, return all columns
 		return rows, nil
 	case *planner.PlanUpdate:
-		// apply update: simple where only supports equality on a column (col = value)
-		updated, err := e.store.UpdateRows(p.Stmt.Table, p.Stmt.Set, p.Stmt.WhereColumn, p.Stmt.WhereValue)
+		q.access = p.Access
+		return e.execUpdate(q, p.Stmt, p.Triggers)
+	case *planner.PlanDelete:
+		q.access = p.Access
+		return e.execDelete(q, p.Stmt, p.Triggers)
+	case *planner.PlanCreateView:
+		return nil, e.execCreateView(q, p)
+	case *planner.PlanCreateSequence:
+		return nil, q.tx.CreateSequence(storage.SequenceMeta{
+			Name: p.Stmt.Name, Start: p.Stmt.Start, Increment: p.Stmt.Increment,
+		})
+	case *planner.PlanDropSequence:
+		if _, ok := q.tx.Sequence(p.Stmt.Name); !ok && p.Stmt.IfExists {
+			return nil, nil
+		}
+		return nil, q.tx.DropSequence(p.Stmt.Name)
+	case *planner.PlanCreateTrigger:
+		return nil, q.tx.CreateTrigger(storage.TriggerMeta{
+			Name: p.Stmt.Name, Table: p.Stmt.Table, Timing: p.Stmt.Timing, Event: p.Stmt.Event, Body: p.Stmt.Source,
+		})
+	case *planner.PlanDropTrigger:
+		if _, ok := q.tx.Trigger(p.Stmt.Name); !ok && p.Stmt.IfExists {
+			return nil, nil
+		}
+		return nil, q.tx.DropTrigger(p.Stmt.Name)
+	case *planner.PlanNotify:
+		q.access = p.Access
+		return nil, e.execNotify(q, p.Stmt)
+	case *planner.PlanDropView:
+		if _, ok := q.tx.ViewDef(p.Stmt.Name); !ok && p.Stmt.IfExists {
+			return nil, nil
+		}
+		return nil, q.tx.DropView(p.Stmt.Name)
+	case *planner.PlanRefreshView:
+		return nil, e.execRefreshView(q, p)
+	case *planner.PlanShowTables:
+		rows := make([]map[string]any, 0)
+		for _, t := range q.tx.Tables() {
+			rows = append(rows, map[string]any{"table_name": t.Name})
+		}
+		return rows, nil
+	case *planner.PlanDescribe:
+		t, ok := q.tx.Table(p.Stmt.Table)
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", storage.ErrTableNotFound, p.Stmt.Table)
+		}
+		rows := make([]map[string]any, 0, len(t.Columns))
+		for _, c := range t.Columns {
+			var def, gen any
+			if c.Default != "" {
+				def = c.Default
+			}
+			if c.Generated != "" {
+				gen = "VIRTUAL"
+				if c.Stored {
+					gen = "STORED"
+				}
+			}
+			rows = append(rows, map[string]any{
+				"column_name": c.Name,
+				"data_type":   c.Type,
+				"not_null":    c.NotNull,
+				"primary_key": c.PrimaryKey,
+				"default":     def,
+				"generated":   gen,
+			})
+		}
+		return rows, nil
+	default:
+		return nil, fmt.Errorf("executor: unsupported plan type %T", p)
+	}
+}
+
+// execCreateTable turns column and table constraints into the schema and
+// unique indexes stored by the storage layer
+func (e *Executor) execCreateTable(q *query, stmt *parser.CreateTableStmt) error {
+	cols := make([]storage.ColumnDefinition, len(stmt.Columns))
+	var pk []string
+	for i, c := range stmt.Columns {
+		cols[i] = storage.ColumnDefinition{
+			Name: c.Name, Type: c.Type, NotNull: c.NotNull, PrimaryKey: c.PrimaryKey, Default: c.DefaultSQL,
+			AutoIncrement: c.AutoIncrement, Generated: c.GeneratedSQL, Stored: c.Stored,
+		}
+		if c.AutoIncrement {
+			if t, _ := parser.NormalizeType(c.Type); t != "INTEGER" {
+				return fmt.Errorf("AUTO_INCREMENT column %s must be of an integer type", c.Name)
+			}
+			if c.Default != nil {
+				return fmt.Errorf("multiple default values specified for column %s", c.Name)
+			}
+			cols[i].Default = autoIncrementDefault(stmt.TableName, c.Name)
+		}
+		if c.PrimaryKey {
+			pk = append(pk, c.Name)
+		}
+	}
+	if len(stmt.PrimaryKey) > 0 {
+		if len(pk) > 0 {
+			return fmt.Errorf("multiple primary keys for table %s are not allowed", stmt.TableName)
+		}
+		pk = stmt.PrimaryKey
+		for i := range cols {
+			for _, name := range pk {
+				if cols[i].Name == name {
+					cols[i].PrimaryKey = true
+					cols[i].NotNull = true
+				}
+			}
+		}
+	}
+
+	var indexes []storage.IndexMeta
+	if len(pk) > 0 {
+		indexes = append(indexes, storage.IndexMeta{
+			Name: stmt.TableName + "_pkey", Table: stmt.TableName, Columns: pk, Unique: true,
+		})
+	}
+	uniques := stmt.Uniques
+	for _, c := range stmt.Columns {
+		if c.Unique && !c.PrimaryKey {
+			uniques = append(uniques, []string{c.Name})
+		}
+	}
+	for _, u := range uniques {
+		indexes = append(indexes, storage.IndexMeta{
+			Name: stmt.TableName + "_" + strings.Join(u, "_") + "_key", Table: stmt.TableName, Columns: u, Unique: true,
+		})
+	}
+
+	defs := stmt.ForeignKeys
+	for _, c := range stmt.Columns {
+		if c.References != nil {
+			defs = append(defs, *c.References)
+		}
+	}
+	var fks []storage.ForeignKey
+	for _, d := range defs {
+		fk := storage.ForeignKey{
+			Name: d.Name, Columns: d.Columns, RefTable: d.RefTable, RefColumns: d.RefColumns,
+			OnDelete: d.OnDelete, OnUpdate: d.OnUpdate,
+		}
+		if fk.Name == "" {
+			fk.Name = stmt.TableName + "_" + strings.Join(d.Columns, "_") + "_fkey"
+		}
+		if len(fk.RefColumns) == 0 {
+			// REFERENCES t without columns means the primary key of t
+			ref := cols
+			if d.RefTable != stmt.TableName {
+				meta, ok := q.tx.Table(d.RefTable)
+				if !ok {
+					return fmt.Errorf("%w: %s", storage.ErrTableNotFound, d.RefTable)
+				}
+				ref = meta.Columns
+			}
+			for _, c := range ref {
+				if c.PrimaryKey {
+					fk.RefColumns = append(fk.RefColumns, c.Name)
+				}
+			}
+			if len(fk.RefColumns) == 0 {
+				return fmt.Errorf("there is no primary key for referenced table %s", d.RefTable)
+			}
+		}
+		fks = append(fks, fk)
+	}
+	return q.tx.CreateTableMeta(storage.TableMeta{
+		Name: stmt.TableName, Columns: cols, Indexes: indexes, ForeignKeys: fks, Checks: tableChecks(stmt),
+	})
+}
+
+// execInsert writes all VALUES tuples (or the rows produced by
+// INSERT ... SELECT) with a single storage append
+func (e *Executor) execInsert(q *query, stmt *parser.InsertStmt, trigs planner.Triggers) (any, error) {
+	if err := q.checkChanging(stmt.Table); err != nil {
+		return nil, err
+	}
+	var tuples [][]any
+	for _, exprs := range stmt.Rows {
+		vals := make([]any, len(exprs))
+		for i, x := range exprs {
+			v, err := e.eval(x, &env{q: q, outer: q.outer})
+			if err != nil {
+				return nil, err
+			}
+			vals[i] = v
+		}
+		tuples = append(tuples, vals)
+	}
+	if stmt.Select != nil {
+		cols, rows, err := e.selectRows(q, stmt.Select, q.outer)
 		if err != nil {
 			return nil, err
 		}
-		return map[string]any{"updated": updated}, nil
-	case *planner.PlanDelete:
-		deleted, err := e.store.DeleteRows(p.Stmt.Table, p.Stmt.WhereColumn, p.Stmt.WhereValue)
+		tuples = make([][]any, len(rows))
+		for i, row := range rows {
+			vals := make([]any, len(cols))
+			for j, c := range cols {
+				vals[j] = row[c]
+			}
+			tuples[i] = vals
+		}
+	}
+
+	rows := make([]map[string]any, len(tuples))
+	for i, vals := range tuples {
+		if len(vals) != len(stmt.Columns) {
+			return nil, fmt.Errorf("INSERT has %d columns but %d values", len(stmt.Columns), len(vals))
+		}
+		row := make(map[string]any, len(vals))
+		for j, c := range stmt.Columns {
+			row[c] = vals[j]
+		}
+		rows[i] = row
+	}
+	if len(rows) == 0 {
+		return map[string]any{"inserted": 0}, nil
+	}
+	meta, ok := q.tx.Table(stmt.Table)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", storage.ErrTableNotFound, stmt.Table)
+	}
+	if err := e.applyDefaults(q, meta, stmt.Columns, rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := e.fire(q, trigs.Fire("BEFORE", "INSERT"), "", nil, row); err != nil {
+			return nil, err
+		}
+	}
+
+	if oc := stmt.OnConflict; oc != nil {
+		var resolve storage.ConflictFunc
+		if !oc.DoNothing {
+			resolve = func(existing, excluded map[string]any) (map[string]any, error) {
+				en := q.env(stmt.Table, existing, q.outer)
+				en.tables["excluded"] = excluded
+				updated := copyMap(existing)
+				for _, a := range oc.Set {
+					v, err := e.eval(a.Value, en)
+					if err != nil {
+						return nil, err
+					}
+					updated[a.Column] = v
+				}
+				if err := e.fire(q, trigs.Fire("BEFORE", "UPDATE"), stmt.Table, existing, updated); err != nil {
+					return nil, err
+				}
+				return updated, nil
+			}
+		}
+		res, err := q.tx.UpsertRows(stmt.Table, rows, oc.Columns, resolve)
 		if err != nil {
 			return nil, err
 		}
-		return map[string]any{"deleted": deleted}, nil
-	default:
-		return nil, fmt.Errorf("executor: unsupported plan type %T", p)
+		if err := e.advanceSequences(q, meta, stmt.Columns, res.Rows); err != nil {
+			return nil, err
+		}
+		for i, row := range res.Rows {
+			var err error
+			if old := res.Olds[i]; old != nil {
+				err = e.fire(q, trigs.Fire("AFTER", "UPDATE"), "", old, row)
+			} else {
+				err = e.fire(q, trigs.Fire("AFTER", "INSERT"), "", nil, row)
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+		if stmt.Returning != nil {
+			return e.returning(q, stmt.Table, stmt.Returning, res.Rows)
+		}
+		return map[string]any{"inserted": res.Inserted, "updated": res.Updated}, nil
+	}
+
+	ids, err := q.tx.AppendRows(stmt.Table, rows)
+	if err != nil {
+		return nil, err
+	}
+	if err := e.advanceSequences(q, meta, stmt.Columns, rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := e.fire(q, trigs.Fire("AFTER", "INSERT"), "", nil, row); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.Returning != nil {
+		return e.returning(q, stmt.Table, stmt.Returning, rows)
+	}
+	if len(ids) == 1 {
+		return map[string]any{"rowid": ids[0]}, nil
+	}
+	return map[string]any{"inserted": len(ids)}, nil
+}
+
+func (e *Executor) execUpdate(q *query, stmt *parser.UpdateStmt, trigs planner.Triggers) (any, error) {
+	if err := q.checkChanging(stmt.Table); err != nil {
+		return nil, err
+	}
+	var olds []map[string]any
+	updated, err := q.tx.UpdateRows(stmt.Table, e.rowFilter(q, stmt.Table, stmt.Where), func(row map[string]any) (map[string]any, error) {
+		// every SET expression sees the row as it was before the update
+		en := q.env(stmt.Table, row, q.outer)
+		newRow := copyMap(row)
+		for _, a := range stmt.Set {
+			v, err := e.eval(a.Value, en)
+			if err != nil {
+				return nil, err
+			}
+			newRow[a.Column] = v
+		}
+		if err := e.fire(q, trigs.Fire("BEFORE", "UPDATE"), stmt.Table, row, newRow); err != nil {
+			return nil, err
+		}
+		olds = append(olds, row)
+		return newRow, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, row := range updated {
+		if err := e.fire(q, trigs.Fire("AFTER", "UPDATE"), "", olds[i], row); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.Returning != nil {
+		return e.returning(q, stmt.Table, stmt.Returning, updated)
+	}
+	return map[string]any{"updated": len(updated)}, nil
+}
+
+func (e *Executor) execDelete(q *query, stmt *parser.DeleteStmt, trigs planner.Triggers) (any, error) {
+	if err := q.checkChanging(stmt.Table); err != nil {
+		return nil, err
+	}
+	match := e.rowFilter(q, stmt.Table, stmt.Where)
+	if before := trigs.Fire("BEFORE", "DELETE"); len(before) > 0 {
+		where := match
+		match = func(row map[string]any) (bool, error) {
+			if where != nil {
+				if ok, err := where(row); err != nil || !ok {
+					return false, err
+				}
+			}
+			return true, e.fire(q, before, stmt.Table, row, nil)
+		}
+	}
+	deleted, err := q.tx.DeleteRows(stmt.Table, match)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range deleted {
+		if err := e.fire(q, trigs.Fire("AFTER", "DELETE"), "", row, nil); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.Returning != nil {
+		return e.returning(q, stmt.Table, stmt.Returning, deleted)
+	}
+	return map[string]any{"deleted": len(deleted)}, nil
+}
+
+// returning projects the rows affected by a mutation into a row result
+func (e *Executor) returning(q *query, table string, items []parser.SelectItem, rows []map[string]any) (any, error) {
+	meta, ok := q.tx.Table(table)
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", storage.ErrTableNotFound, table)
+	}
+	_, out, err := e.project(q, items, meta, rows, q.outer)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func copyMap(m map[string]any) map[string]any {
+	out := make(map[string]any, len(m))
+	for k, v := range m {
+		out[k] = v
 	}
+	return out
 }
@@ -0,0 +1,114 @@
+package executor
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/planner"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// testDB runs statements through the parser, planner and executor
+type testDB struct {
+	t  *testing.T
+	st *storage.Store
+	pl *planner.Planner
+	ex *Executor
+}
+
+func newTestDB(t *testing.T) *testDB {
+	t.Helper()
+	st, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { st.Close() })
+	ex := NewExecutor(st)
+	return &testDB{t: t, st: st, pl: planner.NewPlanner(st, ex), ex: ex}
+}
+
+func (db *testDB) exec(sql string) (any, error) {
+	stmt, err := parser.Parse(sql)
+	if err != nil {
+		return nil, err
+	}
+	plan, err := db.pl.Plan(stmt)
+	if err != nil {
+		return nil, err
+	}
+	return db.ex.Execute(plan, nil)
+}
+
+func (db *testDB) mustExec(sql string) any {
+	db.t.Helper()
+	res, err := db.exec(sql)
+	if err != nil {
+		db.t.Fatalf("%s: %v", sql, err)
+	}
+	return res
+}
+
+// expect runs a query and compares its rows with want
+func (db *testDB) expect(sql string, want ...map[string]any) {
+	db.t.Helper()
+	got := db.mustExec(sql)
+	if want == nil {
+		want = []map[string]any{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		db.t.Fatalf("%s\ngot  %#v\nwant %#v", sql, got, want)
+	}
+}
+
+// column runs a query and returns the values of one result column
+func (db *testDB) column(sql, col string) []any {
+	db.t.Helper()
+	vals := []any{}
+	for _, r := range db.mustExec(sql).([]map[string]any) {
+		vals = append(vals, r[col])
+	}
+	return vals
+}
+
+// expectColumn compares one result column of a query with want
+func (db *testDB) expectColumn(sql, col string, want ...any) {
+	db.t.Helper()
+	if want == nil {
+		want = []any{}
+	}
+	if got := db.column(sql, col); !reflect.DeepEqual(got, want) {
+		db.t.Fatalf("%s\ngot  %#v\nwant %#v", sql, got, want)
+	}
+}
+
+// expectErr runs a statement that must fail
+func (db *testDB) expectErr(sql string) error {
+	db.t.Helper()
+	_, err := db.exec(sql)
+	if err == nil {
+		db.t.Fatalf("%s: no error", sql)
+	}
+	return err
+}
+
+type row = map[string]any
+
+func TestShowTablesAndDescribe(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExec("CREATE TABLE users (id INT PRIMARY KEY, name TEXT NOT NULL DEFAULT 'x')")
+	db.mustExec("CREATE TABLE orders (id INT)")
+	db.expect("SHOW TABLES", row{"table_name": "orders"}, row{"table_name": "users"})
+	db.expect("DESCRIBE users",
+		row{"column_name": "id", "data_type": "INT", "not_null": true, "primary_key": true, "default": nil, "generated": nil},
+		row{"column_name": "name", "data_type": "TEXT", "not_null": true, "primary_key": false, "default": "'x'", "generated": nil})
+	db.expect("SELECT table_name FROM nalar_columns WHERE column_name = 'name'", row{"table_name": "users"})
+	db.expectErr("DESCRIBE missing")
+	db.expectErr("DELETE FROM nalar_tables")
+}
+
+func TestFoldedColumnNames(t *testing.T) {
+	db := newTestDB(t)
+	db.expect("SELECT UPPER('q'), CAST(1 AS INT), ROUND(3.4, 0)",
+		row{"upper": "Q", "integer": int64(1), "round": int64(3)})
+}
@@ -0,0 +1,260 @@
+package executor
+
+import (
+	"fmt"
+	"math"
+	"strings"
+	"unicode/utf8"
+)
+
+// scalarFunc is an entry of the function registry
+type scalarFunc struct {
+	minArgs int
+	maxArgs int // -1 for variadic
+	// nullable functions receive NULL arguments; all others return NULL
+	// as soon as any argument is NULL
+	nullable bool
+	// volatile functions may return different results for the same
+	// arguments and are never folded into constants by the planner
+	volatile bool
+	fn       func(args []any) (any, error)
+}
+
+// builtins holds the functions available to every executor
+var builtins = map[string]scalarFunc{
+	// string functions
+	"UPPER":     {minArgs: 1, maxArgs: 1, fn: fnUpper},
+	"LOWER":     {minArgs: 1, maxArgs: 1, fn: fnLower},
+	"LENGTH":    {minArgs: 1, maxArgs: 1, fn: fnLength},
+	"SUBSTR":    {minArgs: 2, maxArgs: 3, fn: fnSubstr},
+	"SUBSTRING": {minArgs: 2, maxArgs: 3, fn: fnSubstr},
+	"TRIM":      {minArgs: 1, maxArgs: 2, fn: fnTrim},
+	"REPLACE":   {minArgs: 3, maxArgs: 3, fn: fnReplace},
+	"CONCAT":    {minArgs: 1, maxArgs: -1, nullable: true, fn: fnConcat},
+
+	// math functions
+	"ABS":     {minArgs: 1, maxArgs: 1, fn: fnAbs},
+	"ROUND":   {minArgs: 1, maxArgs: 2, fn: fnRound},
+	"FLOOR":   {minArgs: 1, maxArgs: 1, fn: fnFloor},
+	"CEIL":    {minArgs: 1, maxArgs: 1, fn: fnCeil},
+	"CEILING": {minArgs: 1, maxArgs: 1, fn: fnCeil},
+	"MOD":     {minArgs: 2, maxArgs: 2, fn: fnMod},
+
+	// date and time functions
+	"NOW":               {minArgs: 0, maxArgs: 0, volatile: true, fn: fnNow},
+	"CURRENT_TIMESTAMP": {minArgs: 0, maxArgs: 0, volatile: true, fn: fnNow},
+	"CURRENT_DATE":      {minArgs: 0, maxArgs: 0, volatile: true, fn: fnCurrentDate},
+	"DATE_TRUNC":        {minArgs: 2, maxArgs: 2, fn: fnDateTrunc},
+	"EXTRACT":           {minArgs: 2, maxArgs: 2, fn: fnExtract},
+	"STRFTIME":          {minArgs: 2, maxArgs: 2, fn: fnStrftime},
+
+	// NULL handling
+	"COALESCE": {minArgs: 1, maxArgs: -1, nullable: true, fn: fnCoalesce},
+	"IFNULL":   {minArgs: 2, maxArgs: 2, nullable: true, fn: fnCoalesce},
+	"NULLIF":   {minArgs: 2, maxArgs: 2, nullable: true, fn: fnNullIf},
+}
+
+// callFunc resolves name in the registry, checks the argument count and
+// invokes the function
+func (e *Executor) callFunc(name string, args []any) (any, error) {
+	f, ok := e.funcs.scalar(name)
+	if !ok {
+		return nil, fmt.Errorf("function %s does not exist", strings.ToLower(name))
+	}
+	if len(args) < f.minArgs || (f.maxArgs >= 0 && len(args) > f.maxArgs) {
+		return nil, fmt.Errorf("function %s: wrong number of arguments (%d)", strings.ToLower(name), len(args))
+	}
+	if !f.nullable {
+		for _, a := range args {
+			if a == nil {
+				return nil, nil
+			}
+		}
+	}
+	v, err := f.fn(args)
+	if err != nil {
+		return nil, fmt.Errorf("function %s: %w", strings.ToLower(name), err)
+	}
+	return v, nil
+}
+
+func textArg(args []any, i int) (string, error) {
+	s, ok := args[i].(string)
+	if !ok {
+		return "", fmt.Errorf("argument %d must be TEXT, got %s", i+1, typeName(args[i]))
+	}
+	return s, nil
+}
+
+func intArg(args []any, i int) (int64, error) {
+	n, ok := toInt(args[i])
+	if !ok {
+		return 0, fmt.Errorf("argument %d must be INTEGER, got %s", i+1, typeName(args[i]))
+	}
+	return n, nil
+}
+
+func numberArg(args []any, i int) (float64, error) {
+	f, ok := toFloat(args[i])
+	if !ok {
+		return 0, fmt.Errorf("argument %d must be numeric, got %s", i+1, typeName(args[i]))
+	}
+	return f, nil
+}
+
+func fnUpper(args []any) (any, error) {
+	s, err := textArg(args, 0)
+	return strings.ToUpper(s), err
+}
+
+func fnLower(args []any) (any, error) {
+	s, err := textArg(args, 0)
+	return strings.ToLower(s), err
+}
+
+func fnLength(args []any) (any, error) {
+	return int64(utf8.RuneCountInString(toText(args[0]))), nil
+}
+
+// fnSubstr implements SUBSTR(s, start [, len]) with 1-based positions
+func fnSubstr(args []any) (any, error) {
+	s, err := textArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	start, err := intArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	r := []rune(s)
+	end := int64(len(r)) + 1
+	if len(args) == 3 {
+		n, err := intArg(args, 2)
+		if err != nil {
+			return nil, err
+		}
+		if n < 0 {
+			return nil, fmt.Errorf("negative substring length not allowed")
+		}
+		end = start + n
+	}
+	start = max(start, 1)
+	end = min(end, int64(len(r))+1)
+	if start >= end {
+		return "", nil
+	}
+	return string(r[start-1 : end-1]), nil
+}
+
+// fnTrim implements TRIM(s [, chars])
+func fnTrim(args []any) (any, error) {
+	s, err := textArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	if len(args) == 2 {
+		chars, err := textArg(args, 1)
+		if err != nil {
+			return nil, err
+		}
+		return strings.Trim(s, chars), nil
+	}
+	return strings.TrimSpace(s), nil
+}
+
+func fnReplace(args []any) (any, error) {
+	s, err := textArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	from, err := textArg(args, 1)
+	if err != nil {
+		return nil, err
+	}
+	to, err := textArg(args, 2)
+	if err != nil {
+		return nil, err
+	}
+	return strings.ReplaceAll(s, from, to), nil
+}
+
+// fnConcat joins its arguments, skipping NULLs
+func fnConcat(args []any) (any, error) {
+	var b strings.Builder
+	for _, a := range args {
+		if a != nil {
+			b.WriteString(toText(a))
+		}
+	}
+	return b.String(), nil
+}
+
+func fnAbs(args []any) (any, error) {
+	if n, ok := args[0].(int64); ok {
+		if n < 0 {
+			return -n, nil
+		}
+		return n, nil
+	}
+	f, err := numberArg(args, 0)
+	return math.Abs(f), err
+}
+
+// fnRound implements ROUND(x [, digits]), rounding half away from zero
+func fnRound(args []any) (any, error) {
+	f, err := numberArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	digits := int64(0)
+	if len(args) == 2 {
+		if digits, err = intArg(args, 1); err != nil {
+			return nil, err
+		}
+	}
+	if isInt(args[0]) && digits >= 0 {
+		return args[0], nil
+	}
+	scale := math.Pow(10, float64(digits))
+	r := math.Round(f*scale) / scale
+	if digits <= 0 {
+		return int64(r), nil
+	}
+	return r, nil
+}
+
+func fnFloor(args []any) (any, error) {
+	if isInt(args[0]) {
+		return args[0], nil
+	}
+	f, err := numberArg(args, 0)
+	return int64(math.Floor(f)), err
+}
+
+func fnCeil(args []any) (any, error) {
+	if isInt(args[0]) {
+		return args[0], nil
+	}
+	f, err := numberArg(args, 0)
+	return int64(math.Ceil(f)), err
+}
+
+func fnMod(args []any) (any, error) {
+	return arithmetic("%", args[0], args[1])
+}
+
+func fnCoalesce(args []any) (any, error) {
+	for _, a := range args {
+		if a != nil {
+			return a, nil
+		}
+	}
+	return nil, nil
+}
+
+func fnNullIf(args []any) (any, error) {
+	if valuesEqual(args[0], args[1]) {
+		return nil, nil
+	}
+	return args[0], nil
+}
@@ -0,0 +1,61 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+)
+
+// Notifier receives the notifications sent by NOTIFY. Notify is called once
+// the transaction of the statement that sent them committed, and must not
+// block.
+type Notifier interface {
+	Notify(channel, payload string)
+}
+
+// SetNotifier sets where notifications go; without one they are dropped.
+// It must be called before statements are executed.
+func (e *Executor) SetNotifier(n Notifier) {
+	e.notifier = n
+}
+
+// maxNotifyPayload is the size a payload must stay below, as in PostgreSQL
+const maxNotifyPayload = 8000
+
+// notification is sent once the statement that queued it committed
+type notification struct {
+	channel, payload string
+}
+
+// execNotify queues a notification of the running statement. Sending the
+// same payload on a channel twice queues it once.
+func (e *Executor) execNotify(q *query, stmt *parser.NotifyStmt) error {
+	n := notification{channel: stmt.Channel}
+	if stmt.Payload != nil {
+		v, err := e.eval(stmt.Payload, &env{q: q, outer: q.outer})
+		if err != nil {
+			return err
+		}
+		if v != nil {
+			n.payload = toText(v)
+		}
+	}
+	if len(n.payload) >= maxNotifyPayload {
+		return fmt.Errorf("payload string too long")
+	}
+	if !slices.Contains(*q.notes, n) {
+		*q.notes = append(*q.notes, n)
+	}
+	return nil
+}
+
+// deliver hands the notifications queued by a statement to the notifier
+func (e *Executor) deliver(notes []notification) {
+	if e.notifier == nil {
+		return
+	}
+	for _, n := range notes {
+		e.notifier.Notify(n.channel, n.payload)
+	}
+}
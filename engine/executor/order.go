@@ -0,0 +1,118 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+)
+
+// sortRows orders the result rows of a query by ORDER BY. A key naming a
+// result column reads it from the row; any other key is evaluated in the
+// environment the row was projected from.
+func (e *Executor) sortRows(order []parser.OrderItem, cols []string, rows []map[string]any, envs []*env) ([]map[string]any, error) {
+	keys := make([][]any, len(rows))
+	for i, row := range rows {
+		keys[i] = make([]any, len(order))
+		for k, it := range order {
+			if it.Column > 0 {
+				keys[i][k] = row[cols[it.Column-1]]
+				continue
+			}
+			v, err := e.eval(it.Expr, envs[i])
+			if err != nil {
+				return nil, err
+			}
+			keys[i][k] = v
+		}
+	}
+	idx := make([]int, len(rows))
+	for i := range idx {
+		idx[i] = i
+	}
+	var err error
+	sort.SliceStable(idx, func(a, b int) bool {
+		c, cerr := compareSortKeys(keys[idx[a]], keys[idx[b]], order)
+		if cerr != nil && err == nil {
+			err = cerr
+		}
+		return c < 0
+	})
+	if err != nil {
+		return nil, err
+	}
+	sorted := make([]map[string]any, len(rows))
+	for i, j := range idx {
+		sorted[i] = rows[j]
+	}
+	return sorted, nil
+}
+
+// limitRows applies OFFSET and LIMIT; a NULL count means no limit
+func (e *Executor) limitRows(q *query, stmt *parser.SelectStmt, rows []map[string]any, outer *env) ([]map[string]any, error) {
+	offset, err := e.rowCount(q, stmt.Offset, "OFFSET", outer)
+	if err != nil {
+		return nil, err
+	}
+	if offset > 0 {
+		rows = rows[min(offset, int64(len(rows))):]
+	}
+	if stmt.Limit != nil {
+		limit, err := e.rowCount(q, stmt.Limit, "LIMIT", outer)
+		if err != nil {
+			return nil, err
+		}
+		if limit >= 0 && limit < int64(len(rows)) {
+			rows = rows[:limit]
+		}
+	}
+	return rows, nil
+}
+
+// rowCount evaluates the argument of LIMIT or OFFSET; -1 stands for NULL
+func (e *Executor) rowCount(q *query, x parser.Expr, clause string, outer *env) (int64, error) {
+	if x == nil {
+		return 0, nil
+	}
+	v, err := e.eval(x, &env{q: q, outer: outer})
+	if err != nil || v == nil {
+		return -1, err
+	}
+	n, ok := toInt(v)
+	if !ok {
+		return 0, fmt.Errorf("argument of %s must be an integer, got %s", clause, typeName(v))
+	}
+	if n < 0 {
+		return 0, fmt.Errorf("%s must not be negative", clause)
+	}
+	return n, nil
+}
+
+// compareSortKeys orders two rows by their ORDER BY values. NULLs sort
+// after all other values, or before them with NullsFirst, which is the
+// default when descending.
+func compareSortKeys(a, b []any, order []parser.OrderItem) (int, error) {
+	for i, it := range order {
+		x, y := a[i], b[i]
+		switch {
+		case x == nil && y == nil:
+			continue
+		case x == nil || y == nil:
+			if (x == nil) == it.NullsFirst {
+				return -1, nil
+			}
+			return 1, nil
+		}
+		c, err := compare(x, y)
+		if err != nil {
+			return 0, err
+		}
+		if it.Desc {
+			c = -c
+		}
+		if c != 0 {
+			return c, nil
+		}
+	}
+	return 0, nil
+}
@@ -0,0 +1,25 @@
+package executor
+
+import "testing"
+
+func TestOrderBy(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExec("CREATE TABLE p (id INT PRIMARY KEY, name TEXT, discount INT)")
+	db.mustExec("INSERT INTO p (id, name, discount) VALUES (1, 'b', 5), (2, 'a', NULL), (3, 'c', 10), (4, 'a', 5)")
+
+	db.expectColumn("SELECT id FROM p ORDER BY discount", "id", int64(1), int64(4), int64(3), int64(2))
+	db.expectColumn("SELECT id FROM p ORDER BY discount DESC", "id", int64(2), int64(3), int64(1), int64(4))
+	db.expectColumn("SELECT id FROM p ORDER BY discount NULLS FIRST", "id", int64(2), int64(1), int64(4), int64(3))
+	db.expectColumn("SELECT id FROM p ORDER BY discount DESC NULLS LAST", "id", int64(3), int64(1), int64(4), int64(2))
+	db.expectColumn("SELECT id, name FROM p ORDER BY name, 1 DESC", "id", int64(4), int64(2), int64(1), int64(3))
+	db.expectColumn("SELECT id FROM p ORDER BY id * -1 LIMIT 2 OFFSET 1", "id", int64(3), int64(2))
+	db.expectColumn("SELECT id FROM p ORDER BY id LIMIT NULL OFFSET 3", "id", int64(4))
+	db.expectColumn("SELECT id, ROW_NUMBER() OVER (ORDER BY discount NULLS FIRST, id) AS n FROM p ORDER BY id", "n",
+		int64(2), int64(1), int64(4), int64(3))
+
+	db.expectErr("SELECT id FROM p LIMIT -1")
+	db.expectErr("SELECT id FROM p LIMIT 'x'")
+	// trailing words are an error rather than ignored
+	db.expectErr("DELETE FROM p WHER id = 1")
+	db.expectColumn("SELECT id FROM p", "id", int64(1), int64(2), int64(3), int64(4))
+}
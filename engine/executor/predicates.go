@@ -0,0 +1,188 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+)
+
+// evalLike implements [NOT] LIKE and ILIKE
+func (e *Executor) evalLike(n *parser.LikeExpr, en *env) (any, error) {
+	v, err := e.eval(n.Expr, en)
+	if err != nil {
+		return nil, err
+	}
+	pv, err := e.eval(n.Pattern, en)
+	if err != nil || v == nil || pv == nil {
+		return nil, err
+	}
+	pattern, ok := pv.(string)
+	if !ok {
+		return nil, fmt.Errorf("LIKE pattern must be TEXT, got %s", typeName(pv))
+	}
+	s := toText(v)
+	if n.Fold {
+		s, pattern = strings.ToLower(s), strings.ToLower(pattern)
+	}
+	return likeMatch([]rune(s), []rune(pattern)) != n.Not, nil
+}
+
+// likeMatch reports whether s matches a LIKE pattern: % matches any run of
+// characters, _ exactly one, and a backslash escapes the next character
+func likeMatch(s, pattern []rune) bool {
+	si, pi := 0, 0
+	starP, starS := -1, 0 // position after the last %, for backtracking
+	for si < len(s) {
+		if pi < len(pattern) {
+			c, width, wild := pattern[pi], 1, false
+			switch {
+			case c == '%':
+				starP, starS = pi+1, si
+				pi++
+				continue
+			case c == '_':
+				wild = true
+			case c == '\\' && pi+1 < len(pattern):
+				c, width = pattern[pi+1], 2
+			}
+			if wild || c == s[si] {
+				si++
+				pi += width
+				continue
+			}
+		}
+		if starP < 0 {
+			return false
+		}
+		// let the last % swallow one more character and retry
+		starS++
+		si, pi = starS, starP
+	}
+	for pi < len(pattern) && pattern[pi] == '%' {
+		pi++
+	}
+	return pi == len(pattern)
+}
+
+// evalIn implements [NOT] IN with SQL semantics: a NULL operand, or no
+// match in a list containing NULL, gives NULL
+func (e *Executor) evalIn(n *parser.InExpr, en *env) (any, error) {
+	v, err := e.eval(n.Expr, en)
+	if err != nil {
+		return nil, err
+	}
+	if n.Select != nil {
+		return e.evalInSubquery(n, v, en)
+	}
+	vals := make([]any, len(n.List))
+	for i, item := range n.List {
+		if vals[i], err = e.eval(item, en); err != nil {
+			return nil, err
+		}
+	}
+	return inValues(n, v, vals)
+}
+
+// inValues tests v against the values of an IN list: true on a match,
+// NULL when v is NULL or the list holds a NULL, false otherwise
+func inValues(n *parser.InExpr, v any, vals []any) (any, error) {
+	sawNull := false
+	for _, iv := range vals {
+		if iv == nil {
+			sawNull = true
+			continue
+		}
+		if v == nil {
+			continue
+		}
+		eq, err := comparison("=", v, iv)
+		if err != nil {
+			return nil, err
+		}
+		if eq == true {
+			return !n.Not, nil
+		}
+	}
+	if v == nil || sawNull {
+		return nil, nil
+	}
+	return n.Not, nil
+}
+
+// evalBetween implements x [NOT] BETWEEN low AND high as
+// low <= x AND x <= high
+func (e *Executor) evalBetween(n *parser.BetweenExpr, en *env) (any, error) {
+	var vals [3]any
+	for i, x := range []parser.Expr{n.Expr, n.Low, n.High} {
+		v, err := e.eval(x, en)
+		if err != nil {
+			return nil, err
+		}
+		vals[i] = v
+	}
+	ge, err := comparison(">=", vals[0], vals[1])
+	if err != nil {
+		return nil, err
+	}
+	le, err := comparison("<=", vals[0], vals[2])
+	if err != nil {
+		return nil, err
+	}
+	var res any
+	switch {
+	case ge == false || le == false:
+		res = false
+	case ge == nil || le == nil:
+		return nil, nil
+	default:
+		res = true
+	}
+	return res != n.Not, nil
+}
+
+// regexMatch implements ~ (match), ~* (case-insensitive) and their
+// negations !~ and !~*, using RE2 syntax
+func regexMatch(op string, a, b any) (any, error) {
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	pattern, ok := b.(string)
+	if !ok {
+		return nil, fmt.Errorf("regular expression must be TEXT, got %s", typeName(b))
+	}
+	if strings.HasSuffix(op, "*") {
+		pattern = "(?i)" + pattern
+	}
+	re, err := compileRegex(pattern)
+	if err != nil {
+		return nil, err
+	}
+	return re.MatchString(toText(a)) != strings.HasPrefix(op, "!"), nil
+}
+
+// compiled patterns, reset when it grows large so that patterns computed
+// per row cannot grow it without bound
+var (
+	regexMu    sync.Mutex
+	regexCache = map[string]*regexp.Regexp{}
+)
+
+func compileRegex(pattern string) (*regexp.Regexp, error) {
+	regexMu.Lock()
+	defer regexMu.Unlock()
+	if re, ok := regexCache[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid regular expression: %w", err)
+	}
+	if len(regexCache) >= 256 {
+		regexCache = map[string]*regexp.Regexp{}
+	}
+	regexCache[pattern] = re
+	return re, nil
+}
@@ -0,0 +1,40 @@
+package executor
+
+import "testing"
+
+func TestPredicates(t *testing.T) {
+	db := newTestDB(t)
+	db.mustExec("CREATE TABLE users (id INT PRIMARY KEY, name TEXT, phone TEXT)")
+	db.mustExec(`INSERT INTO users (id, name, phone) VALUES (1, 'John', '+123'), (2, 'joan', NULL),
+		(3, 'Abe', '555-1234'), (4, 'Jo_x', '+9'), (5, 'Bob', NULL)`)
+
+	for _, indexed := range []bool{false, true} {
+		if indexed {
+			db.mustExec("CREATE INDEX users_name ON users (name)")
+		}
+		for _, tc := range []struct {
+			where string
+			want  []any
+		}{
+			{"name LIKE 'Jo%'", []any{int64(1), int64(4)}},
+			{"name LIKE 'Jo\\_%'", []any{int64(4)}},
+			{"name LIKE 'J_hn'", []any{int64(1)}},
+			{"name ILIKE 'jo%'", []any{int64(1), int64(2), int64(4)}},
+			{"name NOT LIKE '%o%'", []any{int64(3)}},
+			{"id IN (5, 3, 9)", []any{int64(3), int64(5)}},
+			{"id NOT IN (1, 2, 3)", []any{int64(4), int64(5)}},
+			{"id NOT IN (1, NULL)", nil},
+			{"id BETWEEN 2 AND 4", []any{int64(2), int64(3), int64(4)}},
+			{"id NOT BETWEEN 2 AND 4", []any{int64(1), int64(5)}},
+			{"name BETWEEN 'B' AND 'Jo'", []any{int64(5)}},
+			{"phone IS NULL", []any{int64(2), int64(5)}},
+			{"phone IS NOT NULL AND phone !~ '^\\+[0-9]+$'", []any{int64(3)}},
+			{"name ~* '^jo'", []any{int64(1), int64(2), int64(4)}},
+			{"name REGEXP 'b$'", []any{int64(5)}},
+			{"name NOT REGEXP 'o'", []any{int64(3)}},
+		} {
+			db.expectColumn("SELECT id FROM users WHERE "+tc.where, "id", tc.want...)
+		}
+	}
+	db.expectErr("SELECT id FROM users WHERE name ~ '('")
+}
@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+)
+
+// registry holds the scalar and aggregate functions known to an executor:
+// the built-ins plus everything registered through RegisterFunc and
+// RegisterAggregate.
+type registry struct {
+	mu         sync.RWMutex
+	scalars    map[string]scalarFunc
+	aggregates map[string]aggregateFunc
+}
+
+func newRegistry() *registry {
+	r := &registry{
+		scalars:    make(map[string]scalarFunc, len(builtins)),
+		aggregates: make(map[string]aggregateFunc, len(builtinAggregates)),
+	}
+	for name, f := range builtins {
+		r.scalars[name] = f
+	}
+	for name, f := range builtinAggregates {
+		r.aggregates[name] = f
+	}
+	return r
+}
+
+func (r *registry) scalar(name string) (scalarFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.scalars[strings.ToUpper(name)]
+	return f, ok
+}
+
+func (r *registry) aggregate(name string) (aggregateFunc, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	f, ok := r.aggregates[strings.ToUpper(name)]
+	return f, ok
+}
+
+var funcName = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// reserve validates name and makes sure no function already uses it.
+// Called with r.mu held.
+func (r *registry) reserve(name string) (string, error) {
+	if !funcName.MatchString(name) {
+		return "", fmt.Errorf("invalid function name %q", name)
+	}
+	upper := strings.ToUpper(name)
+	_, scalar := r.scalars[upper]
+	_, agg := r.aggregates[upper]
+	_, win := windowFuncs[upper]
+	_, seq := sequenceFuncs[upper]
+	if scalar || agg || win || seq {
+		return "", fmt.Errorf("function %s already exists", strings.ToLower(name))
+	}
+	return upper, nil
+}
+
+// RegisterFunc makes the Go function fn callable from SQL as name.
+//
+// Parameters may be integers, floats, string, bool, time.Time or any; the
+// last one may be variadic. Arguments are checked against these types when
+// the function is called, and a NULL passed to a parameter that is not of
+// type any makes the call return NULL without invoking fn. fn returns a
+// single value, optionally followed by an error that is reported as the
+// query error.
+//
+// Deterministic functions always return the same result for the same
+// arguments; the planner evaluates their calls with constant arguments
+// once, while planning.
+func (e *Executor) RegisterFunc(name string, fn any, deterministic bool) error {
+	g, err := newGoFunc(fn)
+	if err != nil {
+		return fmt.Errorf("function %s: %w", name, err)
+	}
+	minArgs, maxArgs := g.sig.argRange()
+
+	e.funcs.mu.Lock()
+	defer e.funcs.mu.Unlock()
+	key, err := e.funcs.reserve(name)
+	if err != nil {
+		return err
+	}
+	e.funcs.scalars[key] = scalarFunc{
+		minArgs:  minArgs,
+		maxArgs:  maxArgs,
+		nullable: true, // the adapter applies the NULL rule per parameter
+		volatile: !deterministic,
+		fn:       g.call,
+	}
+	return nil
+}
+
+// RegisterAggregate makes an aggregate function available as name.
+//
+// factory is a function without arguments returning a fresh aggregation
+// state, typically a pointer to a struct. The state must have a Step
+// method, called once per input row with the aggregate arguments (typed as
+// for RegisterFunc) and optionally returning an error, and a Done method
+// returning the result, optionally followed by an error. Rows where a NULL
+// meets a parameter that is not of type any are skipped.
+func (e *Executor) RegisterAggregate(name string, factory any) error {
+	agg, err := newGoAggregate(factory)
+	if err != nil {
+		return fmt.Errorf("aggregate %s: %w", name, err)
+	}
+	minArgs, maxArgs := agg.step.argRange()
+
+	e.funcs.mu.Lock()
+	defer e.funcs.mu.Unlock()
+	key, err := e.funcs.reserve(name)
+	if err != nil {
+		return err
+	}
+	e.funcs.aggregates[key] = aggregateFunc{
+		minArgs: minArgs,
+		maxArgs: maxArgs,
+		new:     agg.instance,
+	}
+	return nil
+}
+
+// Deterministic reports whether calls of the scalar function name with
+// constant arguments may be evaluated at plan time
+func (e *Executor) Deterministic(name string) bool {
+	f, ok := e.funcs.scalar(name)
+	return ok && !f.volatile
+}
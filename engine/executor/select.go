@@ -0,0 +1,183 @@
+package executor
+
+import (
+	"fmt"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// selectRows reads the FROM source, filters it by WHERE and applies the
+// projection, aggregating first when the query groups or calls aggregates,
+// or combines the results of a set operation; then it sorts and limits the
+// result. The returned column list preserves the order of the select list
+// (or the source columns for *), which INSERT ... SELECT relies on. outer
+// is the row of the enclosing query when stmt is a subquery.
+func (e *Executor) selectRows(q *query, stmt *parser.SelectStmt, outer *env) ([]string, []map[string]any, error) {
+	if stmt.With != nil {
+		for _, cte := range stmt.With.CTEs {
+			if err := e.materialize(q, cte, outer); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	var cols []string
+	var out []map[string]any
+	var envs []*env
+	if stmt.Set != nil {
+		var err error
+		if cols, out, err = e.setRows(q, stmt.Set, outer); err != nil {
+			return nil, nil, err
+		}
+	} else {
+		meta, rows, err := e.sourceRows(q, stmt, outer)
+		if err != nil {
+			return nil, nil, err
+		}
+		if calls := e.aggregateCalls(stmt); len(calls) > 0 || len(stmt.GroupBy) > 0 || stmt.Having != nil {
+			if rows, envs, err = e.aggregateRows(q, stmt, calls, meta, rows, outer); err != nil {
+				return nil, nil, err
+			}
+		} else {
+			envs = make([]*env, len(rows))
+			for i, row := range rows {
+				envs[i] = q.env(meta.Name, row, outer)
+			}
+		}
+		if cols, out, err = e.projectEnvs(stmt.Items, meta, rows, envs); err != nil {
+			return nil, nil, err
+		}
+		if stmt.Distinct {
+			if out, envs, err = e.distinctRows(cols, out, envs); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	if len(stmt.OrderBy) > 0 {
+		var err error
+		if out, err = e.sortRows(stmt.OrderBy, cols, out, envs); err != nil {
+			return nil, nil, err
+		}
+	}
+	out, err := e.limitRows(q, stmt, out, outer)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cols, out, nil
+}
+
+// sourceRows returns the rows of the FROM source that satisfy WHERE. A
+// table is read through the index chosen by the planner when there is one;
+// a derived table is computed first and a WITH query was materialized by
+// the SELECT that defines it. The returned meta carries the name
+// the source is visible as.
+func (e *Executor) sourceRows(q *query, stmt *parser.SelectStmt, outer *env) (storage.TableMeta, []map[string]any, error) {
+	var meta storage.TableMeta
+	var rows []map[string]any
+	var err error
+	switch {
+	case stmt.From != nil:
+		var cols []string
+		if cols, rows, err = e.selectRows(q, stmt.From, outer); err != nil {
+			return meta, nil, err
+		}
+		meta.Name = stmt.Alias
+		for _, c := range cols {
+			meta.Columns = append(meta.Columns, storage.ColumnDefinition{Name: c})
+		}
+	case stmt.CTE != nil:
+		res := q.ctes[stmt.CTE]
+		rows = make([]map[string]any, len(res.rows))
+		copy(rows, res.rows)
+		meta.Name = stmt.SourceName()
+		for _, c := range res.cols {
+			meta.Columns = append(meta.Columns, storage.ColumnDefinition{Name: c})
+		}
+	case stmt.Table != "":
+		var ok bool
+		if meta, ok = q.tx.Table(stmt.Table); !ok {
+			return meta, nil, fmt.Errorf("%w: %s", storage.ErrTableNotFound, stmt.Table)
+		}
+		if access := q.access[stmt]; access != nil {
+			rows, err = q.tx.ScanIndex(stmt.Table, access.Column, access.Ranges)
+		} else {
+			rows, err = q.tx.ScanTable(stmt.Table)
+		}
+		if err != nil {
+			return meta, nil, err
+		}
+		meta.Name = stmt.SourceName()
+	default:
+		// SELECT without FROM evaluates the list once against an empty row
+		rows = []map[string]any{{}}
+	}
+
+	if stmt.Where != nil {
+		kept := rows[:0]
+		for _, row := range rows {
+			ok, err := e.match(stmt.Where, q.env(meta.Name, row, outer))
+			if err != nil {
+				return meta, nil, err
+			}
+			if ok {
+				kept = append(kept, row)
+			}
+		}
+		rows = kept
+	}
+	return meta, rows, nil
+}
+
+// project evaluates a projection list (RETURNING or select list) against
+// rows of the given source. * expands to its columns in definition order.
+func (e *Executor) project(q *query, items []parser.SelectItem, meta storage.TableMeta, rows []map[string]any, outer *env) ([]string, []map[string]any, error) {
+	envs := make([]*env, len(rows))
+	for i, row := range rows {
+		envs[i] = q.env(meta.Name, row, outer)
+	}
+	return e.projectEnvs(items, meta, rows, envs)
+}
+
+// projectEnvs is project with a prepared evaluation environment per row.
+// Window calls of the list are computed over all rows first.
+func (e *Executor) projectEnvs(items []parser.SelectItem, meta storage.TableMeta, rows []map[string]any, envs []*env) ([]string, []map[string]any, error) {
+	calls, err := windowCalls(items)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := e.evalWindows(calls, envs); err != nil {
+		return nil, nil, err
+	}
+
+	star := make([]string, len(meta.Columns))
+	for i, c := range meta.Columns {
+		star[i] = c.Name
+	}
+	cols := parser.ColumnNames(items, star)
+
+	out := make([]map[string]any, len(rows))
+	for i, row := range rows {
+		en := envs[i]
+		proj := make(map[string]any, len(cols))
+		k := 0
+		for _, it := range items {
+			if it.Star {
+				for _, c := range star {
+					proj[cols[k]] = row[c]
+					k++
+				}
+				continue
+			}
+			v, err := e.eval(it.Expr, en)
+			if err != nil {
+				return nil, nil, err
+			}
+			proj[cols[k]] = v
+			k++
+		}
+		out[i] = proj
+	}
+	return cols, out, nil
+}
@@ -0,0 +1,141 @@
+package executor
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// Session is the state a connection keeps between its statements: the
+// value currval returns for each sequence it called nextval or setval on
+type Session struct {
+	mu       sync.Mutex
+	currvals map[string]int64
+}
+
+// NewSession returns the state of a new connection
+func NewSession() *Session {
+	return &Session{currvals: map[string]int64{}}
+}
+
+func (s *Session) setCurrval(seq string, v int64) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	s.currvals[seq] = v
+	s.mu.Unlock()
+}
+
+func (s *Session) currval(seq string) (int64, bool) {
+	if s == nil {
+		return 0, false
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	v, ok := s.currvals[seq]
+	return v, ok
+}
+
+// sequenceFuncs are the functions that read or change a sequence. They
+// need the transaction of the statement, so they are not in the registry.
+var sequenceFuncs = map[string]struct{ minArgs, maxArgs int }{
+	"NEXTVAL": {1, 1},
+	"CURRVAL": {1, 1},
+	"SETVAL":  {2, 3},
+}
+
+// callSequenceFunc evaluates nextval(name), currval(name) and
+// setval(name, value [, is_called]). currval returns the value the last
+// nextval or setval of the session gave the sequence.
+func (e *Executor) callSequenceFunc(name string, args []any, en *env) (any, error) {
+	upper, lower := strings.ToUpper(name), strings.ToLower(name)
+	if f := sequenceFuncs[upper]; len(args) < f.minArgs || len(args) > f.maxArgs {
+		return nil, fmt.Errorf("function %s: wrong number of arguments (%d)", lower, len(args))
+	}
+	if en.q == nil {
+		return nil, fmt.Errorf("function %s is not allowed here", lower)
+	}
+	for _, a := range args {
+		if a == nil {
+			return nil, nil
+		}
+	}
+	seq, ok := args[0].(string)
+	if !ok {
+		return nil, fmt.Errorf("function %s: sequence name must be text, got %s", lower, typeName(args[0]))
+	}
+
+	switch upper {
+	case "NEXTVAL":
+		v, err := en.q.tx.NextVal(seq)
+		if err != nil {
+			return nil, err
+		}
+		en.q.sess.setCurrval(seq, v)
+		return v, nil
+	case "CURRVAL":
+		if _, ok := en.q.tx.Sequence(seq); !ok {
+			return nil, fmt.Errorf("%w: %s", storage.ErrSequenceNotFound, seq)
+		}
+		v, ok := en.q.sess.currval(seq)
+		if !ok {
+			return nil, fmt.Errorf("currval of sequence %s is not yet defined in this session", seq)
+		}
+		return v, nil
+	}
+	v, ok := toInt(args[1])
+	if !ok {
+		return nil, fmt.Errorf("function setval: value must be an integer, got %s", typeName(args[1]))
+	}
+	called := true
+	if len(args) == 3 {
+		b, _, err := toBool(args[2])
+		if err != nil {
+			return nil, fmt.Errorf("function setval: %w", err)
+		}
+		called = b
+	}
+	if err := en.q.tx.SetVal(seq, v, called); err != nil {
+		return nil, err
+	}
+	en.q.sess.setCurrval(seq, v)
+	return v, nil
+}
+
+// autoIncrementDefault is the default of an AUTO_INCREMENT column
+func autoIncrementDefault(table, column string) string {
+	return fmt.Sprintf("nextval('%s')", storage.OwnedSequence(table, column))
+}
+
+// advanceSequences moves the sequence of every AUTO_INCREMENT column an
+// INSERT gave explicit values past the largest of them, so that values
+// generated later do not collide with them
+func (e *Executor) advanceSequences(q *query, meta storage.TableMeta, given []string, rows []map[string]any) error {
+	for _, c := range meta.Columns {
+		if !c.AutoIncrement || !slices.Contains(given, c.Name) {
+			continue
+		}
+		name := storage.OwnedSequence(meta.Name, c.Name)
+		seq, ok := q.tx.Sequence(name)
+		if !ok || seq.Increment < 0 {
+			continue
+		}
+		var top int64
+		found := false
+		for _, row := range rows {
+			if n, ok := toInt(row[c.Name]); ok && (!found || n > top) {
+				top, found = n, true
+			}
+		}
+		if found && (top > seq.Last || (top == seq.Last && !seq.Called)) {
+			if err := q.tx.SetVal(name, top, true); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package executor
+
+import (
+	"github.com/Alwin18/nalarSQL/engine/parser"
+)
+
+// setRows evaluates both sides of a UNION, INTERSECT or EXCEPT and combines
+// them. Rows are compared by value, NULLs being equal to each other, and
+// the result takes its column names from the left side. Without ALL the
+// result is free of duplicates; with ALL, INTERSECT keeps a row as often as
+// it occurs on both sides and EXCEPT removes one occurrence per right row.
+func (e *Executor) setRows(q *query, set *parser.SetOp, outer *env) ([]string, []map[string]any, error) {
+	cols, left, err := e.selectRows(q, set.Left, outer)
+	if err != nil {
+		return nil, nil, err
+	}
+	rcols, right, err := e.selectRows(q, set.Right, outer)
+	if err != nil {
+		return nil, nil, err
+	}
+	right = renameColumns(right, rcols, cols)
+
+	if set.Op == "UNION" {
+		out := append(append([]map[string]any{}, left...), right...)
+		if set.All {
+			return cols, out, nil
+		}
+		left, right = nil, nil
+		out, _, err = e.distinctRows(cols, out, nil)
+		return cols, out, err
+	}
+
+	key := func(row map[string]any) string {
+		vals := make([]any, len(cols))
+		for i, c := range cols {
+			vals[i] = row[c]
+		}
+		return groupKey(vals)
+	}
+	var out []map[string]any
+	seen := make(map[string]bool)
+	emit := func(row map[string]any, k string) {
+		if set.All || !seen[k] {
+			seen[k] = true
+			out = append(out, row)
+		}
+	}
+
+	counts := make(map[string]int, len(right))
+	for _, row := range right {
+		counts[key(row)]++
+	}
+	for _, row := range left {
+		k := key(row)
+		switch {
+		case set.Op == "INTERSECT" && counts[k] > 0:
+			if set.All {
+				counts[k]--
+			}
+			emit(row, k)
+		case set.Op == "EXCEPT" && counts[k] == 0:
+			emit(row, k)
+		case set.Op == "EXCEPT" && set.All:
+			counts[k]--
+		}
+	}
+	return cols, out, nil
+}
@@ -0,0 +1,44 @@
+package executor
+
+import "testing"
+
+func setopDB(t *testing.T) *testDB {
+	db := newTestDB(t)
+	db.mustExec("CREATE TABLE a (id INT, name TEXT)")
+	db.mustExec("CREATE TABLE b (id INT, name TEXT)")
+	db.mustExec("INSERT INTO a (id, name) VALUES (1, 'x'), (2, 'y'), (2, 'y'), (3, NULL), (3, NULL)")
+	db.mustExec("INSERT INTO b (id, name) VALUES (2, 'y'), (3, NULL), (4, 'z'), (4, 'z')")
+	return db
+}
+
+func TestSetOperations(t *testing.T) {
+	db := setopDB(t)
+	for _, tc := range []struct {
+		sql  string
+		want []any
+	}{
+		{"SELECT id FROM a UNION SELECT id FROM b ORDER BY id", []any{int64(1), int64(2), int64(3), int64(4)}},
+		{"SELECT id FROM a UNION ALL SELECT id FROM b ORDER BY 1", []any{
+			int64(1), int64(2), int64(2), int64(2), int64(3), int64(3), int64(3), int64(4), int64(4)}},
+		{"SELECT id FROM a INTERSECT SELECT id FROM b ORDER BY id", []any{int64(2), int64(3)}},
+		{"SELECT id FROM a INTERSECT ALL SELECT id FROM b ORDER BY id", []any{int64(2), int64(3)}},
+		{"SELECT id FROM a EXCEPT SELECT id FROM b", []any{int64(1)}},
+		{"SELECT id FROM a EXCEPT ALL SELECT id FROM b ORDER BY id", []any{int64(1), int64(2), int64(3)}},
+		// INTERSECT binds tighter than UNION
+		{"SELECT id FROM a WHERE id = 1 UNION SELECT id FROM a INTERSECT SELECT id FROM b ORDER BY id", []any{
+			int64(1), int64(2), int64(3)}},
+		{"(SELECT id FROM a ORDER BY id DESC LIMIT 1) UNION ALL (SELECT id FROM b ORDER BY id LIMIT 1)", []any{int64(3), int64(2)}},
+		{"SELECT id FROM a UNION SELECT id FROM b ORDER BY id DESC LIMIT 2 OFFSET 1", []any{int64(3), int64(2)}},
+	} {
+		db.expectColumn(tc.sql, "id", tc.want...)
+	}
+
+	// NULLs count as equal; names come from the first query
+	db.expect("SELECT id AS k, name FROM a WHERE id = 3 UNION SELECT id, name FROM b WHERE id = 3",
+		row{"k": int64(3), "name": nil})
+	db.expect("SELECT 1 AS n UNION SELECT 1.0", row{"n": int64(1)})
+
+	db.expectErr("SELECT id FROM a UNION SELECT id, name FROM b")
+	db.expectErr("SELECT id FROM a UNION SELECT name FROM b")
+	db.expectErr("SELECT id FROM a UNION SELECT id FROM b ORDER BY id + 1")
+}
@@ -0,0 +1,135 @@
+package executor
+
+import (
+	"errors"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/planner"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// ErrSubqueryRows is returned when a scalar subquery yields several rows
+var ErrSubqueryRows = errors.New("more than one row returned by a subquery used as an expression")
+
+// query is the state of one statement execution: the storage transaction
+// every read and write goes through, the access paths chosen by the
+// planner and the results of uncorrelated subqueries, which are computed
+// once per statement
+type query struct {
+	tx     *storage.Tx
+	access planner.AccessPaths
+	subs   map[*parser.SelectStmt]*subResult
+	ctes   map[*parser.CTE]*subResult // materialized WITH queries
+	// outer is the environment of the trigger a statement of its body runs
+	// for, exposing NEW and OLD; nil at the top level
+	outer *env
+	depth int // how many triggers fired each other to run this statement
+	// changing holds the tables whose rows are being rewritten while a
+	// BEFORE trigger runs, which its statements must not change
+	changing map[string]bool
+	// notes collects the notifications of the statement and the triggers
+	// it fires, sent when it succeeds
+	notes *[]notification
+	sess  *Session // of the statement, nil when it has none
+}
+
+// subResult is the result of an uncorrelated subquery
+type subResult struct {
+	cols []string
+	rows []map[string]any
+	// set and sawNull index the single column for IN (SELECT ...), built
+	// on first use
+	set     map[string]bool
+	sawNull bool
+}
+
+// env exposes row under its table name and unqualified; outer is the
+// environment of the enclosing query, nil at the top level
+func (q *query) env(table string, row map[string]any, outer *env) *env {
+	return &env{row: row, tables: map[string]map[string]any{table: row}, q: q, outer: outer}
+}
+
+// subquery runs sel for the row of en. Uncorrelated subqueries do not
+// depend on the row and run only once.
+func (e *Executor) subquery(sel *parser.SelectStmt, correlated bool, en *env) (*subResult, error) {
+	q := en.q
+	if !correlated {
+		if res, ok := q.subs[sel]; ok {
+			return res, nil
+		}
+	}
+	cols, rows, err := e.selectRows(q, sel, en)
+	if err != nil {
+		return nil, err
+	}
+	res := &subResult{cols: cols, rows: rows}
+	if !correlated {
+		q.subs[sel] = res
+	}
+	return res, nil
+}
+
+// evalScalarSubquery returns the single value of a subquery, NULL when it
+// returns no row
+func (e *Executor) evalScalarSubquery(n *parser.SubqueryExpr, en *env) (any, error) {
+	res, err := e.subquery(n.Select, n.Correlated, en)
+	if err != nil {
+		return nil, err
+	}
+	switch len(res.rows) {
+	case 0:
+		return nil, nil
+	case 1:
+		return res.rows[0][res.cols[0]], nil
+	}
+	return nil, ErrSubqueryRows
+}
+
+func (e *Executor) evalExists(n *parser.ExistsExpr, en *env) (any, error) {
+	res, err := e.subquery(n.Select, n.Correlated, en)
+	if err != nil {
+		return nil, err
+	}
+	return len(res.rows) > 0, nil
+}
+
+// evalInSubquery implements x [NOT] IN (SELECT ...) with the same NULL
+// rules as a value list. An uncorrelated subquery is turned into a hash
+// set once; a correlated one is compared value by value for each row.
+func (e *Executor) evalInSubquery(n *parser.InExpr, v any, en *env) (any, error) {
+	res, err := e.subquery(n.Select, n.Correlated, en)
+	if err != nil {
+		return nil, err
+	}
+	if len(res.rows) == 0 {
+		return n.Not, nil
+	}
+	col := res.cols[0]
+	if n.Correlated {
+		vals := make([]any, len(res.rows))
+		for i, row := range res.rows {
+			vals[i] = row[col]
+		}
+		return inValues(n, v, vals)
+	}
+
+	if res.set == nil {
+		res.set = make(map[string]bool, len(res.rows))
+		for _, row := range res.rows {
+			if row[col] == nil {
+				res.sawNull = true
+				continue
+			}
+			res.set[groupKey([]any{row[col]})] = true
+		}
+	}
+	switch {
+	case v == nil:
+		return nil, nil
+	case res.set[groupKey([]any{v})]:
+		return !n.Not, nil
+	case res.sawNull:
+		return nil, nil
+	}
+	return n.Not, nil
+}
@@ -0,0 +1,46 @@
+package executor
+
+import "testing"
+
+func subqueryDB(t *testing.T) *testDB {
+	db := newTestDB(t)
+	db.mustExec("CREATE TABLE depts (id INT PRIMARY KEY, title TEXT)")
+	db.mustExec("CREATE TABLE users (id INT PRIMARY KEY, name TEXT, dept_id INT)")
+	db.mustExec("INSERT INTO depts (id, title) VALUES (1, 'eng'), (2, 'ops'), (3, 'hr')")
+	db.mustExec("INSERT INTO users (id, name, dept_id) VALUES (1, 'ann', 1), (2, 'bob', 1), (3, 'cid', 2), (4, 'dan', NULL)")
+	return db
+}
+
+func TestSubqueries(t *testing.T) {
+	db := subqueryDB(t)
+	db.expectColumn("SELECT name FROM users WHERE dept_id IN (SELECT id FROM depts WHERE title = 'eng')", "name", "ann", "bob")
+	db.expectColumn("SELECT title FROM depts d WHERE NOT EXISTS (SELECT 1 FROM users u WHERE u.dept_id = d.id)", "title", "hr")
+	db.expectColumn("SELECT title FROM depts d WHERE EXISTS (SELECT 1 FROM users u WHERE u.dept_id = d.id)", "title", "eng", "ops")
+	db.expect("SELECT title, (SELECT COUNT(*) FROM users WHERE dept_id = depts.id) AS staff FROM depts",
+		row{"title": "eng", "staff": int64(2)}, row{"title": "ops", "staff": int64(1)}, row{"title": "hr", "staff": int64(0)})
+	// no row gives NULL
+	db.expect("SELECT (SELECT name FROM users WHERE id = 9) AS n", row{"n": nil})
+	db.expect("SELECT * FROM (SELECT name, id * 2 AS doubled FROM users) AS p WHERE doubled > 4",
+		row{"name": "cid", "doubled": int64(6)}, row{"name": "dan", "doubled": int64(8)})
+	db.expectColumn("SELECT p.name FROM (SELECT name FROM users WHERE dept_id = 1) p", "name", "ann", "bob")
+	// NULL in the subquery result makes NOT IN unknown
+	db.expectColumn("SELECT title FROM depts WHERE id NOT IN (SELECT dept_id FROM users)", "title")
+	db.expectColumn("SELECT title FROM depts WHERE id NOT IN (SELECT dept_id FROM users WHERE dept_id IS NOT NULL)", "title", "hr")
+
+	db.expectErr("SELECT (SELECT id FROM users) AS x")
+	db.expectErr("SELECT (SELECT id, name FROM users WHERE id = 1) AS x")
+	db.expectErr("SELECT * FROM (SELECT id FROM users)")
+}
+
+func TestSubqueriesInWrites(t *testing.T) {
+	db := subqueryDB(t)
+	db.mustExec("DELETE FROM depts WHERE id NOT IN (SELECT dept_id FROM users WHERE dept_id IS NOT NULL)")
+	db.expectColumn("SELECT title FROM depts", "title", "eng", "ops")
+
+	db.mustExec("UPDATE users SET name = (SELECT title FROM depts WHERE depts.id = users.dept_id) WHERE dept_id IS NOT NULL")
+	db.expectColumn("SELECT name FROM users", "name", "eng", "eng", "ops", "dan")
+
+	// the subquery sees the table as it was before the statement
+	db.mustExec("INSERT INTO users (id, name, dept_id) SELECT id + 10, name, dept_id FROM users WHERE id < (SELECT MAX(id) FROM users)")
+	db.expectColumn("SELECT id FROM users", "id", int64(1), int64(2), int64(3), int64(4), int64(11), int64(12), int64(13))
+}
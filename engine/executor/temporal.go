@@ -0,0 +1,473 @@
+package executor
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// Interval is a span of time. Months and days are kept apart from the
+// fixed-length part because their length depends on the date they are
+// added to.
+type Interval struct {
+	Months int64
+	Days   int64
+	Micros int64
+}
+
+const microsPerDay = int64(24 * time.Hour / time.Microsecond)
+
+func (iv Interval) String() string {
+	var parts []string
+	plural := func(n int64, unit string) string {
+		if n == 1 || n == -1 {
+			return fmt.Sprintf("%d %s", n, unit)
+		}
+		return fmt.Sprintf("%d %ss", n, unit)
+	}
+	if y := iv.Months / 12; y != 0 {
+		parts = append(parts, plural(y, "year"))
+	}
+	if m := iv.Months % 12; m != 0 {
+		parts = append(parts, plural(m, "mon"))
+	}
+	if iv.Days != 0 {
+		parts = append(parts, plural(iv.Days, "day"))
+	}
+	if iv.Micros != 0 || len(parts) == 0 {
+		us := iv.Micros
+		sign := ""
+		if us < 0 {
+			sign, us = "-", -us
+		}
+		d := time.Duration(us) * time.Microsecond
+		clock := fmt.Sprintf("%s%02d:%02d:%02d", sign, int(d.Hours()), int(d.Minutes())%60, int(d.Seconds())%60)
+		if frac := us % 1e6; frac != 0 {
+			clock += strings.TrimRight(fmt.Sprintf(".%06d", frac), "0")
+		}
+		parts = append(parts, clock)
+	}
+	return strings.Join(parts, " ")
+}
+
+func (iv Interval) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Quote(iv.String())), nil
+}
+
+// approxMicros orders intervals assuming 30-day months
+func (iv Interval) approxMicros() int64 {
+	return (iv.Months*30+iv.Days)*microsPerDay + iv.Micros
+}
+
+func (iv Interval) negate() Interval {
+	return Interval{Months: -iv.Months, Days: -iv.Days, Micros: -iv.Micros}
+}
+
+func (iv Interval) add(o Interval) Interval {
+	return Interval{Months: iv.Months + o.Months, Days: iv.Days + o.Days, Micros: iv.Micros + o.Micros}
+}
+
+func (iv Interval) scale(f float64) Interval {
+	return Interval{
+		Months: int64(float64(iv.Months) * f),
+		Days:   int64(float64(iv.Days) * f),
+		Micros: int64(float64(iv.Micros) * f),
+	}
+}
+
+// addTo shifts t by the interval. Months go first and, as in PostgreSQL,
+// a day past the end of the month reached is clamped to its last day:
+// '2024-01-31' + 1 month is 2024-02-29.
+func (iv Interval) addTo(t time.Time) time.Time {
+	if iv.Months != 0 {
+		y, m, d := t.Date()
+		months := int64(m-1) + iv.Months
+		y += int(months / 12)
+		if months %= 12; months < 0 {
+			y, months = y-1, months+12
+		}
+		m = time.Month(months + 1)
+		if last := time.Date(y, m+1, 0, 0, 0, 0, 0, t.Location()).Day(); d > last {
+			d = last
+		}
+		hh, mm, ss := t.Clock()
+		t = time.Date(y, m, d, hh, mm, ss, t.Nanosecond(), t.Location())
+	}
+	return t.AddDate(0, 0, int(iv.Days)).Add(time.Duration(iv.Micros) * time.Microsecond)
+}
+
+var intervalUnits = map[string]Interval{
+	"microsecond": {Micros: 1},
+	"millisecond": {Micros: 1000},
+	"second":      {Micros: 1e6},
+	"sec":         {Micros: 1e6},
+	"minute":      {Micros: 60 * 1e6},
+	"min":         {Micros: 60 * 1e6},
+	"hour":        {Micros: 3600 * 1e6},
+	"day":         {Days: 1},
+	"week":        {Days: 7},
+	"month":       {Months: 1},
+	"mon":         {Months: 1},
+	"year":        {Months: 12},
+}
+
+// parseInterval accepts forms like '1 day', '2 hours 30 minutes',
+// '1 year 2 months' and '1 day 02:00:00'
+func parseInterval(s string) (Interval, error) {
+	var iv Interval
+	fields := strings.Fields(strings.ToLower(s))
+	if len(fields) == 0 {
+		return iv, fmt.Errorf("invalid input syntax for type interval: %q", s)
+	}
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if strings.Contains(f, ":") {
+			us, err := parseClock(f)
+			if err != nil {
+				return iv, fmt.Errorf("invalid input syntax for type interval: %q", s)
+			}
+			iv.Micros += us
+			continue
+		}
+		n, err := strconv.ParseFloat(f, 64)
+		if err != nil || i+1 >= len(fields) {
+			return iv, fmt.Errorf("invalid input syntax for type interval: %q", s)
+		}
+		i++
+		unit, ok := intervalUnits[strings.TrimSuffix(fields[i], "s")]
+		if !ok {
+			return iv, fmt.Errorf("invalid interval unit %q", fields[i])
+		}
+		iv = iv.add(unit.scale(n))
+	}
+	return iv, nil
+}
+
+// parseClock parses [-]hh:mm[:ss[.frac]] into microseconds
+func parseClock(s string) (int64, error) {
+	sign := int64(1)
+	if strings.HasPrefix(s, "-") {
+		sign, s = -1, s[1:]
+	}
+	parts := strings.Split(s, ":")
+	if len(parts) < 2 || len(parts) > 3 {
+		return 0, fmt.Errorf("bad clock")
+	}
+	h, err1 := strconv.ParseInt(parts[0], 10, 64)
+	m, err2 := strconv.ParseInt(parts[1], 10, 64)
+	if err1 != nil || err2 != nil {
+		return 0, fmt.Errorf("bad clock")
+	}
+	sec := 0.0
+	if len(parts) == 3 {
+		var err error
+		if sec, err = strconv.ParseFloat(parts[2], 64); err != nil {
+			return 0, fmt.Errorf("bad clock")
+		}
+	}
+	return sign * ((h*3600+m*60)*1e6 + int64(sec*1e6)), nil
+}
+
+// asTime extracts the instant of a TIMESTAMP or DATE value
+func asTime(v any) (time.Time, bool) {
+	switch t := v.(type) {
+	case storage.Timestamp:
+		return t.Time, true
+	case storage.Date:
+		return t.Time, true
+	}
+	return time.Time{}, false
+}
+
+// temporalArithmetic handles + and - involving dates, timestamps and
+// intervals. The second result is false when no operand is temporal.
+func temporalArithmetic(op string, a, b any) (any, bool, error) {
+	ia, aIv := a.(Interval)
+	ib, bIv := b.(Interval)
+	ta, aTime := asTime(a)
+	tb, bTime := asTime(b)
+	_, aDate := a.(storage.Date)
+	_, bDate := b.(storage.Date)
+	if !aIv && !bIv && !aTime && !bTime {
+		return nil, false, nil
+	}
+
+	switch {
+	case aTime && bIv && (op == "+" || op == "-"):
+		if op == "-" {
+			ib = ib.negate()
+		}
+		return storage.NewTimestamp(ib.addTo(ta)), true, nil
+	case aIv && bTime && op == "+":
+		return storage.NewTimestamp(ia.addTo(tb)), true, nil
+	case aIv && bIv && (op == "+" || op == "-"):
+		if op == "-" {
+			ib = ib.negate()
+		}
+		return ia.add(ib), true, nil
+	case aDate && isInt(b) && (op == "+" || op == "-"):
+		n, _ := toInt(b)
+		if op == "-" {
+			n = -n
+		}
+		return storage.NewDate(ta.AddDate(0, 0, int(n))), true, nil
+	case aDate && bDate && op == "-":
+		return int64(ta.Sub(tb).Hours() / 24), true, nil
+	case aTime && bTime && op == "-":
+		us := ta.Sub(tb).Microseconds()
+		return Interval{Days: us / microsPerDay, Micros: us % microsPerDay}, true, nil
+	case aIv && (op == "*" || op == "/"):
+		if f, ok := toFloat(b); ok {
+			if op == "/" {
+				if f == 0 {
+					return nil, true, fmt.Errorf("division by zero")
+				}
+				f = 1 / f
+			}
+			return ia.scale(f), true, nil
+		}
+	case bIv && op == "*":
+		if f, ok := toFloat(a); ok {
+			return ib.scale(f), true, nil
+		}
+	}
+	return nil, true, fmt.Errorf("operator %s cannot be applied to %s and %s", op, typeName(a), typeName(b))
+}
+
+// compareTemporal orders temporal values; strings are parsed as
+// timestamps so `created_at > '2024-01-01'` works
+func compareTemporal(a, b any) (int, bool, error) {
+	if ia, ok := a.(Interval); ok {
+		ib, ok := b.(Interval)
+		if !ok {
+			return 0, true, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+		}
+		x, y := ia.approxMicros(), ib.approxMicros()
+		return cmpInt(x, y), true, nil
+	}
+	ta, aok := asTime(a)
+	tb, bok := asTime(b)
+	if !aok && !bok {
+		return 0, false, nil
+	}
+	if !aok {
+		s, ok := a.(string)
+		if !ok {
+			return 0, true, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+		}
+		ts, err := storage.ParseTimestamp(s)
+		if err != nil {
+			return 0, true, err
+		}
+		ta = ts.Time
+	}
+	if !bok {
+		s, ok := b.(string)
+		if !ok {
+			return 0, true, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+		}
+		ts, err := storage.ParseTimestamp(s)
+		if err != nil {
+			return 0, true, err
+		}
+		tb = ts.Time
+	}
+	return ta.Compare(tb), true, nil
+}
+
+func cmpInt(x, y int64) int {
+	switch {
+	case x < y:
+		return -1
+	case x > y:
+		return 1
+	}
+	return 0
+}
+
+func fnNow(args []any) (any, error) {
+	return storage.NewTimestamp(time.Now()), nil
+}
+
+func fnCurrentDate(args []any) (any, error) {
+	return storage.NewDate(time.Now().UTC()), nil
+}
+
+// fnDateTrunc implements DATE_TRUNC(field, ts)
+func fnDateTrunc(args []any) (any, error) {
+	field, err := textArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := asTime(args[1])
+	if !ok {
+		return nil, fmt.Errorf("argument 2 must be TIMESTAMP or DATE, got %s", typeName(args[1]))
+	}
+	y, m, d := t.Date()
+	switch strings.ToLower(field) {
+	case "year":
+		t = time.Date(y, 1, 1, 0, 0, 0, 0, time.UTC)
+	case "quarter":
+		t = time.Date(y, (m-1)/3*3+1, 1, 0, 0, 0, 0, time.UTC)
+	case "month":
+		t = time.Date(y, m, 1, 0, 0, 0, 0, time.UTC)
+	case "week":
+		// weeks start on Monday
+		back := (int(t.Weekday()) + 6) % 7
+		t = time.Date(y, m, d-back, 0, 0, 0, 0, time.UTC)
+	case "day":
+		t = time.Date(y, m, d, 0, 0, 0, 0, time.UTC)
+	case "hour":
+		t = t.Truncate(time.Hour)
+	case "minute":
+		t = t.Truncate(time.Minute)
+	case "second":
+		t = t.Truncate(time.Second)
+	default:
+		return nil, fmt.Errorf("unit %q not recognized", field)
+	}
+	return storage.NewTimestamp(t), nil
+}
+
+// fnExtract implements EXTRACT(field FROM x) for dates, timestamps and
+// intervals; the parser passes the field name as the first argument
+func fnExtract(args []any) (any, error) {
+	field, err := textArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	field = strings.ToLower(field)
+
+	if iv, ok := args[1].(Interval); ok {
+		switch field {
+		case "epoch":
+			return float64(iv.approxMicros()) / 1e6, nil
+		case "year":
+			return iv.Months / 12, nil
+		case "month":
+			return iv.Months % 12, nil
+		case "day":
+			return iv.Days, nil
+		case "hour":
+			return iv.Micros / (3600 * 1e6), nil
+		case "minute":
+			return iv.Micros / (60 * 1e6) % 60, nil
+		case "second":
+			return float64(iv.Micros%(60*1e6)) / 1e6, nil
+		}
+		return nil, fmt.Errorf("unit %q not supported for interval", field)
+	}
+
+	t, ok := asTime(args[1])
+	if !ok {
+		return nil, fmt.Errorf("cannot extract from %s", typeName(args[1]))
+	}
+	switch field {
+	case "year":
+		return int64(t.Year()), nil
+	case "quarter":
+		return int64((t.Month()-1)/3 + 1), nil
+	case "month":
+		return int64(t.Month()), nil
+	case "week":
+		_, w := t.ISOWeek()
+		return int64(w), nil
+	case "day":
+		return int64(t.Day()), nil
+	case "hour":
+		return int64(t.Hour()), nil
+	case "minute":
+		return int64(t.Minute()), nil
+	case "second":
+		if t.Nanosecond() == 0 {
+			return int64(t.Second()), nil
+		}
+		return float64(t.Second()) + float64(t.Nanosecond())/1e9, nil
+	case "dow":
+		return int64(t.Weekday()), nil
+	case "isodow":
+		return int64((int(t.Weekday())+6)%7 + 1), nil
+	case "doy":
+		return int64(t.YearDay()), nil
+	case "epoch":
+		if t.Nanosecond() == 0 {
+			return t.Unix(), nil
+		}
+		return float64(t.UnixMicro()) / 1e6, nil
+	}
+	return nil, fmt.Errorf("unit %q not recognized", field)
+}
+
+// fnStrftime implements SQLite-style STRFTIME(format, ts)
+func fnStrftime(args []any) (any, error) {
+	format, err := textArg(args, 0)
+	if err != nil {
+		return nil, err
+	}
+	t, ok := asTime(args[1])
+	if !ok {
+		s, isText := args[1].(string)
+		if !isText {
+			return nil, fmt.Errorf("argument 2 must be TIMESTAMP or DATE, got %s", typeName(args[1]))
+		}
+		ts, err := storage.ParseTimestamp(s)
+		if err != nil {
+			return nil, err
+		}
+		t = ts.Time
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' || i+1 == len(format) {
+			b.WriteByte(format[i])
+			continue
+		}
+		i++
+		switch format[i] {
+		case 'Y':
+			b.WriteString(t.Format("2006"))
+		case 'y':
+			b.WriteString(t.Format("06"))
+		case 'm':
+			b.WriteString(t.Format("01"))
+		case 'd':
+			b.WriteString(t.Format("02"))
+		case 'H':
+			b.WriteString(t.Format("15"))
+		case 'I':
+			b.WriteString(t.Format("03"))
+		case 'p':
+			b.WriteString(t.Format("PM"))
+		case 'M':
+			b.WriteString(t.Format("04"))
+		case 'S':
+			b.WriteString(t.Format("05"))
+		case 'f':
+			b.WriteString(t.Format("05.000"))
+		case 'j':
+			fmt.Fprintf(&b, "%03d", t.YearDay())
+		case 'w':
+			fmt.Fprintf(&b, "%d", t.Weekday())
+		case 's':
+			fmt.Fprintf(&b, "%d", t.Unix())
+		case 'a':
+			b.WriteString(t.Format("Mon"))
+		case 'A':
+			b.WriteString(t.Format("Monday"))
+		case 'b':
+			b.WriteString(t.Format("Jan"))
+		case 'B':
+			b.WriteString(t.Format("January"))
+		case '%':
+			b.WriteByte('%')
+		default:
+			b.WriteByte('%')
+			b.WriteByte(format[i])
+		}
+	}
+	return b.String(), nil
+}
@@ -0,0 +1,32 @@
+package executor
+
+import (
+	"testing"
+	"time"
+)
+
+func TestIntervalMonthEnd(t *testing.T) {
+	date := func(y int, m time.Month, d int) time.Time { return time.Date(y, m, d, 0, 0, 0, 0, time.UTC) }
+	tests := []struct {
+		from time.Time
+		iv   Interval
+		want time.Time
+	}{
+		{date(2024, 1, 31), Interval{Months: 1}, date(2024, 2, 29)},
+		{date(2023, 1, 31), Interval{Months: 1}, date(2023, 2, 28)},
+		{date(2024, 2, 29), Interval{Months: 12}, date(2025, 2, 28)},
+		{date(2024, 2, 29), Interval{Months: 48}, date(2028, 2, 29)},
+		{date(2024, 3, 31), Interval{Months: -1}, date(2024, 2, 29)},
+		{date(2024, 5, 31), Interval{Months: -15}, date(2023, 2, 28)},
+		{date(2024, 8, 31), Interval{Months: 1}, date(2024, 9, 30)},
+		{date(2024, 12, 31), Interval{Months: 2}, date(2025, 2, 28)},
+		{date(2024, 1, 31), Interval{Months: 1, Days: 1}, date(2024, 3, 1)},
+		{date(2024, 1, 15), Interval{Months: 1}, date(2024, 2, 15)},
+		{time.Date(2024, 1, 31, 10, 30, 0, 0, time.UTC), Interval{Months: 1, Micros: 3600e6}, time.Date(2024, 2, 29, 11, 30, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		if got := tt.iv.addTo(tt.from); !got.Equal(tt.want) {
+			t.Errorf("%s + %s = %s, want %s", tt.from.Format(time.DateTime), tt.iv, got.Format(time.DateTime), tt.want.Format(time.DateTime))
+		}
+	}
+}
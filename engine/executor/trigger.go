@@ -0,0 +1,99 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/planner"
+)
+
+// maxTriggerDepth is how many levels deep triggers may fire each other
+// before the statement fails, which stops triggers that fire themselves
+const maxTriggerDepth = 32
+
+// fire runs trigs for one row: newRow is the row as it will be written,
+// oldRow the version it replaces or deletes, nil where the event has none.
+// SET NEW in a BEFORE trigger changes newRow in place. changing names the
+// table being rewritten while the trigger runs, "" when it runs after the
+// write.
+func (e *Executor) fire(q *query, trigs []*planner.PlanTrigger, changing string, oldRow, newRow map[string]any) error {
+	for _, t := range trigs {
+		if q.depth >= maxTriggerDepth {
+			return &triggerError{t.Name, fmt.Errorf("triggers nested more than %d levels deep", maxTriggerDepth)}
+		}
+		tq := q.child(changing)
+		tq.depth++
+		en := &env{tables: map[string]map[string]any{}, q: tq}
+		if oldRow != nil {
+			en.tables["old"] = oldRow
+		}
+		if newRow != nil {
+			en.tables["new"] = newRow
+		}
+		for _, plan := range t.Body {
+			var err error
+			if set, ok := plan.(*planner.PlanSetNew); ok {
+				var v any
+				if v, err = e.eval(set.Stmt.Value, en); err == nil {
+					newRow[set.Stmt.Column] = v
+				}
+			} else {
+				sq := tq.child("")
+				sq.outer = en
+				_, err = e.exec(sq, plan)
+			}
+			if err != nil {
+				var te *triggerError
+				if errors.As(err, &te) {
+					return err
+				}
+				return &triggerError{t.Name, err}
+			}
+		}
+	}
+	return nil
+}
+
+// triggerError reports the trigger whose body failed. Errors of nested
+// triggers are passed up as they are, naming the innermost one.
+type triggerError struct {
+	name string
+	err  error
+}
+
+func (e *triggerError) Error() string { return fmt.Sprintf("trigger %s: %v", e.name, e.err) }
+
+func (e *triggerError) Unwrap() error { return e.err }
+
+// child returns a fresh query in the transaction of q for a trigger and
+// the statements of its body, adding changing to the tables they may not
+// change
+func (q *query) child(changing string) *query {
+	c := &query{
+		tx:       q.tx,
+		subs:     map[*parser.SelectStmt]*subResult{},
+		ctes:     map[*parser.CTE]*subResult{},
+		depth:    q.depth,
+		changing: q.changing,
+		notes:    q.notes,
+		sess:     q.sess,
+	}
+	if changing != "" {
+		c.changing = make(map[string]bool, len(q.changing)+1)
+		for t := range q.changing {
+			c.changing[t] = true
+		}
+		c.changing[changing] = true
+	}
+	return c
+}
+
+// checkChanging fails when a trigger body changes a table whose rows are
+// being rewritten by the statement that fired it
+func (q *query) checkChanging(table string) error {
+	if q.changing[table] {
+		return fmt.Errorf("table %s is being changed by the statement that fired this trigger", table)
+	}
+	return nil
+}
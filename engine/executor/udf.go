@@ -0,0 +1,354 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"time"
+
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// Go functions registered by the embedding program are called through
+// reflection. A signature describes the parameters of such a function and
+// converts SQL values to and from Go values.
+
+var (
+	errorType     = reflect.TypeOf((*error)(nil)).Elem()
+	timeType      = reflect.TypeOf(time.Time{})
+	timestampType = reflect.TypeOf(storage.Timestamp{})
+	dateType      = reflect.TypeOf(storage.Date{})
+	intervalType  = reflect.TypeOf(Interval{})
+)
+
+// errNullArg reports a NULL argument for a parameter that cannot hold it
+var errNullArg = errors.New("null argument")
+
+type signature struct {
+	params   []reflect.Type // element type for the variadic parameter
+	variadic bool
+	result   bool // returns a value before the optional error
+}
+
+// newSignature validates the parameter and result types of ft. With
+// result set, ft must return a value and may add an error; otherwise it
+// may only return an error.
+func newSignature(ft reflect.Type, result bool) (*signature, error) {
+	sig := &signature{variadic: ft.IsVariadic(), result: result}
+	for i := 0; i < ft.NumIn(); i++ {
+		t := ft.In(i)
+		if sig.variadic && i == ft.NumIn()-1 {
+			t = t.Elem()
+		}
+		if !supportedParam(t) {
+			return nil, fmt.Errorf("unsupported parameter type %s", t)
+		}
+		sig.params = append(sig.params, t)
+	}
+
+	outs := ft.NumOut()
+	if outs > 0 && ft.Out(outs-1) == errorType {
+		outs--
+	}
+	switch {
+	case result && outs != 1:
+		return nil, fmt.Errorf("must return a value and optionally an error")
+	case !result && outs != 0:
+		return nil, fmt.Errorf("may only return an error")
+	case result && !supportedResult(ft.Out(0)):
+		return nil, fmt.Errorf("unsupported result type %s", ft.Out(0))
+	}
+	return sig, nil
+}
+
+func supportedParam(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool:
+		return true
+	case reflect.Interface:
+		return t.NumMethod() == 0
+	}
+	return t == timeType
+}
+
+// supportedResult reports whether fromGoValue can convert values of type t;
+// the dynamic type of an interface result is only known per call
+func supportedResult(t reflect.Type) bool {
+	switch t {
+	case timeType, timestampType, dateType, intervalType:
+		return true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64, reflect.String, reflect.Bool, reflect.Interface:
+		return true
+	case reflect.Pointer:
+		return supportedResult(t.Elem())
+	}
+	return false
+}
+
+// argRange returns the accepted argument counts (-1 for no maximum)
+func (s *signature) argRange() (int, int) {
+	if s.variadic {
+		return len(s.params) - 1, -1
+	}
+	return len(s.params), len(s.params)
+}
+
+// convertArgs converts SQL values to the parameter types, returning
+// errNullArg when a NULL meets a parameter that is not of type any
+func (s *signature) convertArgs(args []any) ([]reflect.Value, error) {
+	in := make([]reflect.Value, len(args))
+	for i, a := range args {
+		t := s.params[min(i, len(s.params)-1)]
+		if a == nil {
+			if t.Kind() != reflect.Interface {
+				return nil, errNullArg
+			}
+			in[i] = reflect.Zero(t)
+			continue
+		}
+		v, ok := toGoValue(a, t)
+		if !ok {
+			return nil, fmt.Errorf("argument %d must be %s, got %s", i+1, sqlTypeOf(t), typeName(a))
+		}
+		in[i] = v
+	}
+	return in, nil
+}
+
+func toGoValue(a any, t reflect.Type) (reflect.Value, bool) {
+	if t == timeType {
+		tm, ok := asTime(a)
+		return reflect.ValueOf(tm), ok
+	}
+	v := reflect.New(t).Elem()
+	switch t.Kind() {
+	case reflect.Interface:
+		v.Set(reflect.ValueOf(a))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := toInt(a)
+		if !ok || v.OverflowInt(n) {
+			return v, false
+		}
+		v.SetInt(n)
+	case reflect.Float32, reflect.Float64:
+		f, ok := toFloat(a)
+		if !ok {
+			return v, false
+		}
+		v.SetFloat(f)
+	case reflect.String:
+		s, ok := a.(string)
+		if !ok {
+			return v, false
+		}
+		v.SetString(s)
+	case reflect.Bool:
+		b, ok := a.(bool)
+		if !ok {
+			return v, false
+		}
+		v.SetBool(b)
+	default:
+		return v, false
+	}
+	return v, true
+}
+
+// sqlTypeOf names the SQL type accepted by a parameter
+func sqlTypeOf(t reflect.Type) string {
+	if t == timeType {
+		return "TIMESTAMP"
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return "INTEGER"
+	case reflect.Float32, reflect.Float64:
+		return "numeric"
+	case reflect.String:
+		return "TEXT"
+	case reflect.Bool:
+		return "BOOLEAN"
+	}
+	return t.String()
+}
+
+// fromGoValue converts a result back to the executor's value model
+func fromGoValue(v reflect.Value) (any, error) {
+	if !v.IsValid() {
+		return nil, nil
+	}
+	switch x := v.Interface().(type) {
+	case storage.Timestamp, storage.Date, Interval:
+		return x, nil
+	case time.Time:
+		return storage.NewTimestamp(x), nil
+	}
+	switch v.Kind() {
+	case reflect.Interface, reflect.Pointer:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return fromGoValue(v.Elem())
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int(), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if v.Uint() > math.MaxInt64 {
+			return nil, fmt.Errorf("%w: %d", ErrIntegerRange, v.Uint())
+		}
+		return int64(v.Uint()), nil
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), nil
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return v.Bool(), nil
+	}
+	return nil, fmt.Errorf("unsupported result type %s", v.Type())
+}
+
+// invoke calls fn, turning a panic into an error and splitting the
+// results into value and error
+func (s *signature) invoke(fn reflect.Value, in []reflect.Value) (res any, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	out := fn.Call(in)
+	if n := len(out); n > 0 && fn.Type().Out(n-1) == errorType {
+		if e := out[n-1].Interface(); e != nil {
+			return nil, e.(error)
+		}
+		out = out[:n-1]
+	}
+	if !s.result {
+		return nil, nil
+	}
+	return fromGoValue(out[0])
+}
+
+// goFunc is a registered scalar Go function
+type goFunc struct {
+	fn  reflect.Value
+	sig *signature
+}
+
+func newGoFunc(fn any) (*goFunc, error) {
+	v := reflect.ValueOf(fn)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("%T is not a function", fn)
+	}
+	sig, err := newSignature(v.Type(), true)
+	if err != nil {
+		return nil, err
+	}
+	return &goFunc{fn: v, sig: sig}, nil
+}
+
+func (g *goFunc) call(args []any) (any, error) {
+	in, err := g.sig.convertArgs(args)
+	if errors.Is(err, errNullArg) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return g.sig.invoke(g.fn, in)
+}
+
+// goAggregate is a registered aggregate whose state comes from a Go
+// factory function
+type goAggregate struct {
+	factory reflect.Value
+	step    *signature
+	done    *signature
+}
+
+func newGoAggregate(factory any) (*goAggregate, error) {
+	v := reflect.ValueOf(factory)
+	if v.Kind() != reflect.Func || v.IsNil() {
+		return nil, fmt.Errorf("factory %T is not a function", factory)
+	}
+	ft := v.Type()
+	if ft.NumIn() != 0 || ft.NumOut() != 1 {
+		return nil, fmt.Errorf("factory must take no arguments and return the aggregation state")
+	}
+	state := ft.Out(0)
+	step, ok := methodType(state, "Step")
+	if !ok {
+		return nil, fmt.Errorf("%s has no Step method", state)
+	}
+	done, ok := methodType(state, "Done")
+	if !ok {
+		return nil, fmt.Errorf("%s has no Done method", state)
+	}
+	agg := &goAggregate{factory: v}
+	var err error
+	if agg.step, err = newSignature(step, false); err != nil {
+		return nil, fmt.Errorf("Step: %w", err)
+	}
+	if done.NumIn() != 0 {
+		return nil, fmt.Errorf("Done must not take arguments")
+	}
+	if agg.done, err = newSignature(done, true); err != nil {
+		return nil, fmt.Errorf("Done: %w", err)
+	}
+	return agg, nil
+}
+
+// methodType returns the signature of method name of t without the receiver
+func methodType(t reflect.Type, name string) (reflect.Type, bool) {
+	m, ok := t.MethodByName(name)
+	if !ok {
+		return nil, false
+	}
+	if t.Kind() == reflect.Interface {
+		return m.Type, true
+	}
+	ins := make([]reflect.Type, 0, m.Type.NumIn()-1)
+	for i := 1; i < m.Type.NumIn(); i++ {
+		ins = append(ins, m.Type.In(i))
+	}
+	outs := make([]reflect.Type, m.Type.NumOut())
+	for i := range outs {
+		outs[i] = m.Type.Out(i)
+	}
+	return reflect.FuncOf(ins, outs, m.Type.IsVariadic()), true
+}
+
+func (g *goAggregate) instance() aggregator {
+	state := g.factory.Call(nil)[0]
+	return &goAggregator{
+		agg:  g,
+		step: state.MethodByName("Step"),
+		done: state.MethodByName("Done"),
+	}
+}
+
+type goAggregator struct {
+	agg  *goAggregate
+	step reflect.Value
+	done reflect.Value
+}
+
+func (a *goAggregator) Step(args []any) error {
+	in, err := a.agg.step.convertArgs(args)
+	if errors.Is(err, errNullArg) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	_, err = a.agg.step.invoke(a.step, in)
+	return err
+}
+
+func (a *goAggregator) Done() (any, error) {
+	return a.agg.done.invoke(a.done, nil)
+}
@@ -0,0 +1,36 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestRegisterFuncResultTypes(t *testing.T) {
+	db := newTestDB(t)
+	for name, fn := range map[string]any{
+		"slice": func() []int { return nil },
+		"smap":  func() map[string]int { return nil },
+		"chan":  func() (chan int, error) { return nil, nil },
+	} {
+		if err := db.ex.RegisterFunc(name, fn, true); err == nil {
+			t.Fatalf("%s: registered a function returning %T", name, fn)
+		}
+	}
+	if err := db.ex.RegisterAggregate("agg", func() *badDone { return &badDone{} }); err == nil {
+		t.Fatal("registered an aggregate whose Done returns a slice")
+	}
+
+	if err := db.ex.RegisterFunc("big", func(n int64) uint64 { return uint64(n) }, false); err != nil {
+		t.Fatal(err)
+	}
+	db.expect("SELECT big(7) AS b", row{"b": int64(7)})
+	if err := db.expectErr("SELECT big(-1)"); !errors.Is(err, ErrIntegerRange) {
+		t.Fatalf("uint64 %d: %v, want %v", uint64(math.MaxUint64), err, ErrIntegerRange)
+	}
+}
+
+type badDone struct{}
+
+func (*badDone) Step(int64)   {}
+func (*badDone) Done() []byte { return nil }
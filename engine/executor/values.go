@@ -0,0 +1,326 @@
+package executor
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// Value semantics shared by expression evaluation. Values are nil (NULL),
+// int64, float64, string, bool, storage.Timestamp, storage.Date or Interval;
+// rows decoded from storage use the same representation.
+
+// toFloat converts numeric values to float64
+func toFloat(v any) (float64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case float64:
+		return n, true
+	}
+	return 0, false
+}
+
+// toInt converts integral values to int64
+func toInt(v any) (int64, bool) {
+	switch n := v.(type) {
+	case int64:
+		return n, true
+	case int:
+		return int64(n), true
+	case float64:
+		if n == math.Trunc(n) {
+			return int64(n), true
+		}
+	}
+	return 0, false
+}
+
+func isInt(v any) bool {
+	switch v.(type) {
+	case int64, int:
+		return true
+	}
+	return false
+}
+
+// toText renders a value the way it is concatenated or printed
+func toText(v any) string {
+	switch n := v.(type) {
+	case string:
+		return n
+	case float64:
+		return strconv.FormatFloat(n, 'f', -1, 64)
+	case bool:
+		if n {
+			return "true"
+		}
+		return "false"
+	}
+	return fmt.Sprint(v)
+}
+
+// typeName names the SQL type of a value for error messages
+func typeName(v any) string {
+	switch v.(type) {
+	case nil:
+		return "NULL"
+	case int64, int:
+		return "INTEGER"
+	case float64:
+		return "REAL"
+	case string:
+		return "TEXT"
+	case bool:
+		return "BOOLEAN"
+	case storage.Timestamp:
+		return "TIMESTAMP"
+	case storage.Date:
+		return "DATE"
+	case Interval:
+		return "INTERVAL"
+	}
+	return fmt.Sprintf("%T", v)
+}
+
+// ErrIntegerRange is returned when integer arithmetic overflows 64 bits
+var ErrIntegerRange = errors.New("integer out of range")
+
+// arithmetic applies + - * / % with integer semantics when both
+// operands are integers. NULL operands yield NULL.
+func arithmetic(op string, a, b any) (any, error) {
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	if v, ok, err := temporalArithmetic(op, a, b); ok {
+		return v, err
+	}
+	if isInt(a) && isInt(b) {
+		x, _ := toInt(a)
+		y, _ := toInt(b)
+		switch op {
+		case "+":
+			if y > 0 && x > math.MaxInt64-y || y < 0 && x < math.MinInt64-y {
+				return nil, ErrIntegerRange
+			}
+			return x + y, nil
+		case "-":
+			if y < 0 && x > math.MaxInt64+y || y > 0 && x < math.MinInt64+y {
+				return nil, ErrIntegerRange
+			}
+			return x - y, nil
+		case "*":
+			p := x * y
+			if x != 0 && (p/x != y || x == -1 && y == math.MinInt64) {
+				return nil, ErrIntegerRange
+			}
+			return p, nil
+		case "/":
+			if y == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			if x == math.MinInt64 && y == -1 {
+				return nil, ErrIntegerRange
+			}
+			return x / y, nil
+		case "%":
+			if y == 0 {
+				return nil, fmt.Errorf("division by zero")
+			}
+			return x % y, nil
+		}
+	}
+	x, ok1 := toFloat(a)
+	y, ok2 := toFloat(b)
+	if !ok1 || !ok2 {
+		return nil, fmt.Errorf("operator %s cannot be applied to %s and %s", op, typeName(a), typeName(b))
+	}
+	switch op {
+	case "+":
+		return x + y, nil
+	case "-":
+		return x - y, nil
+	case "*":
+		return x * y, nil
+	case "/":
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return x / y, nil
+	case "%":
+		if y == 0 {
+			return nil, fmt.Errorf("division by zero")
+		}
+		return math.Mod(x, y), nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// compare orders two non-NULL values, returning -1, 0 or 1
+func compare(a, b any) (int, error) {
+	if c, ok, err := compareTemporal(a, b); ok {
+		return c, err
+	}
+	if x, ok := toFloat(a); ok {
+		if y, ok := toFloat(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	switch x := a.(type) {
+	case string:
+		if y, ok := b.(string); ok {
+			return strings.Compare(x, y), nil
+		}
+	case bool:
+		if y, ok := b.(bool); ok {
+			switch {
+			case x == y:
+				return 0, nil
+			case !x:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, fmt.Errorf("cannot compare %s with %s", typeName(a), typeName(b))
+}
+
+// comparison evaluates = <> < <= > >=; NULL operands yield NULL
+func comparison(op string, a, b any) (any, error) {
+	if a == nil || b == nil {
+		return nil, nil
+	}
+	c, err := compare(a, b)
+	if err != nil {
+		return nil, err
+	}
+	switch op {
+	case "=":
+		return c == 0, nil
+	case "<>":
+		return c != 0, nil
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	case ">=":
+		return c >= 0, nil
+	}
+	return nil, fmt.Errorf("unknown operator %s", op)
+}
+
+// toBool interprets a value as a condition; NULL is reported as not ok
+func toBool(v any) (val bool, ok bool, err error) {
+	switch b := v.(type) {
+	case nil:
+		return false, false, nil
+	case bool:
+		return b, true, nil
+	}
+	if n, ok := toFloat(v); ok {
+		return n != 0, true, nil
+	}
+	return false, false, fmt.Errorf("argument of type %s is not a boolean", typeName(v))
+}
+
+// valuesEqual compares two values for equality, handling numeric types
+func valuesEqual(a, b any) bool {
+	if a == nil || b == nil {
+		return false
+	}
+	c, err := compare(a, b)
+	return err == nil && c == 0
+}
+
+// castValue converts v to one of the canonical types
+func castValue(v any, typ string) (any, error) {
+	if v == nil {
+		return nil, nil
+	}
+	switch typ {
+	case "TEXT":
+		return toText(v), nil
+	case "INTEGER":
+		switch n := v.(type) {
+		case int64, int:
+			i, _ := toInt(n)
+			return i, nil
+		case float64:
+			return int64(math.Round(n)), nil
+		case bool:
+			if n {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		case string:
+			i, err := strconv.ParseInt(strings.TrimSpace(n), 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid input syntax for type integer: %q", n)
+			}
+			return i, nil
+		}
+	case "REAL":
+		if f, ok := toFloat(v); ok {
+			return f, nil
+		}
+		if s, ok := v.(string); ok {
+			f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid input syntax for type real: %q", s)
+			}
+			return f, nil
+		}
+	case "BOOLEAN":
+		switch n := v.(type) {
+		case bool:
+			return n, nil
+		case string:
+			switch strings.ToLower(strings.TrimSpace(n)) {
+			case "true", "t", "yes", "y", "1":
+				return true, nil
+			case "false", "f", "no", "n", "0":
+				return false, nil
+			}
+			return nil, fmt.Errorf("invalid input syntax for type boolean: %q", n)
+		}
+		if f, ok := toFloat(v); ok {
+			return f != 0, nil
+		}
+	case "TIMESTAMP":
+		if t, ok := asTime(v); ok {
+			return storage.NewTimestamp(t), nil
+		}
+		if s, ok := v.(string); ok {
+			return storage.ParseTimestamp(s)
+		}
+	case "DATE":
+		if t, ok := asTime(v); ok {
+			return storage.NewDate(t), nil
+		}
+		if s, ok := v.(string); ok {
+			return storage.ParseDate(s)
+		}
+	case "INTERVAL":
+		switch x := v.(type) {
+		case Interval:
+			return x, nil
+		case string:
+			return parseInterval(x)
+		}
+	}
+	return nil, fmt.Errorf("cannot cast %s to %s", typeName(v), typ)
+}
@@ -0,0 +1,53 @@
+package executor
+
+import (
+	"errors"
+	"math"
+	"testing"
+)
+
+func TestIntegerOverflow(t *testing.T) {
+	tests := []struct {
+		op   string
+		a, b int64
+	}{
+		{"+", math.MaxInt64, 1},
+		{"+", math.MinInt64, -1},
+		{"-", math.MinInt64, 1},
+		{"-", math.MaxInt64, -1},
+		{"-", 0, math.MinInt64},
+		{"*", math.MaxInt64, 2},
+		{"*", math.MinInt64, -1},
+		{"*", -1, math.MinInt64},
+		{"*", 1 << 32, 1 << 31},
+		{"/", math.MinInt64, -1},
+	}
+	for _, tt := range tests {
+		if v, err := arithmetic(tt.op, tt.a, tt.b); !errors.Is(err, ErrIntegerRange) {
+			t.Errorf("%d %s %d = %v, %v; want %v", tt.a, tt.op, tt.b, v, err, ErrIntegerRange)
+		}
+	}
+}
+
+func TestIntegerLimits(t *testing.T) {
+	tests := []struct {
+		op   string
+		a, b int64
+		want int64
+	}{
+		{"+", math.MaxInt64 - 1, 1, math.MaxInt64},
+		{"+", math.MinInt64 + 1, -1, math.MinInt64},
+		{"-", math.MinInt64 + 1, 1, math.MinInt64},
+		{"-", -1, math.MaxInt64, math.MinInt64},
+		{"*", math.MinInt64 / 2, 2, math.MinInt64},
+		{"*", math.MaxInt64, -1, -math.MaxInt64},
+		{"/", math.MinInt64, 1, math.MinInt64},
+		{"%", math.MinInt64, -1, 0},
+	}
+	for _, tt := range tests {
+		v, err := arithmetic(tt.op, tt.a, tt.b)
+		if err != nil || v != tt.want {
+			t.Errorf("%d %s %d = %v, %v; want %d", tt.a, tt.op, tt.b, v, err, tt.want)
+		}
+	}
+}
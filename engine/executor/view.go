@@ -0,0 +1,57 @@
+package executor
+
+import (
+	"github.com/Alwin18/nalarSQL/engine/planner"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// execCreateView stores a view in the catalog; a materialized view is
+// computed right away and its rows become the content of its table
+func (e *Executor) execCreateView(q *query, p *planner.PlanCreateView) error {
+	view := storage.ViewMeta{
+		Name:         p.Stmt.Name,
+		Query:        p.Stmt.Query,
+		Columns:      p.Columns,
+		Materialized: p.Stmt.Materialized,
+	}
+	if !view.Materialized {
+		return q.tx.CreateView(view, nil)
+	}
+	q.access = p.Access
+	_, rows, err := e.selectRows(q, p.Stmt.Select, nil)
+	if err != nil {
+		return err
+	}
+	view.Columns = resultTypes(p.Columns, rows)
+	return q.tx.CreateView(view, rows)
+}
+
+// execRefreshView recomputes a materialized view
+func (e *Executor) execRefreshView(q *query, p *planner.PlanRefreshView) error {
+	q.access = p.Access
+	_, rows, err := e.selectRows(q, p.Select, nil)
+	if err != nil {
+		return err
+	}
+	return q.tx.RefreshView(p.Stmt.Name, rows)
+}
+
+// resultTypes fills in the types the planner could not derive from the
+// first non-NULL value of each column, defaulting to TEXT
+func resultTypes(cols []storage.ColumnDefinition, rows []map[string]any) []storage.ColumnDefinition {
+	out := make([]storage.ColumnDefinition, len(cols))
+	for i, c := range cols {
+		out[i] = storage.ColumnDefinition{Name: c.Name, Type: c.Type}
+		if c.Type != "" {
+			continue
+		}
+		out[i].Type = "TEXT"
+		for _, row := range rows {
+			if v := row[c.Name]; v != nil {
+				out[i].Type = typeName(v)
+				break
+			}
+		}
+	}
+	return out
+}
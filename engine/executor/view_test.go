@@ -0,0 +1,82 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/planner"
+)
+
+func viewDB(t *testing.T) *testDB {
+	db := newTestDB(t)
+	db.mustExec("CREATE TABLE orders (id INT PRIMARY KEY, customer TEXT, amount INT)")
+	db.mustExec("INSERT INTO orders (id, customer, amount) VALUES (1, 'ann', 50), (2, 'bob', 150), (3, 'ann', 200)")
+	return db
+}
+
+func TestViews(t *testing.T) {
+	db := viewDB(t)
+	db.mustExec("CREATE VIEW big_orders AS SELECT id, customer, amount FROM orders WHERE amount > 100")
+	db.expectColumn("SELECT id FROM big_orders", "id", int64(2), int64(3))
+	db.expect("SELECT customer, COUNT(*) AS n FROM big_orders GROUP BY customer ORDER BY customer",
+		row{"customer": "ann", "n": int64(1)}, row{"customer": "bob", "n": int64(1)})
+
+	// a view is expanded when read, so it sees later writes
+	db.mustExec("INSERT INTO orders (id, customer, amount) VALUES (4, 'cid', 300)")
+	db.expectColumn("SELECT b.customer FROM big_orders b WHERE b.id > 2", "customer", "ann", "cid")
+
+	// views over views
+	db.mustExec("CREATE VIEW ann_big AS SELECT id FROM big_orders WHERE customer = 'ann'")
+	db.expectColumn("SELECT id FROM ann_big", "id", int64(3))
+	db.expectColumn("SELECT view_name FROM nalar_views ORDER BY view_name", "view_name", "ann_big", "big_orders")
+
+	db.expectErr("INSERT INTO big_orders (id, customer, amount) VALUES (9, 'x', 1)")
+	db.expectErr("UPDATE big_orders SET amount = 1")
+	db.expectErr("DELETE FROM big_orders")
+	db.expectErr("CREATE VIEW big_orders AS SELECT 1")
+	db.expectErr("CREATE TABLE big_orders (id INT)")
+	db.expectErr("DROP VIEW big_orders") // ann_big reads it
+
+	db.mustExec("DROP VIEW ann_big")
+	db.mustExec("DROP VIEW big_orders")
+	db.mustExec("DROP VIEW IF EXISTS big_orders")
+	db.expectErr("DROP VIEW big_orders")
+	db.expectErr("SELECT * FROM big_orders")
+}
+
+func TestMaterializedViews(t *testing.T) {
+	db := viewDB(t)
+	db.mustExec("CREATE MATERIALIZED VIEW sales AS SELECT customer, SUM(amount) AS total FROM orders GROUP BY customer")
+	db.expect("SELECT * FROM sales ORDER BY customer",
+		row{"customer": "ann", "total": int64(250)}, row{"customer": "bob", "total": int64(150)})
+
+	// only REFRESH changes it
+	db.mustExec("INSERT INTO orders (id, customer, amount) VALUES (4, 'bob', 10)")
+	db.expectColumn("SELECT total FROM sales WHERE customer = 'bob'", "total", int64(150))
+	db.mustExec("REFRESH MATERIALIZED VIEW sales")
+	db.expectColumn("SELECT total FROM sales WHERE customer = 'bob'", "total", int64(160))
+
+	// it can be indexed like a table, and the index survives a refresh
+	db.mustExec("CREATE INDEX sales_customer ON sales (customer)")
+	db.mustExec("REFRESH MATERIALIZED VIEW sales")
+	stmt, err := parser.Parse("SELECT total FROM sales WHERE customer = 'ann'")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := db.pl.Plan(stmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ps := plan.(*planner.PlanSelect); ps.Access[ps.Stmt] == nil {
+		t.Fatal("materialized view lookup does not use its index")
+	}
+	db.expectColumn("SELECT total FROM sales WHERE customer = 'ann'", "total", int64(250))
+
+	db.expectErr("INSERT INTO sales (customer, total) VALUES ('x', 1)")
+	db.expectErr("DELETE FROM sales")
+	db.expectErr("REFRESH MATERIALIZED VIEW orders")
+	db.expectErr("DROP VIEW sales")
+	db.mustExec("DROP MATERIALIZED VIEW sales")
+	db.expectErr("SELECT * FROM sales")
+	db.mustExec("DROP MATERIALIZED VIEW IF EXISTS sales")
+}
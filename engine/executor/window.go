@@ -0,0 +1,311 @@
+package executor
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+)
+
+// windowFunc is a built-in function that is only valid with OVER
+type windowFunc struct {
+	minArgs int
+	maxArgs int
+}
+
+var windowFuncs = map[string]windowFunc{
+	"ROW_NUMBER":  {0, 0},
+	"RANK":        {0, 0},
+	"DENSE_RANK":  {0, 0},
+	"LAG":         {1, 3},
+	"LEAD":        {1, 3},
+	"FIRST_VALUE": {1, 1},
+	"LAST_VALUE":  {1, 1},
+}
+
+// windowCalls collects the window calls of a projection list
+func windowCalls(items []parser.SelectItem) ([]*parser.FuncCall, error) {
+	var calls []*parser.FuncCall
+	var walk func(x parser.Expr, inWindow bool) error
+	walk = func(x parser.Expr, inWindow bool) error {
+		if n, ok := x.(*parser.FuncCall); ok && n.Over != nil {
+			if inWindow {
+				return fmt.Errorf("window function calls cannot be nested")
+			}
+			calls = append(calls, n)
+			inWindow = true
+		}
+		for _, sub := range parser.Subexprs(x) {
+			if err := walk(*sub, inWindow); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, it := range items {
+		if it.Star {
+			continue
+		}
+		if err := walk(it.Expr, false); err != nil {
+			return nil, err
+		}
+	}
+	return calls, nil
+}
+
+// evalWindows computes a window call for every row. Rows are split into
+// partitions by PARTITION BY and each partition is sorted by the window's
+// ORDER BY; the results are kept in the environments of the rows like
+// aggregate results.
+func (e *Executor) evalWindows(calls []*parser.FuncCall, envs []*env) error {
+	for _, c := range calls {
+		if err := e.checkWindowCall(c); err != nil {
+			return err
+		}
+		parts := map[string][]int{}
+		var order []string
+		keys := make([][]any, len(envs))
+		for i, en := range envs {
+			pk, err := e.evalList(c.Over.PartitionBy, en)
+			if err != nil {
+				return err
+			}
+			k := groupKey(pk)
+			if _, ok := parts[k]; !ok {
+				order = append(order, k)
+			}
+			parts[k] = append(parts[k], i)
+			if keys[i], err = e.evalList(orderExprs(c.Over.OrderBy), en); err != nil {
+				return err
+			}
+		}
+		for _, k := range order {
+			idx := parts[k]
+			var err error
+			sort.SliceStable(idx, func(a, b int) bool {
+				cmp, cerr := compareSortKeys(keys[idx[a]], keys[idx[b]], c.Over.OrderBy)
+				if cerr != nil && err == nil {
+					err = cerr
+				}
+				return cmp < 0
+			})
+			if err != nil {
+				return err
+			}
+			rows := make([]*env, len(idx))
+			peers := make([][]any, len(idx))
+			for j, i := range idx {
+				rows[j], peers[j] = envs[i], keys[i]
+			}
+			vals, err := e.windowPartition(c, rows, peers)
+			if err != nil {
+				return fmt.Errorf("function %s: %w", strings.ToLower(c.Name), err)
+			}
+			for j, en := range rows {
+				if en.aggs == nil {
+					en.aggs = map[*parser.FuncCall]any{}
+				}
+				en.aggs[c] = vals[j]
+			}
+		}
+	}
+	return nil
+}
+
+// checkWindowCall validates the function and argument count of a window call
+func (e *Executor) checkWindowCall(c *parser.FuncCall) error {
+	name := strings.ToLower(c.Name)
+	if c.Distinct {
+		return fmt.Errorf("DISTINCT is not implemented for window functions")
+	}
+	if wf, ok := windowFuncs[c.Name]; ok {
+		if c.Star {
+			return fmt.Errorf("function %s(*) does not exist", name)
+		}
+		if n := len(c.Args); n < wf.minArgs || n > wf.maxArgs {
+			return fmt.Errorf("function %s: wrong number of arguments (%d)", name, n)
+		}
+		return nil
+	}
+	if f, ok := e.funcs.aggregate(c.Name); ok {
+		return checkAggregateCall(c, f)
+	}
+	return fmt.Errorf("OVER specified, but %s is not a window function nor an aggregate function", name)
+}
+
+// windowPartition computes a window call over one sorted partition; keys
+// holds the ORDER BY values of each row, rows with equal keys are peers
+func (e *Executor) windowPartition(c *parser.FuncCall, rows []*env, keys [][]any) ([]any, error) {
+	n := len(rows)
+	// peer groups: first and last position of the group of each row
+	first, last := make([]int, n), make([]int, n)
+	for j := 0; j < n; j++ {
+		first[j] = j
+		if j > 0 {
+			cmp, err := compareSortKeys(keys[j-1], keys[j], c.Over.OrderBy)
+			if err != nil {
+				return nil, err
+			}
+			if cmp == 0 {
+				first[j] = first[j-1]
+			}
+		}
+	}
+	for j := n - 1; j >= 0; j-- {
+		last[j] = j
+		if j < n-1 && first[j+1] == first[j] {
+			last[j] = last[j+1]
+		}
+	}
+
+	out := make([]any, n)
+	switch c.Name {
+	case "ROW_NUMBER":
+		for j := range out {
+			out[j] = int64(j + 1)
+		}
+		return out, nil
+	case "RANK":
+		for j := range out {
+			out[j] = int64(first[j] + 1)
+		}
+		return out, nil
+	case "DENSE_RANK":
+		rank := int64(0)
+		for j := range out {
+			if first[j] == j {
+				rank++
+			}
+			out[j] = rank
+		}
+		return out, nil
+	case "LAG", "LEAD":
+		return e.windowShift(c, rows)
+	}
+
+	// the remaining functions work on the frame of each row
+	args := make([][]any, n)
+	for j, en := range rows {
+		var err error
+		if args[j], err = e.evalList(c.Args, en); err != nil {
+			return nil, err
+		}
+	}
+	frame := func(j int) (int, int) {
+		f := c.Over.Frame
+		if f == nil {
+			// without ORDER BY all rows are peers, so this is the
+			// whole partition
+			return 0, last[j]
+		}
+		return max(frameBound(f.Start, j, n), 0), min(frameBound(f.End, j, n), n-1)
+	}
+
+	spec, _ := e.funcs.aggregate(c.Name)
+	prevS, prevE := -1, -1
+	var prev any
+	for j := range out {
+		s, end := frame(j)
+		if s == prevS && end == prevE {
+			out[j] = prev
+			continue
+		}
+		var v any
+		switch {
+		case s > end:
+			if spec.new != nil {
+				// an empty frame aggregates no rows
+				agg := spec.new()
+				var err error
+				if v, err = agg.Done(); err != nil {
+					return nil, err
+				}
+			}
+		case c.Name == "FIRST_VALUE":
+			v = args[s][0]
+		case c.Name == "LAST_VALUE":
+			v = args[end][0]
+		default:
+			agg := spec.new()
+			for k := s; k <= end; k++ {
+				if err := agg.Step(args[k]); err != nil {
+					return nil, err
+				}
+			}
+			var err error
+			if v, err = agg.Done(); err != nil {
+				return nil, err
+			}
+		}
+		out[j], prev, prevS, prevE = v, v, s, end
+	}
+	return out, nil
+}
+
+// frameBound returns the partition position a frame bound refers to for
+// the row at position j, possibly outside the partition
+func frameBound(b parser.FrameBound, j, n int) int {
+	switch b.Kind {
+	case parser.UnboundedPreceding:
+		return 0
+	case parser.Preceding:
+		return j - int(min(b.Offset, int64(n)))
+	case parser.Following:
+		return j + int(min(b.Offset, int64(n)))
+	case parser.UnboundedFollowing:
+		return n - 1
+	}
+	return j
+}
+
+// windowShift implements LAG(x [, offset [, default]]) and LEAD, the value
+// of x offset rows before or after the current row
+func (e *Executor) windowShift(c *parser.FuncCall, rows []*env) ([]any, error) {
+	out := make([]any, len(rows))
+	for j, en := range rows {
+		offset := int64(1)
+		if len(c.Args) > 1 {
+			v, err := e.eval(c.Args[1], en)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				continue
+			}
+			n, ok := toInt(v)
+			if !ok {
+				return nil, fmt.Errorf("offset must be an integer, got %s", typeName(v))
+			}
+			offset = n
+		}
+		if c.Name == "LAG" {
+			offset = -offset
+		}
+		target := int64(j) + offset
+		if target < 0 || target >= int64(len(rows)) {
+			if len(c.Args) > 2 {
+				v, err := e.eval(c.Args[2], en)
+				if err != nil {
+					return nil, err
+				}
+				out[j] = v
+			}
+			continue
+		}
+		v, err := e.eval(c.Args[0], rows[target])
+		if err != nil {
+			return nil, err
+		}
+		out[j] = v
+	}
+	return out, nil
+}
+
+func orderExprs(items []parser.OrderItem) []parser.Expr {
+	xs := make([]parser.Expr, len(items))
+	for i, it := range items {
+		xs[i] = it.Expr
+	}
+	return xs
+}
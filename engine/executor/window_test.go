@@ -0,0 +1,61 @@
+package executor
+
+import "testing"
+
+func windowDB(t *testing.T) *testDB {
+	db := newTestDB(t)
+	db.mustExec("CREATE TABLE sales (id INT PRIMARY KEY, region TEXT, amount INT)")
+	db.mustExec(`INSERT INTO sales (id, region, amount) VALUES
+		(1, 'n', 10), (2, 's', 30), (3, 'n', 30), (4, 'n', 20), (5, 's', NULL)`)
+	return db
+}
+
+func TestRankingWindows(t *testing.T) {
+	db := windowDB(t)
+	db.expect("SELECT id, ROW_NUMBER() OVER (PARTITION BY region ORDER BY amount DESC) AS pos FROM sales ORDER BY id",
+		row{"id": int64(1), "pos": int64(3)}, row{"id": int64(2), "pos": int64(2)}, row{"id": int64(3), "pos": int64(1)},
+		row{"id": int64(4), "pos": int64(2)}, row{"id": int64(5), "pos": int64(1)})
+	// NULLs sort first with DESC; peers share a rank
+	db.expectColumn("SELECT RANK() OVER (ORDER BY amount DESC) AS r FROM sales ORDER BY id", "r",
+		int64(5), int64(2), int64(2), int64(4), int64(1))
+	db.expectColumn("SELECT DENSE_RANK() OVER (ORDER BY amount DESC) AS r FROM sales ORDER BY id", "r",
+		int64(4), int64(2), int64(2), int64(3), int64(1))
+}
+
+func TestValueWindows(t *testing.T) {
+	db := windowDB(t)
+	db.expectColumn("SELECT LAG(amount) OVER (ORDER BY id) AS v FROM sales", "v",
+		nil, int64(10), int64(30), int64(30), int64(20))
+	db.expectColumn("SELECT LAG(amount, 2, 0) OVER (ORDER BY id) AS v FROM sales", "v",
+		int64(0), int64(0), int64(10), int64(30), int64(30))
+	db.expectColumn("SELECT LEAD(id) OVER (PARTITION BY region ORDER BY id) AS v FROM sales ORDER BY id", "v",
+		int64(3), int64(5), int64(4), nil, nil)
+	db.expectColumn("SELECT FIRST_VALUE(id) OVER (PARTITION BY region ORDER BY id) AS v FROM sales ORDER BY id", "v",
+		int64(1), int64(2), int64(1), int64(1), int64(2))
+	// the default frame ends at the current row's peers
+	db.expectColumn("SELECT LAST_VALUE(id) OVER (ORDER BY region) AS v FROM sales ORDER BY id", "v",
+		int64(4), int64(5), int64(4), int64(4), int64(5))
+}
+
+func TestAggregateWindows(t *testing.T) {
+	db := windowDB(t)
+	db.expectColumn("SELECT SUM(amount) OVER (PARTITION BY region ORDER BY id) AS v FROM sales ORDER BY id", "v",
+		int64(10), int64(30), int64(40), int64(60), int64(30))
+	db.expectColumn("SELECT SUM(amount) OVER (PARTITION BY region) AS v FROM sales ORDER BY id", "v",
+		int64(60), int64(30), int64(60), int64(60), int64(30))
+	db.expectColumn("SELECT COUNT(*) OVER (ORDER BY amount) AS v FROM sales ORDER BY id", "v",
+		int64(1), int64(4), int64(4), int64(2), int64(5))
+	db.expectColumn("SELECT SUM(id) OVER (ORDER BY id ROWS BETWEEN 1 PRECEDING AND 1 FOLLOWING) AS v FROM sales", "v",
+		int64(3), int64(6), int64(9), int64(12), int64(9))
+	db.expectColumn("SELECT MAX(id) OVER (ORDER BY id ROWS BETWEEN CURRENT ROW AND UNBOUNDED FOLLOWING) AS v FROM sales", "v",
+		int64(5), int64(5), int64(5), int64(5), int64(5))
+	db.expectColumn("SELECT COUNT(*) OVER (ORDER BY id ROWS 2 PRECEDING) AS v FROM sales", "v",
+		int64(1), int64(2), int64(3), int64(3), int64(3))
+
+	// after GROUP BY, over the groups
+	db.expect("SELECT region, SUM(amount) AS total, RANK() OVER (ORDER BY SUM(amount) DESC) AS r FROM sales GROUP BY region ORDER BY region",
+		row{"region": "n", "total": int64(60), "r": int64(1)}, row{"region": "s", "total": int64(30), "r": int64(2)})
+
+	db.expectErr("SELECT id FROM sales WHERE ROW_NUMBER() OVER (ORDER BY id) = 1")
+	db.expectErr("SELECT LAG() OVER (ORDER BY id) FROM sales")
+}
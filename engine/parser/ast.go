@@ -7,42 +7,253 @@ type Statement interface {
 }
 
 type CreateTableStmt struct {
-	TableName string
-	Columns   []ColumnDef
+	TableName   string
+	Columns     []ColumnDef
+	PrimaryKey  []string   // table-level PRIMARY KEY (a, b)
+	Uniques     [][]string // table-level UNIQUE (a, b)
+	ForeignKeys []ForeignKeyDef
+	Checks      []CheckDef // table-level CHECK constraints
 }
 
-type ColumnDef struct {
-	Name string
-	Type string
+// CheckDef is [CONSTRAINT name] CHECK (expr). SQL keeps the source text of
+// Expr, which is what the table header stores.
+type CheckDef struct {
+	Name string // empty when not given
+	Expr Expr
+	SQL  string
 }
 
-type InsertStmt struct {
+// ForeignKeyDef is [CONSTRAINT name] FOREIGN KEY (cols) REFERENCES
+// table [(cols)] [ON DELETE action] [ON UPDATE action], or the REFERENCES
+// clause of a column. Actions are NO ACTION, RESTRICT, CASCADE or SET NULL.
+type ForeignKeyDef struct {
+	Name       string // empty when not given
+	Columns    []string
+	RefTable   string
+	RefColumns []string // empty for the primary key of RefTable
+	OnDelete   string
+	OnUpdate   string
+}
+
+// CreateIndexStmt is CREATE [UNIQUE] INDEX name ON table (cols)
+type CreateIndexStmt struct {
+	Name    string
 	Table   string
 	Columns []string
-	Values  []any
+	Unique  bool
+}
+
+type ColumnDef struct {
+	Name       string
+	Type       string
+	PrimaryKey bool
+	Unique     bool
+	NotNull    bool
+	References *ForeignKeyDef // REFERENCES table [(col)]
+	Default    Expr           // DEFAULT expr, nil when not given
+	DefaultSQL string         // source text of Default
+	Checks     []CheckDef     // CHECK (expr) of the column
+	// AutoIncrement is set by AUTO_INCREMENT and the SERIAL types: the
+	// column takes its values from a sequence owned by the table
+	AutoIncrement bool
+	// Generated is the expression of GENERATED ALWAYS AS (expr) [STORED |
+	// VIRTUAL]; a stored column is computed when a row is written, a
+	// virtual one whenever it is read
+	Generated    Expr
+	GeneratedSQL string // source text of Generated
+	Stored       bool
+}
+
+type InsertStmt struct {
+	Table      string
+	Columns    []string
+	Rows       [][]Expr    // VALUES (...), (...)
+	Select     *SelectStmt // INSERT ... SELECT, nil for VALUES
+	OnConflict *OnConflict
+	Returning  []SelectItem
+}
+
+// OnConflict is the ON CONFLICT (cols) DO NOTHING | DO UPDATE SET clause
+type OnConflict struct {
+	Columns   []string
+	DoNothing bool
+	Set       []Assignment
 }
 
 type SelectStmt struct {
-	Table   string
-	Columns []string // empty or ["*"] = all
+	With  *With
+	Table string      // empty for SELECT without FROM
+	From  *SelectStmt // FROM (SELECT ...) AS alias, Table is then empty
+	Alias string      // name the FROM source is visible as, if not Table
+	// Distinct removes duplicate result rows: SELECT DISTINCT
+	Distinct bool
+	Items    []SelectItem
+	Where    Expr
+	GroupBy  []Expr
+	Having   Expr
+	// Set combines two queries; Table through Having are then unused
+	Set     *SetOp
+	OrderBy []OrderItem
+	Limit   Expr
+	Offset  Expr
+	// CTE is set by the planner when Table names a WITH query
+	CTE *CTE
+}
+
+// SetOp is left UNION|INTERSECT|EXCEPT [ALL] right
+type SetOp struct {
+	Op    string
+	All   bool
+	Left  *SelectStmt
+	Right *SelectStmt
+}
+
+// With is the WITH [RECURSIVE] clause in front of a SELECT
+type With struct {
+	Recursive bool
+	CTEs      []*CTE
+}
+
+// CTE is one WITH query: name [(cols)] AS (SELECT ... [UNION ALL SELECT ...])
+type CTE struct {
+	Name    string
+	Columns []string // renames the result columns when given
+	Select  *SelectStmt
+	// Union is the term after the last UNION or UNION ALL of a WITH
+	// RECURSIVE query. It may read the CTE itself and is then repeated
+	// until it returns no new rows.
+	Union     *SelectStmt
+	UnionAll  bool // keep duplicate rows; UNION drops them
+	Recursive bool // set by the planner when Union reads the CTE
+}
+
+// SourceName is the name columns of the FROM source are qualified with
+func (s *SelectStmt) SourceName() string {
+	if s.Alias != "" {
+		return s.Alias
+	}
+	return s.Table
 }
 
 type UpdateStmt struct {
-	Table       string
-	Set         map[string]any
-	WhereColumn string
-	WhereValue  any
+	Table     string
+	Set       []Assignment
+	Where     Expr
+	Returning []SelectItem
 }
 
 type DeleteStmt struct {
-	Table       string
-	WhereColumn string
-	WhereValue  any
+	Table     string
+	Where     Expr
+	Returning []SelectItem
+}
+
+// CreateViewStmt is CREATE [MATERIALIZED] VIEW name AS query
+type CreateViewStmt struct {
+	Name         string
+	Select       *SelectStmt
+	Query        string // source text of the query, kept in the catalog
+	Materialized bool
+}
+
+// DropViewStmt is DROP [MATERIALIZED] VIEW [IF EXISTS] name
+type DropViewStmt struct {
+	Name         string
+	Materialized bool
+	IfExists     bool
+}
+
+// RefreshViewStmt is REFRESH MATERIALIZED VIEW name
+type RefreshViewStmt struct {
+	Name string
+}
+
+// CreateSequenceStmt is CREATE SEQUENCE name [START [WITH] n]
+// [INCREMENT [BY] n]
+type CreateSequenceStmt struct {
+	Name      string
+	Start     int64
+	Increment int64
+}
+
+// DropSequenceStmt is DROP SEQUENCE [IF EXISTS] name
+type DropSequenceStmt struct {
+	Name     string
+	IfExists bool
+}
+
+// CreateTriggerStmt is CREATE TRIGGER name {BEFORE|AFTER}
+// {INSERT|UPDATE|DELETE} ON table FOR EACH ROW BEGIN stmt; ... END
+type CreateTriggerStmt struct {
+	Name   string
+	Timing string // BEFORE or AFTER
+	Event  string // INSERT, UPDATE or DELETE
+	Table  string
+	Body   []Statement
+	Source string // source text of Body, kept in the catalog
+}
+
+// DropTriggerStmt is DROP TRIGGER [IF EXISTS] name
+type DropTriggerStmt struct {
+	Name     string
+	IfExists bool
+}
+
+// SetNewStmt is SET NEW.column = expr, which changes the row a BEFORE
+// INSERT or UPDATE trigger fires for
+type SetNewStmt struct {
+	Column string
+	Value  Expr
+}
+
+// ListenStmt is LISTEN channel, which subscribes the session to the
+// notifications sent on channel
+type ListenStmt struct {
+	Channel string
+}
+
+// UnlistenStmt is UNLISTEN {channel | *}; Channel is "" for *
+type UnlistenStmt struct {
+	Channel string
+}
+
+// NotifyStmt is NOTIFY channel [, payload]
+type NotifyStmt struct {
+	Channel string
+	Payload Expr // nil without a payload
+}
+
+// VacuumStmt is VACUUM [table]; Table is "" for every table
+type VacuumStmt struct {
+	Table string
+}
+
+// ShowTablesStmt lists user tables (SHOW TABLES)
+type ShowTablesStmt struct{}
+
+// DescribeStmt lists the columns of a table (DESCRIBE t)
+type DescribeStmt struct {
+	Table string
 }
 
 // Implement Statement interface marker methods
-func (*CreateTableStmt) stmt() {}
-func (*InsertStmt) stmt()      {}
-func (*SelectStmt) stmt()      {}
-func (*UpdateStmt) stmt()      {}
-func (*DeleteStmt) stmt()      {}
+func (*CreateTableStmt) stmt()    {}
+func (*CreateIndexStmt) stmt()    {}
+func (*InsertStmt) stmt()         {}
+func (*SelectStmt) stmt()         {}
+func (*UpdateStmt) stmt()         {}
+func (*DeleteStmt) stmt()         {}
+func (*ShowTablesStmt) stmt()     {}
+func (*DescribeStmt) stmt()       {}
+func (*CreateViewStmt) stmt()     {}
+func (*DropViewStmt) stmt()       {}
+func (*RefreshViewStmt) stmt()    {}
+func (*CreateSequenceStmt) stmt() {}
+func (*DropSequenceStmt) stmt()   {}
+func (*CreateTriggerStmt) stmt()  {}
+func (*DropTriggerStmt) stmt()    {}
+func (*SetNewStmt) stmt()         {}
+func (*ListenStmt) stmt()         {}
+func (*UnlistenStmt) stmt()       {}
+func (*NotifyStmt) stmt()         {}
+func (*VacuumStmt) stmt()         {}
@@ -0,0 +1,310 @@
+package parser
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Expr is a scalar expression evaluated per row by the executor
+type Expr interface {
+	expr() // marker method
+}
+
+// Literal is a constant number, string, boolean or NULL (nil)
+type Literal struct {
+	Value any
+	// Name is set by the planner on a literal it folded from a constant
+	// expression, so the result column keeps that expression's name
+	Name string
+}
+
+// ColumnRef references a column, optionally qualified (excluded.col)
+type ColumnRef struct {
+	Table string
+	Name  string
+	// Level is set by the planner: 0 for a column of the query the
+	// reference appears in, n for the nth enclosing query (correlation)
+	Level int
+}
+
+// BinaryExpr applies an infix operator: + - * / % || = <> < <= > >= AND OR,
+// or a regular expression match ~ ~* !~ !~* (REGEXP is ~)
+type BinaryExpr struct {
+	Op    string
+	Left  Expr
+	Right Expr
+}
+
+// UnaryExpr applies a prefix operator: - or NOT
+type UnaryExpr struct {
+	Op   string
+	Expr Expr
+}
+
+// FuncCall is a scalar, aggregate or window function call resolved by the
+// executor
+type FuncCall struct {
+	Name     string // upper-cased
+	Args     []Expr
+	Star     bool    // COUNT(*)
+	Distinct bool    // COUNT(DISTINCT x): aggregate each argument list once
+	Over     *Window // OVER (...) for window calls, nil otherwise
+}
+
+// Window is the OVER clause of a window call
+type Window struct {
+	PartitionBy []Expr
+	OrderBy     []OrderItem
+	Frame       *Frame // nil for the default frame
+}
+
+// OrderItem is one sort key of an ORDER BY list
+type OrderItem struct {
+	Expr Expr
+	Desc bool
+	// NullsFirst places NULLs before the other values; it defaults to Desc
+	// unless NULLS FIRST or NULLS LAST is given
+	NullsFirst bool
+	// Column is set by the planner when the key names a result column of
+	// the query or gives its position: the 1-based column number
+	Column int
+}
+
+// Frame is ROWS BETWEEN start AND end
+type Frame struct {
+	Start FrameBound
+	End   FrameBound
+}
+
+// FrameBound is one end of a window frame
+type FrameBound struct {
+	Kind   BoundKind
+	Offset int64 // rows for Preceding and Following
+}
+
+type BoundKind int
+
+const (
+	UnboundedPreceding BoundKind = iota
+	Preceding
+	CurrentRow
+	Following
+	UnboundedFollowing
+)
+
+// CastExpr converts a value to another type: CAST(x AS type)
+type CastExpr struct {
+	Expr Expr
+	Type string // normalized, see NormalizeType
+}
+
+// LikeExpr matches text against a pattern: x [NOT] LIKE|ILIKE pattern
+type LikeExpr struct {
+	Expr    Expr
+	Pattern Expr
+	Not     bool
+	Fold    bool // ILIKE: case-insensitive
+}
+
+// InExpr tests list membership: x [NOT] IN (a, b, ...) or
+// x [NOT] IN (SELECT ...)
+type InExpr struct {
+	Expr       Expr
+	List       []Expr
+	Select     *SelectStmt
+	Not        bool
+	Correlated bool // IN (SELECT ...) referencing outer columns
+}
+
+// SubqueryExpr is a scalar subquery: (SELECT ...) yielding one value
+type SubqueryExpr struct {
+	Select     *SelectStmt
+	Correlated bool // set by the planner when it references outer columns
+}
+
+// ExistsExpr tests whether a subquery returns any row: EXISTS (SELECT ...)
+type ExistsExpr struct {
+	Select     *SelectStmt
+	Correlated bool
+}
+
+// CaseExpr picks the result of the first WHEN that applies, or Else (NULL
+// when missing). In the searched form, CASE WHEN cond THEN x ..., a WHEN
+// applies when its condition is true; in the simple form, CASE operand WHEN
+// value THEN x ..., when its value equals the operand.
+type CaseExpr struct {
+	Operand Expr // nil for the searched form
+	Whens   []When
+	Else    Expr
+}
+
+// When is one WHEN ... THEN ... arm of a CASE expression
+type When struct {
+	Cond   Expr
+	Result Expr
+}
+
+// BetweenExpr tests x [NOT] BETWEEN low AND high, bounds included
+type BetweenExpr struct {
+	Expr Expr
+	Low  Expr
+	High Expr
+	Not  bool
+}
+
+// IsNullExpr tests x IS [NOT] NULL
+type IsNullExpr struct {
+	Expr Expr
+	Not  bool
+}
+
+// Assignment is a single col = expr pair of a SET list
+type Assignment struct {
+	Column string
+	Value  Expr
+}
+
+// SelectItem is one entry of a projection list (RETURNING, select list)
+type SelectItem struct {
+	Star  bool // *
+	Expr  Expr
+	Alias string
+}
+
+// Name is the result column name of a non-star item: its alias, or one
+// derived from the expression as PostgreSQL does (the column, function or
+// type name), "?column?" when there is none
+func (it SelectItem) Name() string {
+	if it.Alias != "" {
+		return it.Alias
+	}
+	return ExprName(it.Expr)
+}
+
+// ExprName is the column name PostgreSQL derives from an expression
+func ExprName(x Expr) string {
+	switch x := x.(type) {
+	case *Literal:
+		if x.Name != "" {
+			return x.Name
+		}
+	case *ColumnRef:
+		return x.Name
+	case *FuncCall:
+		return strings.ToLower(x.Name)
+	case *CastExpr:
+		if name := ExprName(x.Expr); name != "?column?" {
+			return name
+		}
+		return strings.ToLower(x.Type)
+	case *CaseExpr:
+		return "case"
+	case *ExistsExpr:
+		return "exists"
+	case *SubqueryExpr:
+		if items := x.Select.Items; len(items) == 1 && !items[0].Star {
+			return items[0].Name()
+		}
+	}
+	return "?column?"
+}
+
+// ColumnNames returns the result column names of a projection list, star
+// standing for the columns * expands to. Rows are keyed by column name, so
+// a name taken by an earlier column gets a numeric suffix: UPPER(a),
+// UPPER(b) become upper and upper_2.
+func ColumnNames(items []SelectItem, star []string) []string {
+	var names []string
+	for _, it := range items {
+		if it.Star {
+			names = append(names, star...)
+			continue
+		}
+		names = append(names, it.Name())
+	}
+	taken := make(map[string]bool, len(names))
+	for _, name := range names {
+		taken[name] = true
+	}
+	seen := make(map[string]bool, len(names))
+	for i, name := range names {
+		if !seen[name] {
+			seen[name] = true
+			continue
+		}
+		n := 2
+		for taken[fmt.Sprintf("%s_%d", name, n)] {
+			n++
+		}
+		names[i] = fmt.Sprintf("%s_%d", name, n)
+		taken[names[i]] = true
+		seen[names[i]] = true
+	}
+	return names
+}
+
+func (*Literal) expr()      {}
+func (*ColumnRef) expr()    {}
+func (*BinaryExpr) expr()   {}
+func (*UnaryExpr) expr()    {}
+func (*FuncCall) expr()     {}
+func (*CastExpr) expr()     {}
+func (*LikeExpr) expr()     {}
+func (*InExpr) expr()       {}
+func (*BetweenExpr) expr()  {}
+func (*IsNullExpr) expr()   {}
+func (*SubqueryExpr) expr() {}
+func (*ExistsExpr) expr()   {}
+func (*CaseExpr) expr()     {}
+
+// Subexprs returns pointers to the direct subexpressions of x, so that
+// walkers can inspect them or replace them in place. Subqueries are
+// statements of their own and are not descended into.
+func Subexprs(x Expr) []*Expr {
+	switch n := x.(type) {
+	case *BinaryExpr:
+		return []*Expr{&n.Left, &n.Right}
+	case *UnaryExpr:
+		return []*Expr{&n.Expr}
+	case *CastExpr:
+		return []*Expr{&n.Expr}
+	case *FuncCall:
+		subs := exprPtrs(n.Args)
+		if w := n.Over; w != nil {
+			subs = append(subs, exprPtrs(w.PartitionBy)...)
+			for i := range w.OrderBy {
+				subs = append(subs, &w.OrderBy[i].Expr)
+			}
+		}
+		return subs
+	case *LikeExpr:
+		return []*Expr{&n.Expr, &n.Pattern}
+	case *InExpr:
+		return append([]*Expr{&n.Expr}, exprPtrs(n.List)...)
+	case *BetweenExpr:
+		return []*Expr{&n.Expr, &n.Low, &n.High}
+	case *IsNullExpr:
+		return []*Expr{&n.Expr}
+	case *CaseExpr:
+		var subs []*Expr
+		if n.Operand != nil {
+			subs = append(subs, &n.Operand)
+		}
+		for i := range n.Whens {
+			subs = append(subs, &n.Whens[i].Cond, &n.Whens[i].Result)
+		}
+		if n.Else != nil {
+			subs = append(subs, &n.Else)
+		}
+		return subs
+	}
+	return nil
+}
+
+func exprPtrs(list []Expr) []*Expr {
+	out := make([]*Expr, len(list))
+	for i := range list {
+		out[i] = &list[i]
+	}
+	return out
+}
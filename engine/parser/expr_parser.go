@@ -0,0 +1,699 @@
+package parser
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Expression parsing by precedence climbing, lowest binding first:
+//
+//	OR
+//	AND
+//	NOT
+//	= <> < <= > >=, [NOT] LIKE/ILIKE/IN/BETWEEN/REGEXP, ~, IS [NOT] NULL
+//	||
+//	+ -
+//	* / %
+//	unary -
+
+// parseExpr parses a full scalar expression
+func (p *Parser) parseExpr() (Expr, error) {
+	return p.parseOr()
+}
+
+// ParseExpr parses a standalone expression, like the column defaults and
+// CHECK constraints stored in table headers
+func ParseExpr(sql string) (Expr, error) {
+	p := NewParser(NewLexer(sql))
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokEOF {
+		return nil, fmt.Errorf("unexpected %v after expression", p.cur)
+	}
+	return x, nil
+}
+
+func (p *Parser) parseOr() (Expr, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.Type == TokKeyword && p.cur.Value == "OR" {
+		p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: "OR", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseAnd() (Expr, error) {
+	left, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.Type == TokKeyword && p.cur.Value == "AND" {
+		p.next()
+		right, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: "AND", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseNot() (Expr, error) {
+	if p.cur.Type == TokKeyword && p.cur.Value == "NOT" {
+		p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &UnaryExpr{Op: "NOT", Expr: x}, nil
+	}
+	return p.parseComparison()
+}
+
+var comparisonOps = map[TokenType]string{
+	TokEqual: "=", TokNE: "<>", TokLT: "<", TokLE: "<=", TokGT: ">", TokGE: ">=",
+}
+
+// predicates that may be negated with an infix NOT
+var negatable = map[string]bool{"LIKE": true, "ILIKE": true, "IN": true, "BETWEEN": true, "REGEXP": true}
+
+func (p *Parser) parseComparison() (Expr, error) {
+	left, err := p.parseConcat()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if op, ok := comparisonOps[p.cur.Type]; ok {
+			p.next()
+			right, err := p.parseConcat()
+			if err != nil {
+				return nil, err
+			}
+			left = &BinaryExpr{Op: op, Left: left, Right: right}
+			continue
+		}
+		if p.cur.Type == TokMatch {
+			op := p.cur.Value
+			p.next()
+			right, err := p.parseConcat()
+			if err != nil {
+				return nil, err
+			}
+			left = &BinaryExpr{Op: op, Left: left, Right: right}
+			continue
+		}
+		if p.cur.Type != TokKeyword {
+			return left, nil
+		}
+		not := false
+		if p.cur.Value == "NOT" && p.peekT.Type == TokKeyword && negatable[p.peekT.Value] {
+			not = true
+			p.next()
+		}
+		pred, err := p.parsePredicate(left, not)
+		if err != nil {
+			return nil, err
+		}
+		if pred == nil {
+			if not {
+				return nil, fmt.Errorf("unexpected NOT")
+			}
+			return left, nil
+		}
+		left = pred
+	}
+}
+
+// parsePredicate parses the keyword predicate following x. It returns nil
+// when the current token does not start one, after which x is complete.
+func (p *Parser) parsePredicate(x Expr, not bool) (Expr, error) {
+	switch p.cur.Value {
+	case "LIKE", "ILIKE":
+		fold := p.cur.Value == "ILIKE"
+		p.next()
+		pattern, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		return &LikeExpr{Expr: x, Pattern: pattern, Not: not, Fold: fold}, nil
+	case "REGEXP":
+		p.next()
+		pattern, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		op := "~"
+		if not {
+			op = "!~"
+		}
+		return &BinaryExpr{Op: op, Left: x, Right: pattern}, nil
+	case "IN":
+		p.next()
+		if err := p.expect(TokLParen, ""); err != nil {
+			return nil, err
+		}
+		if startsSelect(p.cur) {
+			sel, err := p.parseSubquery()
+			if err != nil {
+				return nil, err
+			}
+			return &InExpr{Expr: x, Select: sel, Not: not}, nil
+		}
+		list, err := p.parseExprList()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(TokRParen, ""); err != nil {
+			return nil, err
+		}
+		return &InExpr{Expr: x, List: list, Not: not}, nil
+	case "BETWEEN":
+		// bounds bind tighter than AND, so the AND below is not logical
+		p.next()
+		low, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(TokKeyword, "AND"); err != nil {
+			return nil, err
+		}
+		high, err := p.parseConcat()
+		if err != nil {
+			return nil, err
+		}
+		return &BetweenExpr{Expr: x, Low: low, High: high, Not: not}, nil
+	case "IS":
+		p.next()
+		isNot := false
+		if p.cur.Type == TokKeyword && p.cur.Value == "NOT" {
+			isNot = true
+			p.next()
+		}
+		if err := p.expect(TokKeyword, "NULL"); err != nil {
+			return nil, err
+		}
+		return &IsNullExpr{Expr: x, Not: isNot}, nil
+	}
+	return nil, nil
+}
+
+func (p *Parser) parseConcat() (Expr, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.Type == TokConcat {
+		p.next()
+		right, err := p.parseAdditive()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: "||", Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseAdditive() (Expr, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.Type == TokPlus || p.cur.Type == TokMinus {
+		op := p.cur.Value
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseMultiplicative() (Expr, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.Type == TokStar || p.cur.Type == TokSlash || p.cur.Type == TokPercent {
+		op := p.cur.Value
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &BinaryExpr{Op: op, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *Parser) parseUnary() (Expr, error) {
+	switch p.cur.Type {
+	case TokMinus:
+		p.next()
+		x, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		// fold negative number literals right away
+		if lit, ok := x.(*Literal); ok {
+			switch v := lit.Value.(type) {
+			case int64:
+				return &Literal{Value: -v}, nil
+			case float64:
+				return &Literal{Value: -v}, nil
+			}
+		}
+		return &UnaryExpr{Op: "-", Expr: x}, nil
+	case TokPlus:
+		p.next()
+		return p.parseUnary()
+	}
+	return p.parsePrimary()
+}
+
+// parsePrimary parses literals, column references, function calls and
+// parenthesized expressions
+func (p *Parser) parsePrimary() (Expr, error) {
+	switch p.cur.Type {
+	case TokNumber:
+		lit, err := numberLiteral(p.cur.Value)
+		if err != nil {
+			return nil, err
+		}
+		p.next()
+		return lit, nil
+	case TokString:
+		v := p.cur.Value
+		p.next()
+		return &Literal{Value: v}, nil
+	case TokLParen:
+		p.next()
+		if startsSelect(p.cur) {
+			sel, err := p.parseSubquery()
+			if err != nil {
+				return nil, err
+			}
+			return &SubqueryExpr{Select: sel}, nil
+		}
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		if err := p.expect(TokRParen, ""); err != nil {
+			return nil, err
+		}
+		return x, nil
+	case TokKeyword:
+		switch p.cur.Value {
+		case "NULL":
+			p.next()
+			return &Literal{Value: nil}, nil
+		case "TRUE", "FALSE":
+			v := p.cur.Value == "TRUE"
+			p.next()
+			return &Literal{Value: v}, nil
+		case "CASE":
+			return p.parseCase()
+		}
+	case TokIdent:
+		name := p.cur.Value
+		upper := strings.ToUpper(name)
+		p.next()
+		switch p.cur.Type {
+		case TokLParen:
+			switch upper {
+			case "EXISTS":
+				if startsSelect(p.peekT) {
+					p.next()
+					sel, err := p.parseSubquery()
+					if err != nil {
+						return nil, err
+					}
+					return &ExistsExpr{Select: sel}, nil
+				}
+			case "CAST":
+				return p.parseCast()
+			case "EXTRACT":
+				return p.parseExtract()
+			}
+			return p.parseCall(name)
+		case TokString:
+			// typed literals: DATE '2024-01-31', TIMESTAMP '...', INTERVAL '1 day'
+			if upper == "DATE" || upper == "TIMESTAMP" || upper == "INTERVAL" {
+				lit := &Literal{Value: p.cur.Value}
+				p.next()
+				return &CastExpr{Expr: lit, Type: upper}, nil
+			}
+		case TokDot:
+			p.next()
+			if p.cur.Type != TokIdent {
+				return nil, fmt.Errorf("expected column name after %s.", name)
+			}
+			ref := &ColumnRef{Table: name, Name: p.cur.Value}
+			if p.trigger && (upper == "NEW" || upper == "OLD") {
+				ref.Table = strings.ToLower(name)
+			}
+			p.next()
+			return ref, nil
+		}
+		if upper == "CURRENT_DATE" || upper == "CURRENT_TIMESTAMP" {
+			return &FuncCall{Name: upper}, nil
+		}
+		return &ColumnRef{Name: name}, nil
+	}
+	return nil, fmt.Errorf("unexpected %v in expression", p.cur)
+}
+
+// parseCase parses CASE [operand] WHEN x THEN y ... [ELSE z] END
+func (p *Parser) parseCase() (Expr, error) {
+	p.next()
+	c := &CaseExpr{}
+	var err error
+	if p.cur.Type != TokKeyword || p.cur.Value != "WHEN" {
+		if c.Operand, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	for p.cur.Type == TokKeyword && p.cur.Value == "WHEN" {
+		p.next()
+		var w When
+		if w.Cond, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		if err := p.expect(TokKeyword, "THEN"); err != nil {
+			return nil, err
+		}
+		if w.Result, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+		c.Whens = append(c.Whens, w)
+	}
+	if len(c.Whens) == 0 {
+		return nil, fmt.Errorf("CASE requires at least one WHEN")
+	}
+	if p.cur.Type == TokKeyword && p.cur.Value == "ELSE" {
+		p.next()
+		if c.Else, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return c, p.expect(TokKeyword, "END")
+}
+
+// parseSubquery parses SELECT ...) after an opening parenthesis
+func (p *Parser) parseSubquery() (*SelectStmt, error) {
+	sel, err := p.parseSelect()
+	if err != nil {
+		return nil, err
+	}
+	return sel, p.expect(TokRParen, "")
+}
+
+// parseCall parses the argument list of name(...) and a following OVER
+// clause
+func (p *Parser) parseCall(name string) (Expr, error) {
+	call, err := p.parseArgs(name)
+	if err != nil {
+		return nil, err
+	}
+	if p.isWord("OVER") {
+		p.next()
+		if call.Over, err = p.parseWindow(); err != nil {
+			return nil, err
+		}
+	}
+	return call, nil
+}
+
+func (p *Parser) parseArgs(name string) (*FuncCall, error) {
+	if err := p.expect(TokLParen, ""); err != nil {
+		return nil, err
+	}
+	call := &FuncCall{Name: strings.ToUpper(name)}
+	if p.cur.Type == TokKeyword && p.cur.Value == "DISTINCT" {
+		p.next()
+		call.Distinct = true
+	} else if p.cur.Type == TokRParen {
+		p.next()
+		return call, nil
+	}
+	if p.cur.Type == TokStar && !call.Distinct {
+		// COUNT(*)
+		p.next()
+		call.Star = true
+		return call, p.expect(TokRParen, "")
+	}
+	for {
+		arg, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		call.Args = append(call.Args, arg)
+		if p.cur.Type == TokRParen {
+			p.next()
+			return call, nil
+		}
+		if err := p.expect(TokComma, ""); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseWindow parses (PARTITION BY exprs ORDER BY keys ROWS frame), every
+// part optional
+func (p *Parser) parseWindow() (*Window, error) {
+	if err := p.expect(TokLParen, ""); err != nil {
+		return nil, err
+	}
+	w := &Window{}
+	var err error
+	if p.isWord("PARTITION") {
+		p.next()
+		if err := p.expect(TokKeyword, "BY"); err != nil {
+			return nil, err
+		}
+		if w.PartitionBy, err = p.parseExprList(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isWord("ORDER") {
+		p.next()
+		if w.OrderBy, err = p.parseOrderBy(); err != nil {
+			return nil, err
+		}
+	}
+	if p.isWord("ROWS") {
+		p.next()
+		if w.Frame, err = p.parseFrame(); err != nil {
+			return nil, err
+		}
+	}
+	return w, p.expect(TokRParen, "")
+}
+
+// parseOrderBy parses BY expr [ASC|DESC] [NULLS FIRST|LAST], ...
+func (p *Parser) parseOrderBy() ([]OrderItem, error) {
+	if err := p.expect(TokKeyword, "BY"); err != nil {
+		return nil, err
+	}
+	var items []OrderItem
+	for {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		item := OrderItem{Expr: x}
+		switch {
+		case p.isWord("ASC"):
+			p.next()
+		case p.isWord("DESC"):
+			item.Desc = true
+			p.next()
+		}
+		item.NullsFirst = item.Desc
+		if p.isWord("NULLS") {
+			p.next()
+			switch {
+			case p.isWord("FIRST"):
+				item.NullsFirst = true
+			case p.isWord("LAST"):
+				item.NullsFirst = false
+			default:
+				return nil, fmt.Errorf("expected FIRST or LAST, got %v", p.cur)
+			}
+			p.next()
+		}
+		items = append(items, item)
+		if p.cur.Type != TokComma {
+			return items, nil
+		}
+		p.next()
+	}
+}
+
+// parseFrame parses the frame after ROWS: BETWEEN start AND end, or a
+// start alone, which ends at the current row
+func (p *Parser) parseFrame() (*Frame, error) {
+	between := p.cur.Type == TokKeyword && p.cur.Value == "BETWEEN"
+	if between {
+		p.next()
+	}
+	start, err := p.parseFrameBound()
+	if err != nil {
+		return nil, err
+	}
+	f := &Frame{Start: start, End: FrameBound{Kind: CurrentRow}}
+	if between {
+		if err := p.expect(TokKeyword, "AND"); err != nil {
+			return nil, err
+		}
+		if f.End, err = p.parseFrameBound(); err != nil {
+			return nil, err
+		}
+	}
+	switch {
+	case f.Start.Kind == UnboundedFollowing:
+		return nil, fmt.Errorf("frame start cannot be UNBOUNDED FOLLOWING")
+	case f.End.Kind == UnboundedPreceding:
+		return nil, fmt.Errorf("frame end cannot be UNBOUNDED PRECEDING")
+	case f.End.Kind < f.Start.Kind:
+		return nil, fmt.Errorf("frame ending row cannot come before its starting row")
+	}
+	return f, nil
+}
+
+// parseFrameBound parses UNBOUNDED PRECEDING|FOLLOWING, CURRENT ROW or
+// n PRECEDING|FOLLOWING
+func (p *Parser) parseFrameBound() (FrameBound, error) {
+	var b FrameBound
+	switch {
+	case p.isWord("UNBOUNDED"):
+		p.next()
+		b.Kind = UnboundedPreceding
+		if p.isWord("FOLLOWING") {
+			b.Kind = UnboundedFollowing
+		} else if !p.isWord("PRECEDING") {
+			return b, fmt.Errorf("expected PRECEDING or FOLLOWING after UNBOUNDED")
+		}
+	case p.isWord("CURRENT"):
+		p.next()
+		b.Kind = CurrentRow
+		if !p.isWord("ROW") {
+			return b, fmt.Errorf("expected ROW after CURRENT")
+		}
+	case p.cur.Type == TokNumber:
+		n, err := strconv.ParseInt(p.cur.Value, 10, 64)
+		if err != nil {
+			return b, fmt.Errorf("frame offset must be a non-negative integer")
+		}
+		p.next()
+		b.Offset = n
+		b.Kind = Preceding
+		if p.isWord("FOLLOWING") {
+			b.Kind = Following
+		} else if !p.isWord("PRECEDING") {
+			return b, fmt.Errorf("expected PRECEDING or FOLLOWING after frame offset")
+		}
+	default:
+		return b, fmt.Errorf("expected frame bound, got %v", p.cur)
+	}
+	p.next()
+	return b, nil
+}
+
+// parseCast parses the (x AS type) part of CAST
+func (p *Parser) parseCast() (Expr, error) {
+	if err := p.expect(TokLParen, ""); err != nil {
+		return nil, err
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(TokKeyword, "AS"); err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected type name in CAST")
+	}
+	typ, ok := NormalizeType(p.cur.Value)
+	if !ok {
+		return nil, fmt.Errorf("unknown type %s in CAST", p.cur.Value)
+	}
+	p.next()
+	if err := p.expect(TokRParen, ""); err != nil {
+		return nil, err
+	}
+	return &CastExpr{Expr: x, Type: typ}, nil
+}
+
+// parseExtract parses (field FROM expr) into EXTRACT('field', expr)
+func (p *Parser) parseExtract() (Expr, error) {
+	if err := p.expect(TokLParen, ""); err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected field name in EXTRACT")
+	}
+	field := &Literal{Value: strings.ToLower(p.cur.Value)}
+	p.next()
+	if err := p.expect(TokKeyword, "FROM"); err != nil {
+		return nil, err
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	if err := p.expect(TokRParen, ""); err != nil {
+		return nil, err
+	}
+	return &FuncCall{Name: "EXTRACT", Args: []Expr{field, x}}, nil
+}
+
+// NormalizeType maps type names and their common aliases to the canonical
+// INTEGER, REAL, TEXT, BOOLEAN, TIMESTAMP, DATE or INTERVAL
+func NormalizeType(name string) (string, bool) {
+	switch strings.ToUpper(name) {
+	case "INTEGER", "INT", "BIGINT", "SMALLINT":
+		return "INTEGER", true
+	case "REAL", "FLOAT", "DOUBLE", "NUMERIC", "DECIMAL":
+		return "REAL", true
+	case "TEXT", "VARCHAR", "CHAR", "STRING":
+		return "TEXT", true
+	case "BOOLEAN", "BOOL":
+		return "BOOLEAN", true
+	case "TIMESTAMP", "DATETIME", "TIMESTAMPTZ":
+		return "TIMESTAMP", true
+	case "DATE":
+		return "DATE", true
+	case "INTERVAL":
+		return "INTERVAL", true
+	}
+	return "", false
+}
+
+func numberLiteral(s string) (*Literal, error) {
+	if strings.Contains(s, ".") {
+		f, err := strconv.ParseFloat(s, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %s", s)
+		}
+		return &Literal{Value: f}, nil
+	}
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid number %s", s)
+	}
+	return &Literal{Value: n}, nil
+}
@@ -0,0 +1,18 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestColumnNames(t *testing.T) {
+	stmt, err := Parse("SELECT *, id, UPPER(a), UPPER(b) AS upper_2, UPPER(c), CAST(1 AS INT), a + 1, a - 1 FROM t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := ColumnNames(stmt.(*SelectStmt).Items, []string{"id", "a"})
+	want := []string{"id", "a", "id_2", "upper", "upper_2", "upper_3", "integer", "?column?", "?column?_2"}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+}
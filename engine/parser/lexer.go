@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"fmt"
 	"strings"
 	"unicode"
 )
@@ -20,12 +21,58 @@ const (
 	TokRParen  TokenType = ")"
 	TokStar    TokenType = "*"
 	TokEqual   TokenType = "="
+	TokDot     TokenType = "."
+	TokPlus    TokenType = "+"
+	TokMinus   TokenType = "-"
+	TokSlash   TokenType = "/"
+	TokPercent TokenType = "%"
+	TokConcat  TokenType = "||"
+	TokLT      TokenType = "<"
+	TokGT      TokenType = ">"
+	TokLE      TokenType = "<="
+	TokGE      TokenType = ">="
+	TokNE      TokenType = "<>"
+	TokMatch   TokenType = "~" // regex match: ~ ~* !~ !~*
+	TokSemi    TokenType = ";"
 	TokKeyword TokenType = "KEYWORD"
 )
 
 type Token struct {
 	Type  TokenType
 	Value string
+	Pos   int // offset of the token in the input, in runes
+}
+
+// String describes the token for error messages, with its position
+// counted in characters from 1
+func (t Token) String() string {
+	if t.Type == TokEOF {
+		return fmt.Sprintf("end of input at position %d", t.Pos+1)
+	}
+	text := t.Value
+	if text == "" {
+		text = string(t.Type)
+	}
+	return fmt.Sprintf("'%s' at position %d", text, t.Pos+1)
+}
+
+// describe names what a token of type tt with value val would be
+func describe(tt TokenType, val string) string {
+	switch {
+	case val != "":
+		return "'" + val + "'"
+	case tt == TokEOF:
+		return "end of input"
+	case tt == TokIdent:
+		return "identifier"
+	case tt == TokNumber:
+		return "number"
+	case tt == TokString:
+		return "string"
+	case tt == TokKeyword:
+		return "keyword"
+	}
+	return "'" + string(tt) + "'"
 }
 
 type Lexer struct {
@@ -80,9 +127,24 @@ func (l *Lexer) readNumber() string {
 	for unicode.IsDigit(l.peek()) {
 		out = append(out, l.next())
 	}
+	// optional fractional part
+	if l.peek() == '.' && l.pos+1 < len(l.input) && unicode.IsDigit(l.input[l.pos+1]) {
+		out = append(out, l.next())
+		for unicode.IsDigit(l.peek()) {
+			out = append(out, l.next())
+		}
+	}
 	return string(out)
 }
 
+// peekAt returns the rune n positions ahead without consuming it
+func (l *Lexer) peekAt(n int) rune {
+	if l.pos+n >= len(l.input) {
+		return 0
+	}
+	return l.input[l.pos+n]
+}
+
 func (l *Lexer) readString() string {
 	l.next() // skip initial quote
 	var out []rune
@@ -98,6 +160,18 @@ func (l *Lexer) readString() string {
 
 func (l *Lexer) NextToken() Token {
 	l.skipSpaces()
+	pos := l.pos
+	t := l.lex()
+	t.Pos = pos
+	return t
+}
+
+// source returns the input between two offsets, e.g. of two tokens
+func (l *Lexer) source(from, to int) string {
+	return strings.TrimSpace(string(l.input[from:to]))
+}
+
+func (l *Lexer) lex() Token {
 	ch := l.peek()
 
 	switch {
@@ -106,6 +180,9 @@ func (l *Lexer) NextToken() Token {
 	case ch == ',':
 		l.next()
 		return Token{Type: TokComma, Value: ","}
+	case ch == ';':
+		l.next()
+		return Token{Type: TokSemi, Value: ";"}
 	case ch == '(':
 		l.next()
 		return Token{Type: TokLParen, Value: "("}
@@ -118,11 +195,67 @@ func (l *Lexer) NextToken() Token {
 	case ch == '=':
 		l.next()
 		return Token{Type: TokEqual, Value: "="}
+	case ch == '.':
+		l.next()
+		return Token{Type: TokDot, Value: "."}
+	case ch == '+':
+		l.next()
+		return Token{Type: TokPlus, Value: "+"}
+	case ch == '-':
+		l.next()
+		return Token{Type: TokMinus, Value: "-"}
+	case ch == '/':
+		l.next()
+		return Token{Type: TokSlash, Value: "/"}
+	case ch == '%':
+		l.next()
+		return Token{Type: TokPercent, Value: "%"}
+	case ch == '|' && l.peekAt(1) == '|':
+		l.pos += 2
+		return Token{Type: TokConcat, Value: "||"}
+	case ch == '<':
+		l.next()
+		switch l.peek() {
+		case '=':
+			l.next()
+			return Token{Type: TokLE, Value: "<="}
+		case '>':
+			l.next()
+			return Token{Type: TokNE, Value: "<>"}
+		}
+		return Token{Type: TokLT, Value: "<"}
+	case ch == '>':
+		l.next()
+		if l.peek() == '=' {
+			l.next()
+			return Token{Type: TokGE, Value: ">="}
+		}
+		return Token{Type: TokGT, Value: ">"}
+	case ch == '!' && l.peekAt(1) == '=':
+		l.pos += 2
+		return Token{Type: TokNE, Value: "<>"}
+	case ch == '~' || (ch == '!' && l.peekAt(1) == '~'):
+		op := "~"
+		if ch == '!' {
+			op = "!~"
+			l.next()
+		}
+		l.next()
+		if l.peek() == '*' {
+			l.next()
+			op += "*"
+		}
+		return Token{Type: TokMatch, Value: op}
 	case unicode.IsLetter(ch):
 		ident := l.readIdent()
 		upper := strings.ToUpper(ident)
 		switch upper {
-		case "SELECT", "INSERT", "INTO", "VALUES", "CREATE", "TABLE", "WHERE", "SET", "FROM", "UPDATE", "DELETE":
+		case "SELECT", "INSERT", "INTO", "VALUES", "CREATE", "TABLE", "WHERE", "SET", "FROM", "UPDATE", "DELETE",
+			"SHOW", "TABLES", "DESCRIBE", "ON", "CONFLICT", "DO", "NOTHING",
+			"RETURNING", "AS", "AND", "OR", "NOT", "NULL", "TRUE", "FALSE",
+			"GROUP", "BY", "HAVING", "LIKE", "ILIKE", "IN", "BETWEEN", "IS", "REGEXP",
+			"WITH", "RECURSIVE", "UNION", "ALL", "INTERSECT", "EXCEPT", "ORDER", "LIMIT", "OFFSET",
+			"DISTINCT", "CASE", "WHEN", "THEN", "ELSE", "END", "DROP":
 			return Token{Type: TokKeyword, Value: upper}
 		default:
 			return Token{Type: TokIdent, Value: ident}
@@ -143,5 +276,15 @@ func (l *Lexer) NextToken() Token {
 func Parse(sql string) (Statement, error) {
 	l := NewLexer(sql)
 	p := NewParser(l)
-	return p.ParseStatement()
+	stmt, err := p.ParseStatement()
+	if err != nil {
+		return nil, err
+	}
+	for p.cur.Type == TokSemi {
+		p.next()
+	}
+	if p.cur.Type != TokEOF {
+		return nil, fmt.Errorf("unexpected %v after the end of the statement", p.cur)
+	}
+	return stmt, nil
 }
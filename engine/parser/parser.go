@@ -10,6 +10,9 @@ type Parser struct {
 	l     *Lexer
 	cur   Token
 	peekT Token
+	// trigger is set while parsing a trigger body, where the NEW and OLD
+	// rows are referenced in any case and bound as new and old
+	trigger bool
 }
 
 func NewParser(l *Lexer) *Parser {
@@ -26,7 +29,7 @@ func (p *Parser) next() {
 
 func (p *Parser) expect(tt TokenType, val string) error {
 	if p.cur.Type != tt || (val != "" && strings.ToUpper(p.cur.Value) != val) {
-		return fmt.Errorf("expected %s '%s', got %v '%s'", tt, val, p.cur.Type, p.cur.Value)
+		return fmt.Errorf("expected %s, got %v", describe(tt, val), p.cur)
 	}
 	p.next()
 	return nil
@@ -38,11 +41,15 @@ func (p *Parser) ParseStatement() (Statement, error) {
 	if p.cur.Type == TokEOF {
 		return nil, ErrUnsupportedSQL
 	}
+	if p.cur.Type == TokLParen {
+		// (SELECT ...) UNION ...
+		return p.parseSelect()
+	}
 	if p.cur.Type == TokKeyword {
 		switch p.cur.Value {
 		case "INSERT":
 			return p.parseInsert()
-		case "SELECT":
+		case "SELECT", "WITH":
 			return p.parseSelect()
 		case "UPDATE":
 			return p.parseUpdate()
@@ -51,58 +58,776 @@ func (p *Parser) ParseStatement() (Statement, error) {
 		case "CREATE":
 			// delegate to previous simplistic behavior
 			return p.parseCreate()
+		case "SHOW":
+			return p.parseShow()
+		case "DESCRIBE":
+			return p.parseDescribe()
+		case "DROP":
+			switch strings.ToUpper(p.peekT.Value) {
+			case "SEQUENCE":
+				return p.parseDropSequence()
+			case "TRIGGER":
+				return p.parseDropTrigger()
+			}
+			return p.parseDropView()
 		}
 	}
+	switch {
+	case p.isWord("REFRESH"):
+		return p.parseRefreshView()
+	case p.isWord("LISTEN"):
+		return p.parseListen()
+	case p.isWord("UNLISTEN"):
+		return p.parseUnlisten()
+	case p.isWord("NOTIFY"):
+		return p.parseNotify()
+	case p.isWord("VACUUM"):
+		return p.parseVacuum()
+	}
 	return nil, ErrUnsupportedSQL
 }
 
-func (p *Parser) parseCreate() (*CreateTableStmt, error) {
-	// very simple: CREATE TABLE name (col TYPE [constraints], ...)
+func (p *Parser) parseCreate() (Statement, error) {
 	if err := p.expect(TokKeyword, "CREATE"); err != nil {
 		return nil, err
 	}
+	if p.isWord("INDEX") || p.isWord("UNIQUE") {
+		return p.parseCreateIndex()
+	}
+	if p.isWord("VIEW") || p.isWord("MATERIALIZED") {
+		return p.parseCreateView()
+	}
+	if p.isWord("SEQUENCE") {
+		return p.parseCreateSequence()
+	}
+	if p.isWord("TRIGGER") {
+		return p.parseCreateTrigger()
+	}
+	return p.parseCreateTable()
+}
+
+// parseCreateSequence parses SEQUENCE name [START [WITH] n] [INCREMENT
+// [BY] n], in any order. A sequence counts up from 1 by default, or down
+// from -1 when the increment is negative.
+func (p *Parser) parseCreateSequence() (*CreateSequenceStmt, error) {
+	p.next()
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected sequence name")
+	}
+	stmt := &CreateSequenceStmt{Name: p.cur.Value, Increment: 1}
+	p.next()
+	hasStart := false
+	for {
+		var err error
+		switch {
+		case p.isWord("START"):
+			p.next()
+			if p.cur.Type == TokKeyword && p.cur.Value == "WITH" {
+				p.next()
+			}
+			stmt.Start, err = p.parseSignedInt()
+			hasStart = true
+		case p.isWord("INCREMENT"):
+			p.next()
+			if p.cur.Type == TokKeyword && p.cur.Value == "BY" {
+				p.next()
+			}
+			if stmt.Increment, err = p.parseSignedInt(); err == nil && stmt.Increment == 0 {
+				err = fmt.Errorf("INCREMENT must not be zero")
+			}
+		default:
+			if !hasStart {
+				stmt.Start = 1
+				if stmt.Increment < 0 {
+					stmt.Start = -1
+				}
+			}
+			return stmt, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// parseSignedInt parses an integer literal with an optional minus sign
+func (p *Parser) parseSignedInt() (int64, error) {
+	neg := p.cur.Type == TokMinus
+	if neg {
+		p.next()
+	}
+	if p.cur.Type != TokNumber {
+		return 0, fmt.Errorf("expected integer, got %v", p.cur)
+	}
+	n, err := strconv.ParseInt(p.cur.Value, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid integer %s", p.cur.Value)
+	}
+	p.next()
+	if neg {
+		n = -n
+	}
+	return n, nil
+}
+
+// parseDropSequence parses DROP SEQUENCE [IF EXISTS] name
+func (p *Parser) parseDropSequence() (*DropSequenceStmt, error) {
+	p.next()
+	p.next()
+	stmt := &DropSequenceStmt{}
+	if p.isWord("IF") {
+		p.next()
+		if !p.isWord("EXISTS") {
+			return nil, fmt.Errorf("expected EXISTS after IF")
+		}
+		p.next()
+		stmt.IfExists = true
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected sequence name")
+	}
+	stmt.Name = p.cur.Value
+	p.next()
+	return stmt, nil
+}
+
+// parseCreateTrigger parses TRIGGER name {BEFORE|AFTER}
+// {INSERT|UPDATE|DELETE} ON table FOR EACH ROW BEGIN stmt; ... END
+func (p *Parser) parseCreateTrigger() (*CreateTriggerStmt, error) {
+	p.next()
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected trigger name")
+	}
+	stmt := &CreateTriggerStmt{Name: p.cur.Value}
+	p.next()
+	if !p.isWord("BEFORE") && !p.isWord("AFTER") {
+		return nil, fmt.Errorf("expected BEFORE or AFTER")
+	}
+	stmt.Timing = strings.ToUpper(p.cur.Value)
+	p.next()
+	if !p.isWord("INSERT") && !p.isWord("UPDATE") && !p.isWord("DELETE") {
+		return nil, fmt.Errorf("expected INSERT, UPDATE or DELETE")
+	}
+	stmt.Event = strings.ToUpper(p.cur.Value)
+	p.next()
+	if err := p.expect(TokKeyword, "ON"); err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected table name")
+	}
+	stmt.Table = p.cur.Value
+	p.next()
+	for _, w := range []string{"FOR", "EACH", "ROW"} {
+		if !p.isWord(w) {
+			if w == "ROW" && p.isWord("STATEMENT") {
+				return nil, fmt.Errorf("only FOR EACH ROW triggers are supported")
+			}
+			return nil, fmt.Errorf("expected FOR EACH ROW")
+		}
+		p.next()
+	}
+	if !p.isWord("BEGIN") {
+		return nil, fmt.Errorf("expected BEGIN")
+	}
+	p.next()
+	start := p.cur.Pos
+	body, err := p.parseTriggerBody()
+	if err != nil {
+		return nil, err
+	}
+	if !p.isWord("END") {
+		return nil, fmt.Errorf("expected END, got %v", p.cur)
+	}
+	stmt.Body, stmt.Source = body, p.l.source(start, p.cur.Pos)
+	p.next()
+	return stmt, nil
+}
+
+// parseTriggerBody parses statements separated by semicolons up to END or
+// the end of the input
+func (p *Parser) parseTriggerBody() ([]Statement, error) {
+	p.trigger = true
+	defer func() { p.trigger = false }()
+
+	var body []Statement
+	for !p.isWord("END") && p.cur.Type != TokEOF {
+		var stmt Statement
+		var err error
+		switch {
+		case p.isWord("SET"):
+			stmt, err = p.parseSetNew()
+		case p.isWord("INSERT"), p.isWord("UPDATE"), p.isWord("DELETE"), p.isWord("NOTIFY"), startsSelect(p.cur):
+			stmt, err = p.ParseStatement()
+		default:
+			return nil, fmt.Errorf("unsupported statement in trigger body: %s", p.cur.Value)
+		}
+		if err != nil {
+			return nil, err
+		}
+		body = append(body, stmt)
+		if p.cur.Type != TokSemi {
+			break
+		}
+		p.next()
+	}
+	if len(body) == 0 {
+		return nil, fmt.Errorf("trigger body must not be empty")
+	}
+	return body, nil
+}
+
+// parseSetNew parses SET NEW.column = expr
+func (p *Parser) parseSetNew() (*SetNewStmt, error) {
+	p.next()
+	if !p.isWord("NEW") {
+		return nil, fmt.Errorf("expected NEW after SET")
+	}
+	p.next()
+	if err := p.expect(TokDot, ""); err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected column name after NEW.")
+	}
+	stmt := &SetNewStmt{Column: p.cur.Value}
+	p.next()
+	if err := p.expect(TokEqual, ""); err != nil {
+		return nil, err
+	}
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Value = x
+	return stmt, nil
+}
+
+// ParseTriggerBody parses the body of a trigger as stored in the catalog
+func ParseTriggerBody(sql string) ([]Statement, error) {
+	p := NewParser(NewLexer(sql))
+	body, err := p.parseTriggerBody()
+	if err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokEOF {
+		return nil, fmt.Errorf("unexpected %v after trigger body", p.cur)
+	}
+	return body, nil
+}
+
+// parseListen parses LISTEN channel
+func (p *Parser) parseListen() (*ListenStmt, error) {
+	p.next()
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected channel name")
+	}
+	stmt := &ListenStmt{Channel: p.cur.Value}
+	p.next()
+	return stmt, nil
+}
+
+// parseUnlisten parses UNLISTEN {channel | *}
+func (p *Parser) parseUnlisten() (*UnlistenStmt, error) {
+	p.next()
+	stmt := &UnlistenStmt{}
+	switch p.cur.Type {
+	case TokStar:
+	case TokIdent:
+		stmt.Channel = p.cur.Value
+	default:
+		return nil, fmt.Errorf("expected channel name or *")
+	}
+	p.next()
+	return stmt, nil
+}
+
+// parseNotify parses NOTIFY channel [, payload]. Unlike PostgreSQL the
+// payload may be any expression, so that a trigger can send values of the
+// row it fires for.
+func (p *Parser) parseNotify() (*NotifyStmt, error) {
+	p.next()
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected channel name")
+	}
+	stmt := &NotifyStmt{Channel: p.cur.Value}
+	p.next()
+	if p.cur.Type != TokComma {
+		return stmt, nil
+	}
+	p.next()
+	x, err := p.parseExpr()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Payload = x
+	return stmt, nil
+}
+
+// parseVacuum parses VACUUM [table]
+func (p *Parser) parseVacuum() (*VacuumStmt, error) {
+	p.next()
+	stmt := &VacuumStmt{}
+	if p.cur.Type == TokIdent {
+		stmt.Table = p.cur.Value
+		p.next()
+	}
+	return stmt, nil
+}
+
+// parseDropTrigger parses DROP TRIGGER [IF EXISTS] name
+func (p *Parser) parseDropTrigger() (*DropTriggerStmt, error) {
+	p.next()
+	p.next()
+	stmt := &DropTriggerStmt{}
+	if p.isWord("IF") {
+		p.next()
+		if !p.isWord("EXISTS") {
+			return nil, fmt.Errorf("expected EXISTS after IF")
+		}
+		p.next()
+		stmt.IfExists = true
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected trigger name")
+	}
+	stmt.Name = p.cur.Value
+	p.next()
+	return stmt, nil
+}
+
+// parseCreateView parses [MATERIALIZED] VIEW name AS query
+func (p *Parser) parseCreateView() (*CreateViewStmt, error) {
+	stmt := &CreateViewStmt{Materialized: p.isWord("MATERIALIZED")}
+	name, err := p.parseViewName(stmt.Materialized)
+	if err != nil {
+		return nil, err
+	}
+	stmt.Name = name
+	if err := p.expect(TokKeyword, "AS"); err != nil {
+		return nil, err
+	}
+	if !startsSelect(p.cur) && p.cur.Type != TokLParen {
+		return nil, fmt.Errorf("expected SELECT after AS")
+	}
+	start := p.cur.Pos
+	if stmt.Select, err = p.parseSelect(); err != nil {
+		return nil, err
+	}
+	stmt.Query = p.l.source(start, p.cur.Pos)
+	return stmt, nil
+}
+
+// parseViewName parses [MATERIALIZED] VIEW name
+func (p *Parser) parseViewName(materialized bool) (string, error) {
+	if materialized {
+		p.next()
+	}
+	if !p.isWord("VIEW") {
+		return "", fmt.Errorf("expected VIEW")
+	}
+	p.next()
+	if p.cur.Type != TokIdent {
+		return "", fmt.Errorf("expected view name")
+	}
+	name := p.cur.Value
+	p.next()
+	return name, nil
+}
+
+// parseDropView parses DROP [MATERIALIZED] VIEW [IF EXISTS] name
+func (p *Parser) parseDropView() (*DropViewStmt, error) {
+	p.next()
+	stmt := &DropViewStmt{Materialized: p.isWord("MATERIALIZED")}
+	if stmt.Materialized {
+		p.next()
+	}
+	if !p.isWord("VIEW") {
+		return nil, fmt.Errorf("expected VIEW after DROP")
+	}
+	if p.peekT.Type == TokIdent && strings.ToUpper(p.peekT.Value) == "IF" {
+		p.next()
+		p.next()
+		if !p.isWord("EXISTS") {
+			return nil, fmt.Errorf("expected EXISTS after IF")
+		}
+		stmt.IfExists = true
+	}
+	p.next()
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected view name")
+	}
+	stmt.Name = p.cur.Value
+	p.next()
+	return stmt, nil
+}
+
+// parseRefreshView parses REFRESH MATERIALIZED VIEW name
+func (p *Parser) parseRefreshView() (*RefreshViewStmt, error) {
+	p.next()
+	if !p.isWord("MATERIALIZED") {
+		return nil, fmt.Errorf("expected MATERIALIZED after REFRESH")
+	}
+	name, err := p.parseViewName(true)
+	if err != nil {
+		return nil, err
+	}
+	return &RefreshViewStmt{Name: name}, nil
+}
+
+// parseCreateIndex parses [UNIQUE] INDEX name ON table (cols)
+func (p *Parser) parseCreateIndex() (*CreateIndexStmt, error) {
+	stmt := &CreateIndexStmt{}
+	if p.isWord("UNIQUE") {
+		stmt.Unique = true
+		p.next()
+	}
+	if !p.isWord("INDEX") {
+		return nil, fmt.Errorf("expected INDEX")
+	}
+	p.next()
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected index name")
+	}
+	stmt.Name = p.cur.Value
+	p.next()
+	if err := p.expect(TokKeyword, "ON"); err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected table name")
+	}
+	stmt.Table = p.cur.Value
+	p.next()
+	cols, err := p.parseIdentList()
+	if err != nil {
+		return nil, err
+	}
+	stmt.Columns = cols
+	return stmt, nil
+}
+
+func (p *Parser) parseCreateTable() (*CreateTableStmt, error) {
+	// very simple: CREATE TABLE name (col TYPE [constraints], ...)
 	if err := p.expect(TokKeyword, "TABLE"); err != nil {
 		return nil, err
 	}
 	if p.cur.Type != TokIdent {
 		return nil, fmt.Errorf("expected table name")
 	}
-	name := p.cur.Value
-	p.next()
+	name := p.cur.Value
+	p.next()
+	if err := p.expect(TokLParen, ""); err != nil {
+		return nil, err
+	}
+	stmt := &CreateTableStmt{TableName: name}
+	for {
+		switch {
+		case p.isWord("PRIMARY"):
+			// table-level PRIMARY KEY (a, b)
+			p.next()
+			if !p.isWord("KEY") {
+				return nil, fmt.Errorf("expected KEY after PRIMARY")
+			}
+			p.next()
+			cols, err := p.parseIdentList()
+			if err != nil {
+				return nil, err
+			}
+			stmt.PrimaryKey = cols
+		case p.isWord("UNIQUE") && p.peekT.Type == TokLParen:
+			// table-level UNIQUE (a, b)
+			p.next()
+			cols, err := p.parseIdentList()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Uniques = append(stmt.Uniques, cols)
+		case p.isWord("CONSTRAINT"), p.isWord("FOREIGN"), p.isWord("CHECK"):
+			name, err := p.parseConstraintName()
+			if err != nil {
+				return nil, err
+			}
+			if p.isWord("CHECK") {
+				check, err := p.parseCheck()
+				if err != nil {
+					return nil, err
+				}
+				check.Name = name
+				stmt.Checks = append(stmt.Checks, check)
+				break
+			}
+			fk, err := p.parseForeignKey()
+			if err != nil {
+				return nil, err
+			}
+			fk.Name = name
+			stmt.ForeignKeys = append(stmt.ForeignKeys, fk)
+		default:
+			col, err := p.parseColumnDef()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Columns = append(stmt.Columns, col)
+		}
+
+		if p.cur.Type == TokRParen {
+			p.next()
+			break
+		}
+		if err := p.expect(TokComma, ""); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+// serialTypes maps the SERIAL types to the integer type of the column;
+// as in PostgreSQL, they are integers filled from a sequence
+var serialTypes = map[string]string{"SERIAL": "INTEGER", "BIGSERIAL": "BIGINT", "SMALLSERIAL": "SMALLINT"}
+
+func (p *Parser) parseColumnDef() (ColumnDef, error) {
+	if p.cur.Type != TokIdent {
+		return ColumnDef{}, fmt.Errorf("expected column name")
+	}
+	col := ColumnDef{Name: p.cur.Value}
+	p.next()
+	if p.cur.Type != TokIdent {
+		return ColumnDef{}, fmt.Errorf("expected column type")
+	}
+	col.Type = strings.ToUpper(p.cur.Value)
+	p.next()
+	if t, ok := serialTypes[col.Type]; ok {
+		col.Type, col.AutoIncrement, col.NotNull = t, true, true
+	}
+
+	// Column constraints. A CONSTRAINT name applies to the CHECK or
+	// REFERENCES that follows.
+	var name string
+	for p.cur.Type == TokIdent || p.cur.Type == TokKeyword {
+		switch strings.ToUpper(p.cur.Value) {
+		case "CONSTRAINT":
+			var err error
+			if name, err = p.parseConstraintName(); err != nil {
+				return ColumnDef{}, err
+			}
+			if !p.isWord("CHECK") && !p.isWord("REFERENCES") {
+				return ColumnDef{}, fmt.Errorf("expected CHECK or REFERENCES after constraint name")
+			}
+			continue
+		case "CHECK":
+			check, err := p.parseCheck()
+			if err != nil {
+				return ColumnDef{}, err
+			}
+			check.Name, name = name, ""
+			col.Checks = append(col.Checks, check)
+			continue
+		case "PRIMARY":
+			p.next()
+			if !p.isWord("KEY") {
+				return ColumnDef{}, fmt.Errorf("expected KEY after PRIMARY")
+			}
+			col.PrimaryKey = true
+			col.NotNull = true
+		case "UNIQUE":
+			col.Unique = true
+		case "NOT":
+			p.next()
+			if !p.isWord("NULL") {
+				return ColumnDef{}, fmt.Errorf("expected NULL after NOT")
+			}
+			col.NotNull = true
+		case "REFERENCES":
+			fk, err := p.parseReferences()
+			if err != nil {
+				return ColumnDef{}, err
+			}
+			fk.Name, fk.Columns, name = name, []string{col.Name}, ""
+			col.References = &fk
+			continue
+		case "GENERATED", "AS":
+			if err := p.parseGenerated(&col); err != nil {
+				return ColumnDef{}, err
+			}
+			continue
+		case "NULL":
+		case "AUTO_INCREMENT", "AUTOINCREMENT":
+			col.AutoIncrement, col.NotNull = true, true
+		case "DEFAULT":
+			// a default is an operand, so that DEFAULT 0 NOT NULL does
+			// not read NOT as part of the expression
+			p.next()
+			start := p.cur.Pos
+			x, err := p.parseConcat()
+			if err != nil {
+				return ColumnDef{}, err
+			}
+			col.Default, col.DefaultSQL = x, p.l.source(start, p.cur.Pos)
+			continue
+		default:
+			return col, nil
+		}
+		p.next()
+	}
+	return col, nil
+}
+
+// parseGenerated parses [GENERATED ALWAYS] AS (expr) [STORED | VIRTUAL];
+// columns are virtual unless declared STORED
+func (p *Parser) parseGenerated(col *ColumnDef) error {
+	if p.isWord("GENERATED") {
+		p.next()
+		if !p.isWord("ALWAYS") {
+			return fmt.Errorf("expected ALWAYS after GENERATED")
+		}
+		p.next()
+	}
+	if err := p.expect(TokKeyword, "AS"); err != nil {
+		return err
+	}
+	if err := p.expect(TokLParen, ""); err != nil {
+		return err
+	}
+	start := p.cur.Pos
+	x, err := p.parseExpr()
+	if err != nil {
+		return err
+	}
+	col.Generated, col.GeneratedSQL = x, p.l.source(start, p.cur.Pos)
+	if err := p.expect(TokRParen, ""); err != nil {
+		return err
+	}
+	switch {
+	case p.isWord("STORED"):
+		col.Stored = true
+		p.next()
+	case p.isWord("VIRTUAL"):
+		p.next()
+	}
+	return nil
+}
+
+// parseConstraintName parses an optional CONSTRAINT name
+func (p *Parser) parseConstraintName() (string, error) {
+	if !p.isWord("CONSTRAINT") {
+		return "", nil
+	}
+	p.next()
+	if p.cur.Type != TokIdent {
+		return "", fmt.Errorf("expected constraint name")
+	}
+	name := p.cur.Value
+	p.next()
+	return name, nil
+}
+
+// parseCheck parses CHECK (expr)
+func (p *Parser) parseCheck() (CheckDef, error) {
+	p.next()
+	if err := p.expect(TokLParen, ""); err != nil {
+		return CheckDef{}, err
+	}
+	start := p.cur.Pos
+	x, err := p.parseExpr()
+	if err != nil {
+		return CheckDef{}, err
+	}
+	check := CheckDef{Expr: x, SQL: p.l.source(start, p.cur.Pos)}
+	if err := p.expect(TokRParen, ""); err != nil {
+		return CheckDef{}, err
+	}
+	return check, nil
+}
+
+// parseForeignKey parses FOREIGN KEY (cols) REFERENCES ...
+func (p *Parser) parseForeignKey() (ForeignKeyDef, error) {
+	if !p.isWord("FOREIGN") {
+		return ForeignKeyDef{}, fmt.Errorf("expected FOREIGN KEY")
+	}
+	p.next()
+	if !p.isWord("KEY") {
+		return ForeignKeyDef{}, fmt.Errorf("expected KEY after FOREIGN")
+	}
+	p.next()
+	cols, err := p.parseIdentList()
+	if err != nil {
+		return ForeignKeyDef{}, err
+	}
+	if !p.isWord("REFERENCES") {
+		return ForeignKeyDef{}, fmt.Errorf("expected REFERENCES")
+	}
+	fk, err := p.parseReferences()
+	if err != nil {
+		return ForeignKeyDef{}, err
+	}
+	fk.Columns = cols
+	return fk, nil
+}
+
+// parseReferences parses REFERENCES table [(cols)] [ON DELETE action]
+// [ON UPDATE action]
+func (p *Parser) parseReferences() (ForeignKeyDef, error) {
+	p.next()
+	if p.cur.Type != TokIdent {
+		return ForeignKeyDef{}, fmt.Errorf("expected table name after REFERENCES")
+	}
+	fk := ForeignKeyDef{RefTable: p.cur.Value, OnDelete: "NO ACTION", OnUpdate: "NO ACTION"}
+	p.next()
+	if p.cur.Type == TokLParen {
+		cols, err := p.parseIdentList()
+		if err != nil {
+			return ForeignKeyDef{}, err
+		}
+		fk.RefColumns = cols
+	}
+	for p.cur.Type == TokKeyword && p.cur.Value == "ON" {
+		p.next()
+		var action *string
+		switch {
+		case p.isWord("DELETE"):
+			action = &fk.OnDelete
+		case p.isWord("UPDATE"):
+			action = &fk.OnUpdate
+		default:
+			return ForeignKeyDef{}, fmt.Errorf("expected DELETE or UPDATE after ON")
+		}
+		p.next()
+		switch {
+		case p.isWord("CASCADE"), p.isWord("RESTRICT"):
+			*action = strings.ToUpper(p.cur.Value)
+		case p.isWord("NO"):
+			p.next()
+			if !p.isWord("ACTION") {
+				return ForeignKeyDef{}, fmt.Errorf("expected ACTION after NO")
+			}
+			*action = "NO ACTION"
+		case p.isWord("SET"):
+			p.next()
+			if !p.isWord("NULL") {
+				return ForeignKeyDef{}, fmt.Errorf("only SET NULL is supported as a SET action")
+			}
+			*action = "SET NULL"
+		default:
+			return ForeignKeyDef{}, fmt.Errorf("expected CASCADE, RESTRICT, NO ACTION or SET NULL")
+		}
+		p.next()
+	}
+	return fk, nil
+}
+
+// parseIdentList parses a parenthesized, comma separated list of names
+func (p *Parser) parseIdentList() ([]string, error) {
 	if err := p.expect(TokLParen, ""); err != nil {
 		return nil, err
 	}
-	cols := []ColumnDef{}
+	names := []string{}
 	for {
 		if p.cur.Type != TokIdent {
 			return nil, fmt.Errorf("expected column name")
 		}
-		col := p.cur.Value
-		p.next()
-		if p.cur.Type != TokIdent {
-			return nil, fmt.Errorf("expected column type")
-		}
-		typ := strings.ToUpper(p.cur.Value)
+		names = append(names, p.cur.Value)
 		p.next()
-
-		// Skip constraints like PRIMARY KEY, NOT NULL, etc.
-		for p.cur.Type == TokIdent || p.cur.Type == TokKeyword {
-			constraintUpper := strings.ToUpper(p.cur.Value)
-			if constraintUpper == "PRIMARY" || constraintUpper == "NOT" ||
-				constraintUpper == "NULL" || constraintUpper == "KEY" ||
-				constraintUpper == "UNIQUE" || constraintUpper == "AUTO_INCREMENT" ||
-				constraintUpper == "AUTOINCREMENT" || constraintUpper == "DEFAULT" {
-				p.next()
-				// For DEFAULT, skip the value as well
-				if constraintUpper == "DEFAULT" {
-					p.next()
-				}
-			} else {
-				break
-			}
-		}
-
-		cols = append(cols, ColumnDef{Name: col, Type: typ})
 		if p.cur.Type == TokRParen {
 			p.next()
 			break
@@ -111,7 +836,13 @@ func (p *Parser) parseCreate() (*CreateTableStmt, error) {
 			return nil, err
 		}
 	}
-	return &CreateTableStmt{TableName: name, Columns: cols}, nil
+	return names, nil
+}
+
+// isWord reports whether the current token is the given word, whether it
+// was lexed as a keyword or as a plain identifier
+func (p *Parser) isWord(w string) bool {
+	return (p.cur.Type == TokIdent || p.cur.Type == TokKeyword) && strings.ToUpper(p.cur.Value) == w
 }
 
 func (p *Parser) parseInsert() (*InsertStmt, error) {
@@ -128,44 +859,159 @@ func (p *Parser) parseInsert() (*InsertStmt, error) {
 	table := p.cur.Value
 	p.next()
 
-	if err := p.expect(TokLParen, ""); err != nil {
+	cols, err := p.parseIdentList()
+	if err != nil {
 		return nil, err
 	}
-	cols := []string{}
-	for {
-		if p.cur.Type != TokIdent {
-			return nil, fmt.Errorf("expected column name")
+	stmt := &InsertStmt{Table: table, Columns: cols}
+
+	if startsSelect(p.cur) {
+		// INSERT INTO t (cols) SELECT ...
+		if stmt.Select, err = p.parseSelect(); err != nil {
+			return nil, err
 		}
-		cols = append(cols, p.cur.Value)
-		p.next()
-		if p.cur.Type == TokRParen {
+	} else {
+		if err := p.expect(TokKeyword, "VALUES"); err != nil {
+			return nil, err
+		}
+		// one or more (v1, v2, ...) tuples separated by commas
+		for {
+			vals, err := p.parseValueTuple()
+			if err != nil {
+				return nil, err
+			}
+			stmt.Rows = append(stmt.Rows, vals)
+			if p.cur.Type != TokComma {
+				break
+			}
 			p.next()
-			break
 		}
-		if err := p.expect(TokComma, ""); err != nil {
+	}
+
+	if p.cur.Type == TokKeyword && p.cur.Value == "ON" {
+		if stmt.OnConflict, err = p.parseOnConflict(); err != nil {
 			return nil, err
 		}
 	}
+	if stmt.Returning, err = p.parseReturning(); err != nil {
+		return nil, err
+	}
+	return stmt, nil
+}
+
+// parseReturning parses an optional RETURNING * | expr [AS alias], ...
+func (p *Parser) parseReturning() ([]SelectItem, error) {
+	if p.cur.Type != TokKeyword || p.cur.Value != "RETURNING" {
+		return nil, nil
+	}
+	p.next()
+	return p.parseSelectItems()
+}
+
+func (p *Parser) parseSelectItems() ([]SelectItem, error) {
+	var items []SelectItem
+	for {
+		if p.cur.Type == TokStar {
+			p.next()
+			items = append(items, SelectItem{Star: true})
+		} else {
+			x, err := p.parseExpr()
+			if err != nil {
+				return nil, err
+			}
+			item := SelectItem{Expr: x}
+			if p.cur.Type == TokKeyword && p.cur.Value == "AS" {
+				p.next()
+				if p.cur.Type != TokIdent {
+					return nil, fmt.Errorf("expected alias after AS")
+				}
+				item.Alias = p.cur.Value
+				p.next()
+			}
+			items = append(items, item)
+		}
+		if p.cur.Type != TokComma {
+			break
+		}
+		p.next()
+	}
+	return items, nil
+}
 
-	if err := p.expect(TokKeyword, "VALUES"); err != nil {
+func (p *Parser) parseOnConflict() (*OnConflict, error) {
+	// ON CONFLICT [(cols)] DO NOTHING | DO UPDATE SET col = expr [, ...]
+	if err := p.expect(TokKeyword, "ON"); err != nil {
 		return nil, err
 	}
-	if err := p.expect(TokLParen, ""); err != nil {
+	if err := p.expect(TokKeyword, "CONFLICT"); err != nil {
+		return nil, err
+	}
+	oc := &OnConflict{}
+	if p.cur.Type == TokLParen {
+		cols, err := p.parseIdentList()
+		if err != nil {
+			return nil, err
+		}
+		oc.Columns = cols
+	}
+	if err := p.expect(TokKeyword, "DO"); err != nil {
+		return nil, err
+	}
+	if p.cur.Type == TokKeyword && p.cur.Value == "NOTHING" {
+		p.next()
+		oc.DoNothing = true
+		return oc, nil
+	}
+	if err := p.expect(TokKeyword, "UPDATE"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(TokKeyword, "SET"); err != nil {
+		return nil, err
+	}
+	set, err := p.parseAssignments()
+	if err != nil {
 		return nil, err
 	}
+	oc.Set = set
+	return oc, nil
+}
 
-	vals := []any{}
+// parseAssignments parses col = expr [, col = expr ...]
+func (p *Parser) parseAssignments() ([]Assignment, error) {
+	var set []Assignment
 	for {
-		switch p.cur.Type {
-		case TokNumber:
-			n, _ := strconv.ParseInt(p.cur.Value, 10, 64)
-			vals = append(vals, n)
-		case TokString:
-			vals = append(vals, p.cur.Value)
-		default:
-			return nil, fmt.Errorf("unexpected value token %v", p.cur)
+		if p.cur.Type != TokIdent {
+			return nil, fmt.Errorf("expected column in set")
+		}
+		col := p.cur.Value
+		p.next()
+		if err := p.expect(TokEqual, ""); err != nil {
+			return nil, err
+		}
+		val, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		set = append(set, Assignment{Column: col, Value: val})
+		if p.cur.Type != TokComma {
+			break
 		}
 		p.next()
+	}
+	return set, nil
+}
+
+func (p *Parser) parseValueTuple() ([]Expr, error) {
+	if err := p.expect(TokLParen, ""); err != nil {
+		return nil, err
+	}
+	vals := []Expr{}
+	for {
+		x, err := p.parseExpr()
+		if err != nil {
+			return nil, err
+		}
+		vals = append(vals, x)
 		if p.cur.Type == TokRParen {
 			p.next()
 			break
@@ -174,111 +1020,294 @@ func (p *Parser) parseInsert() (*InsertStmt, error) {
 			return nil, err
 		}
 	}
+	return vals, nil
+}
 
-	return &InsertStmt{Table: table, Columns: cols, Values: vals}, nil
+// startsSelect reports whether t begins a query: SELECT or WITH
+func startsSelect(t Token) bool {
+	return t.Type == TokKeyword && (t.Value == "SELECT" || t.Value == "WITH")
 }
 
 func (p *Parser) parseSelect() (*SelectStmt, error) {
-	if err := p.expect(TokKeyword, "SELECT"); err != nil {
+	// [WITH ...] query [ORDER BY keys] [LIMIT n] [OFFSET n], where query
+	// combines SELECTs with UNION, INTERSECT and EXCEPT
+	var with *With
+	if p.cur.Type == TokKeyword && p.cur.Value == "WITH" {
+		var err error
+		if with, err = p.parseWith(); err != nil {
+			return nil, err
+		}
+	}
+	stmt, err := p.parseUnion()
+	if err != nil {
 		return nil, err
 	}
-	cols := []string{}
-	if p.cur.Type == TokStar {
-		cols = append(cols, "*")
+	if with != nil {
+		if stmt.With != nil {
+			return nil, fmt.Errorf("multiple WITH clauses not allowed")
+		}
+		stmt.With = with
+	}
+	if p.cur.Type == TokKeyword && p.cur.Value == "ORDER" {
+		if stmt.OrderBy != nil {
+			return nil, fmt.Errorf("multiple ORDER BY clauses not allowed")
+		}
 		p.next()
-	} else {
-		for {
-			if p.cur.Type != TokIdent {
-				return nil, fmt.Errorf("expected column in select")
-			}
-			cols = append(cols, p.cur.Value)
-			p.next()
-			if p.cur.Type == TokComma {
-				p.next()
-				continue
-			}
-			break
+		if stmt.OrderBy, err = p.parseOrderBy(); err != nil {
+			return nil, err
 		}
 	}
-	if err := p.expect(TokKeyword, "FROM"); err != nil {
+	for _, clause := range []struct {
+		word string
+		x    *Expr
+	}{{"LIMIT", &stmt.Limit}, {"OFFSET", &stmt.Offset}} {
+		if p.cur.Type != TokKeyword || p.cur.Value != clause.word {
+			continue
+		}
+		if *clause.x != nil {
+			return nil, fmt.Errorf("multiple %s clauses not allowed", clause.word)
+		}
+		p.next()
+		if *clause.x, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+// parseUnion parses queries combined with UNION and EXCEPT, which bind
+// less tightly than INTERSECT
+func (p *Parser) parseUnion() (*SelectStmt, error) {
+	left, err := p.parseIntersect()
+	if err != nil {
 		return nil, err
 	}
-	if p.cur.Type != TokIdent {
-		return nil, fmt.Errorf("expected table name in select")
+	for p.cur.Type == TokKeyword && (p.cur.Value == "UNION" || p.cur.Value == "EXCEPT") {
+		set := &SetOp{Op: p.cur.Value, Left: left}
+		p.next()
+		set.All = p.skipAll()
+		if set.Right, err = p.parseIntersect(); err != nil {
+			return nil, err
+		}
+		left = &SelectStmt{Set: set}
 	}
-	table := p.cur.Value
-	p.next()
-	return &SelectStmt{Table: table, Columns: cols}, nil
+	return left, nil
 }
 
-func (p *Parser) parseUpdate() (*UpdateStmt, error) {
-	// UPDATE <table> SET col = val [, ...] [WHERE col = val]
-	if err := p.expect(TokKeyword, "UPDATE"); err != nil {
+func (p *Parser) parseIntersect() (*SelectStmt, error) {
+	left, err := p.parseSetOperand()
+	if err != nil {
 		return nil, err
 	}
-	if p.cur.Type != TokIdent {
-		return nil, fmt.Errorf("expected table name in update")
+	for p.cur.Type == TokKeyword && p.cur.Value == "INTERSECT" {
+		set := &SetOp{Op: "INTERSECT", Left: left}
+		p.next()
+		set.All = p.skipAll()
+		if set.Right, err = p.parseSetOperand(); err != nil {
+			return nil, err
+		}
+		left = &SelectStmt{Set: set}
 	}
-	table := p.cur.Value
-	p.next()
-	if err := p.expect(TokKeyword, "SET"); err != nil {
+	return left, nil
+}
+
+// skipAll consumes the ALL of UNION ALL and reports whether it was there
+func (p *Parser) skipAll() bool {
+	if p.cur.Type == TokKeyword && p.cur.Value == "ALL" {
+		p.next()
+		return true
+	}
+	return false
+}
+
+// parseSetOperand parses a plain SELECT or a parenthesized query
+func (p *Parser) parseSetOperand() (*SelectStmt, error) {
+	if p.cur.Type == TokLParen {
+		p.next()
+		return p.parseSubquery()
+	}
+	return p.parseSimpleSelect()
+}
+
+func (p *Parser) parseSimpleSelect() (*SelectStmt, error) {
+	// SELECT [DISTINCT] items [FROM source] [WHERE expr] [GROUP BY exprs]
+	// [HAVING expr]
+	if err := p.expect(TokKeyword, "SELECT"); err != nil {
+		return nil, err
+	}
+	distinct := p.cur.Type == TokKeyword && p.cur.Value == "DISTINCT"
+	if distinct {
+		p.next()
+	}
+	items, err := p.parseSelectItems()
+	if err != nil {
+		return nil, err
+	}
+	stmt := &SelectStmt{Distinct: distinct, Items: items}
+	if p.cur.Type == TokKeyword && p.cur.Value == "FROM" {
+		p.next()
+		if err := p.parseFrom(stmt); err != nil {
+			return nil, err
+		}
+	}
+	if stmt.Where, err = p.parseWhere(); err != nil {
 		return nil, err
 	}
-	set := map[string]any{}
+	if p.cur.Type == TokKeyword && p.cur.Value == "GROUP" {
+		p.next()
+		if err := p.expect(TokKeyword, "BY"); err != nil {
+			return nil, err
+		}
+		if stmt.GroupBy, err = p.parseExprList(); err != nil {
+			return nil, err
+		}
+	}
+	if p.cur.Type == TokKeyword && p.cur.Value == "HAVING" {
+		p.next()
+		if stmt.Having, err = p.parseExpr(); err != nil {
+			return nil, err
+		}
+	}
+	return stmt, nil
+}
+
+// parseWith parses WITH [RECURSIVE] name [(cols)] AS (query), ...
+func (p *Parser) parseWith() (*With, error) {
+	p.next()
+	with := &With{}
+	if p.cur.Type == TokKeyword && p.cur.Value == "RECURSIVE" {
+		with.Recursive = true
+		p.next()
+	}
 	for {
 		if p.cur.Type != TokIdent {
-			return nil, fmt.Errorf("expected column in set")
+			return nil, fmt.Errorf("expected WITH query name")
 		}
-		col := p.cur.Value
+		cte := &CTE{Name: p.cur.Value}
 		p.next()
-		if err := p.expect(TokEqual, ""); err != nil {
+		if p.cur.Type == TokLParen {
+			cols, err := p.parseIdentList()
+			if err != nil {
+				return nil, err
+			}
+			cte.Columns = cols
+		}
+		if err := p.expect(TokKeyword, "AS"); err != nil {
 			return nil, err
 		}
-		switch p.cur.Type {
-		case TokNumber:
-			n, _ := strconv.ParseInt(p.cur.Value, 10, 64)
-			set[col] = n
-		case TokString:
-			set[col] = p.cur.Value
-		default:
-			return nil, fmt.Errorf("unexpected token in set %v", p.cur)
+		if err := p.expect(TokLParen, ""); err != nil {
+			return nil, err
+		}
+		var err error
+		if cte.Select, err = p.parseSelect(); err != nil {
+			return nil, err
+		}
+		// the last UNION [ALL] of a recursive query separates the term that
+		// may read the query itself
+		if s := cte.Select; with.Recursive && s.Set != nil && s.Set.Op == "UNION" &&
+			s.With == nil && s.OrderBy == nil && s.Limit == nil && s.Offset == nil {
+			cte.Select, cte.Union, cte.UnionAll = s.Set.Left, s.Set.Right, s.Set.All
+		}
+		if err := p.expect(TokRParen, ""); err != nil {
+			return nil, err
+		}
+		with.CTEs = append(with.CTEs, cte)
+		if p.cur.Type != TokComma {
+			return with, nil
 		}
 		p.next()
-		if p.cur.Type == TokComma {
-			p.next()
-			continue
+	}
+}
+
+// parseFrom parses the FROM source: table [[AS] alias] or
+// (SELECT ...) [AS] alias
+func (p *Parser) parseFrom(stmt *SelectStmt) error {
+	switch p.cur.Type {
+	case TokIdent:
+		stmt.Table = p.cur.Value
+		p.next()
+	case TokLParen:
+		p.next()
+		sel, err := p.parseSubquery()
+		if err != nil {
+			return err
 		}
-		break
+		stmt.From = sel
+	default:
+		return fmt.Errorf("expected table name in select")
 	}
-	// optional WHERE
-	whereCol := ""
-	var whereVal any = nil
-	if p.cur.Type == TokKeyword && p.cur.Value == "WHERE" {
+
+	if p.cur.Type == TokKeyword && p.cur.Value == "AS" {
 		p.next()
 		if p.cur.Type != TokIdent {
-			return nil, fmt.Errorf("expected column in where")
+			return fmt.Errorf("expected alias after AS")
 		}
-		whereCol = p.cur.Value
+	}
+	if p.cur.Type == TokIdent {
+		stmt.Alias = p.cur.Value
 		p.next()
-		if err := p.expect(TokEqual, ""); err != nil {
+	}
+	if stmt.From != nil && stmt.Alias == "" {
+		return fmt.Errorf("subquery in FROM must have an alias")
+	}
+	return nil
+}
+
+// parseExprList parses expr [, expr ...]
+func (p *Parser) parseExprList() ([]Expr, error) {
+	var list []Expr
+	for {
+		x, err := p.parseExpr()
+		if err != nil {
 			return nil, err
 		}
-		switch p.cur.Type {
-		case TokNumber:
-			n, _ := strconv.ParseInt(p.cur.Value, 10, 64)
-			whereVal = n
-		case TokString:
-			whereVal = p.cur.Value
-		default:
-			return nil, fmt.Errorf("unexpected token in where %v", p.cur)
+		list = append(list, x)
+		if p.cur.Type != TokComma {
+			return list, nil
 		}
 		p.next()
 	}
-	return &UpdateStmt{Table: table, Set: set, WhereColumn: whereCol, WhereValue: whereVal}, nil
+}
+
+// parseWhere parses an optional WHERE clause
+func (p *Parser) parseWhere() (Expr, error) {
+	if p.cur.Type != TokKeyword || p.cur.Value != "WHERE" {
+		return nil, nil
+	}
+	p.next()
+	return p.parseExpr()
+}
+
+func (p *Parser) parseUpdate() (*UpdateStmt, error) {
+	// UPDATE <table> SET col = expr [, ...] [WHERE expr] [RETURNING ...]
+	if err := p.expect(TokKeyword, "UPDATE"); err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected table name in update")
+	}
+	table := p.cur.Value
+	p.next()
+	if err := p.expect(TokKeyword, "SET"); err != nil {
+		return nil, err
+	}
+	set, err := p.parseAssignments()
+	if err != nil {
+		return nil, err
+	}
+	where, err := p.parseWhere()
+	if err != nil {
+		return nil, err
+	}
+	returning, err := p.parseReturning()
+	if err != nil {
+		return nil, err
+	}
+	return &UpdateStmt{Table: table, Set: set, Where: where, Returning: returning}, nil
 }
 
 func (p *Parser) parseDelete() (*DeleteStmt, error) {
-	// DELETE FROM <table> [WHERE col = val]
+	// DELETE FROM <table> [WHERE expr] [RETURNING ...]
 	if err := p.expect(TokKeyword, "DELETE"); err != nil {
 		return nil, err
 	}
@@ -290,28 +1319,37 @@ func (p *Parser) parseDelete() (*DeleteStmt, error) {
 	}
 	table := p.cur.Value
 	p.next()
-	whereCol := ""
-	var whereVal any = nil
-	if p.cur.Type == TokKeyword && p.cur.Value == "WHERE" {
-		p.next()
-		if p.cur.Type != TokIdent {
-			return nil, fmt.Errorf("expected column in where")
-		}
-		whereCol = p.cur.Value
-		p.next()
-		if err := p.expect(TokEqual, ""); err != nil {
-			return nil, err
-		}
-		switch p.cur.Type {
-		case TokNumber:
-			n, _ := strconv.ParseInt(p.cur.Value, 10, 64)
-			whereVal = n
-		case TokString:
-			whereVal = p.cur.Value
-		default:
-			return nil, fmt.Errorf("unexpected token in where %v", p.cur)
-		}
-		p.next()
+	where, err := p.parseWhere()
+	if err != nil {
+		return nil, err
 	}
-	return &DeleteStmt{Table: table, WhereColumn: whereCol, WhereValue: whereVal}, nil
+	returning, err := p.parseReturning()
+	if err != nil {
+		return nil, err
+	}
+	return &DeleteStmt{Table: table, Where: where, Returning: returning}, nil
+}
+
+func (p *Parser) parseShow() (*ShowTablesStmt, error) {
+	// SHOW TABLES
+	if err := p.expect(TokKeyword, "SHOW"); err != nil {
+		return nil, err
+	}
+	if err := p.expect(TokKeyword, "TABLES"); err != nil {
+		return nil, err
+	}
+	return &ShowTablesStmt{}, nil
+}
+
+func (p *Parser) parseDescribe() (*DescribeStmt, error) {
+	// DESCRIBE <table>
+	if err := p.expect(TokKeyword, "DESCRIBE"); err != nil {
+		return nil, err
+	}
+	if p.cur.Type != TokIdent {
+		return nil, fmt.Errorf("expected table name in describe")
+	}
+	table := p.cur.Value
+	p.next()
+	return &DescribeStmt{Table: table}, nil
 }
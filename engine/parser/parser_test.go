@@ -0,0 +1,49 @@
+package parser
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestErrorMessages(t *testing.T) {
+	tests := []struct {
+		sql, want string
+	}{
+		{"SELECT 1 +", "unexpected end of input at position 11 in expression"},
+		{"SELECT 1 + )", "unexpected ')' at position 12 in expression"},
+		{"INSERT INTO t VALUES (1)", "expected '(', got 'VALUES' at position 15"},
+		{"DELETE FROM s WHER a = 1", "unexpected 'WHER' at position 15 after the end of the statement"},
+		{"SELECT * FROM big, big b2", "unexpected ',' at position 18 after the end of the statement"},
+		{"SELECT 1; SELECT 2", "unexpected 'SELECT' at position 11 after the end of the statement"},
+		{"SELECT a FROM t ORDER BY a NULLS", "expected FIRST or LAST, got end of input at position 33"},
+	}
+	for _, tt := range tests {
+		_, err := Parse(tt.sql)
+		if err == nil || err.Error() != tt.want {
+			t.Errorf("Parse(%q) error = %v, want %q", tt.sql, err, tt.want)
+		}
+	}
+}
+
+func TestParseTrailingSemicolons(t *testing.T) {
+	for _, sql := range []string{"SELECT 1", "SELECT 1;", "SELECT 1 ; ;"} {
+		if _, err := Parse(sql); err != nil {
+			t.Errorf("Parse(%q): %v", sql, err)
+		}
+	}
+}
+
+func TestOrderByNulls(t *testing.T) {
+	stmt, err := Parse("SELECT a FROM t ORDER BY a, b DESC, c NULLS FIRST, d DESC NULLS LAST")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got [][2]bool
+	for _, it := range stmt.(*SelectStmt).OrderBy {
+		got = append(got, [2]bool{it.Desc, it.NullsFirst})
+	}
+	want := [][2]bool{{false, false}, {true, true}, {false, true}, {true, false}}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("desc, nulls first = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,313 @@
+package planner
+
+import (
+	"strings"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// IndexScan reads the candidate rows of a SELECT through an index instead
+// of scanning the whole table. The executor still applies the complete
+// WHERE clause to every row it returns.
+type IndexScan struct {
+	Index  string
+	Column string // leading column of the index
+	Ranges []storage.KeyRange
+}
+
+// AccessPaths holds the chosen index scan of every SELECT of a statement,
+// subqueries included. SELECTs without an entry scan their whole source.
+type AccessPaths map[*parser.SelectStmt]*IndexScan
+
+// planAccess chooses the access path of every SELECT in a bound and folded
+// statement
+func (p *Planner) planAccess(stmt parser.Statement) AccessPaths {
+	paths := AccessPaths{}
+	var walk func(stmt parser.Statement)
+	walk = func(stmt parser.Statement) {
+		if s, ok := stmt.(*parser.SelectStmt); ok {
+			if scan := p.accessPath(s); scan != nil {
+				paths[s] = scan
+			}
+		}
+		for _, sub := range subqueries(stmt) {
+			walk(sub)
+		}
+	}
+	walk(stmt)
+	return paths
+}
+
+// accessPath chooses the index scan of one SELECT, nil for a full scan
+func (p *Planner) accessPath(s *parser.SelectStmt) *IndexScan {
+	if s.Table == "" || s.CTE != nil || s.Where == nil {
+		return nil
+	}
+	meta, ok := p.store.Table(s.Table)
+	if !ok || storage.IsSystemTable(s.Table) {
+		return nil
+	}
+	meta.Name = s.SourceName()
+
+	// collect the usable restriction of every column from the top-level
+	// AND conjuncts, preferring point lookups over bounded and one-sided
+	// ranges
+	best := map[string]*restriction{}
+	for _, x := range conjuncts(s.Where) {
+		r := restrict(x, meta)
+		if r == nil {
+			continue
+		}
+		if cur, ok := best[r.column]; ok {
+			r = cur.combine(r)
+		}
+		best[r.column] = r
+	}
+
+	var chosen *restriction
+	var scan *IndexScan
+	for _, idx := range meta.Indexes {
+		r, ok := best[idx.Columns[0]]
+		if !ok || (chosen != nil && chosen.rank >= r.rank) {
+			continue
+		}
+		chosen = r
+		scan = &IndexScan{Index: idx.Name, Column: r.column, Ranges: r.ranges}
+	}
+	return scan
+}
+
+// conjuncts splits a condition on its top-level ANDs
+func conjuncts(x parser.Expr) []parser.Expr {
+	if b, ok := x.(*parser.BinaryExpr); ok && b.Op == "AND" {
+		return append(conjuncts(b.Left), conjuncts(b.Right)...)
+	}
+	return []parser.Expr{x}
+}
+
+// restriction is the set of key ranges a conjunct allows for one column
+type restriction struct {
+	column string
+	ranges []storage.KeyRange
+	rank   int // 3 point lookups, 2 bounded range, 1 one-sided range
+}
+
+// combine merges two restrictions of the same column. A lower and an upper
+// bound (a > 1 AND a < 9) form one bounded range; otherwise the better
+// ranked restriction wins, since the WHERE clause is re-checked anyway.
+func (r *restriction) combine(o *restriction) *restriction {
+	if r.rank == 1 && o.rank == 1 {
+		a, b := r.ranges[0], o.ranges[0]
+		switch {
+		case a.Low != nil && a.High == nil && b.Low == nil && b.High != nil:
+			return &restriction{column: r.column, rank: 2, ranges: []storage.KeyRange{{
+				Low: a.Low, LowIncl: a.LowIncl, High: b.High, HighIncl: b.HighIncl,
+			}}}
+		case a.Low == nil && a.High != nil && b.Low != nil && b.High == nil:
+			return o.combine(r)
+		}
+	}
+	if o.rank > r.rank {
+		return o
+	}
+	return r
+}
+
+// restrict derives the key ranges a single conjunct allows, or nil when it
+// does not restrict a column to constants
+func restrict(x parser.Expr, meta storage.TableMeta) *restriction {
+	switch n := x.(type) {
+	case *parser.BinaryExpr:
+		col, lit, op := columnAndLiteral(n, meta.Name)
+		if col == "" {
+			return nil
+		}
+		v, ok := keyValue(meta, col, lit)
+		if !ok {
+			return nil
+		}
+		r := &restriction{column: col, rank: 1}
+		switch op {
+		case "=":
+			r.rank = 3
+			r.ranges = []storage.KeyRange{{Low: v, High: v, LowIncl: true, HighIncl: true}}
+		case "<", "<=":
+			r.ranges = []storage.KeyRange{{High: v, HighIncl: op == "<="}}
+		case ">", ">=":
+			r.ranges = []storage.KeyRange{{Low: v, LowIncl: op == ">="}}
+		default:
+			return nil
+		}
+		return r
+	case *parser.InExpr:
+		col := columnName(n.Expr, meta.Name)
+		if col == "" || n.Not || n.Select != nil {
+			return nil
+		}
+		r := &restriction{column: col, rank: 3}
+		for _, item := range n.List {
+			lit, ok := item.(*parser.Literal)
+			if !ok {
+				return nil
+			}
+			if lit.Value == nil {
+				continue // x IN (NULL) never holds
+			}
+			v, ok := keyValue(meta, col, lit.Value)
+			if !ok {
+				return nil
+			}
+			r.ranges = append(r.ranges, storage.KeyRange{Low: v, High: v, LowIncl: true, HighIncl: true})
+		}
+		return r
+	case *parser.BetweenExpr:
+		col := columnName(n.Expr, meta.Name)
+		low, ok1 := n.Low.(*parser.Literal)
+		high, ok2 := n.High.(*parser.Literal)
+		if col == "" || n.Not || !ok1 || !ok2 {
+			return nil
+		}
+		lv, ok1 := keyValue(meta, col, low.Value)
+		hv, ok2 := keyValue(meta, col, high.Value)
+		if !ok1 || !ok2 {
+			return nil
+		}
+		return &restriction{column: col, rank: 2, ranges: []storage.KeyRange{{
+			Low: lv, High: hv, LowIncl: true, HighIncl: true,
+		}}}
+	case *parser.LikeExpr:
+		col := columnName(n.Expr, meta.Name)
+		lit, ok := n.Pattern.(*parser.Literal)
+		if col == "" || n.Not || n.Fold || !ok {
+			return nil
+		}
+		pattern, ok := lit.Value.(string)
+		if !ok || columnKind(meta, col) != "TEXT" {
+			return nil
+		}
+		prefix, exact := likePrefix(pattern)
+		if prefix == "" {
+			return nil
+		}
+		if exact {
+			return &restriction{column: col, rank: 3, ranges: []storage.KeyRange{{
+				Low: prefix, High: prefix, LowIncl: true, HighIncl: true,
+			}}}
+		}
+		r := storage.KeyRange{Low: prefix, LowIncl: true}
+		if succ, ok := prefixSuccessor(prefix); ok {
+			r.High = succ
+		}
+		return &restriction{column: col, rank: 2, ranges: []storage.KeyRange{r}}
+	}
+	return nil
+}
+
+// columnAndLiteral matches col <op> literal and literal <op> col, returning
+// the operator as seen from the column
+func columnAndLiteral(b *parser.BinaryExpr, table string) (string, any, string) {
+	flipped := map[string]string{"=": "=", "<": ">", "<=": ">=", ">": "<", ">=": "<="}
+	if col := columnName(b.Left, table); col != "" {
+		if lit, ok := b.Right.(*parser.Literal); ok {
+			return col, lit.Value, b.Op
+		}
+	}
+	if col := columnName(b.Right, table); col != "" {
+		if lit, ok := b.Left.(*parser.Literal); ok {
+			return col, lit.Value, flipped[b.Op]
+		}
+	}
+	return "", nil, ""
+}
+
+// columnName returns the column x refers to, if x is a reference to a
+// column of table in the query itself (not an outer one)
+func columnName(x parser.Expr, table string) string {
+	ref, ok := x.(*parser.ColumnRef)
+	if !ok || ref.Level != 0 || (ref.Table != "" && ref.Table != table) {
+		return ""
+	}
+	return ref.Name
+}
+
+func columnKind(meta storage.TableMeta, col string) string {
+	c, ok := meta.Column(col)
+	if !ok {
+		return ""
+	}
+	kind, _ := parser.NormalizeType(c.Type)
+	return kind
+}
+
+// keyValue converts a constant to the representation stored in col, so it
+// orders correctly against the index keys. Constants whose type does not
+// match the column are not used for lookups; the full scan then reports
+// any comparison error.
+func keyValue(meta storage.TableMeta, col string, v any) (any, bool) {
+	if v == nil {
+		return nil, false
+	}
+	switch columnKind(meta, col) {
+	case "INTEGER", "REAL":
+		switch v.(type) {
+		case int64, float64:
+			return v, true
+		}
+	case "TEXT":
+		s, ok := v.(string)
+		return s, ok
+	case "TIMESTAMP":
+		switch t := v.(type) {
+		case storage.Timestamp:
+			return t, true
+		case storage.Date:
+			return storage.NewTimestamp(t.Time), true
+		case string:
+			ts, err := storage.ParseTimestamp(t)
+			return ts, err == nil
+		}
+	case "DATE":
+		switch t := v.(type) {
+		case storage.Date:
+			return t, true
+		case string:
+			// only whole dates; a time of day would compare as a timestamp
+			d, err := storage.ParseDate(t)
+			return d, err == nil && len(strings.TrimSpace(t)) == len("2006-01-02")
+		}
+	}
+	return nil, false
+}
+
+// likePrefix returns the literal text before the first wildcard of a LIKE
+// pattern, and whether the pattern has no wildcard at all
+func likePrefix(pattern string) (string, bool) {
+	var b strings.Builder
+	rs := []rune(pattern)
+	for i := 0; i < len(rs); i++ {
+		switch rs[i] {
+		case '%', '_':
+			return b.String(), false
+		case '\\':
+			if i+1 < len(rs) {
+				i++
+			}
+		}
+		b.WriteRune(rs[i])
+	}
+	return b.String(), true
+}
+
+// prefixSuccessor returns the smallest string greater than every string
+// starting with prefix, in byte order
+func prefixSuccessor(prefix string) (string, bool) {
+	b := []byte(prefix)
+	for i := len(b) - 1; i >= 0; i-- {
+		if b[i] < 0xff {
+			b[i]++
+			return string(b[:i+1]), true
+		}
+	}
+	return "", false
+}
@@ -0,0 +1,80 @@
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+func TestAccessPaths(t *testing.T) {
+	st, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	cols := []storage.ColumnDefinition{{Name: "id", Type: "INTEGER"}, {Name: "name", Type: "TEXT"}, {Name: "n", Type: "INTEGER"}}
+	err = st.CreateTable("t", cols,
+		storage.IndexMeta{Name: "t_id", Table: "t", Columns: []string{"id"}, Unique: true},
+		storage.IndexMeta{Name: "t_name", Table: "t", Columns: []string{"name"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	point := func(v any) storage.KeyRange {
+		return storage.KeyRange{Low: v, High: v, LowIncl: true, HighIncl: true}
+	}
+	for _, tc := range []struct {
+		where string
+		want  *IndexScan
+	}{
+		{"id = 3", &IndexScan{Index: "t_id", Column: "id", Ranges: []storage.KeyRange{point(int64(3))}}},
+		{"id IN (1, NULL, 2)", &IndexScan{Index: "t_id", Column: "id", Ranges: []storage.KeyRange{point(int64(1)), point(int64(2))}}},
+		{"id BETWEEN 2 AND 5", &IndexScan{Index: "t_id", Column: "id", Ranges: []storage.KeyRange{
+			{Low: int64(2), High: int64(5), LowIncl: true, HighIncl: true}}}},
+		{"id > 2 AND id <= 5", &IndexScan{Index: "t_id", Column: "id", Ranges: []storage.KeyRange{
+			{Low: int64(2), High: int64(5), HighIncl: true}}}},
+		{"name LIKE 'ab%'", &IndexScan{Index: "t_name", Column: "name", Ranges: []storage.KeyRange{
+			{Low: "ab", High: "ac", LowIncl: true}}}},
+		{"name LIKE 'a\\_b'", &IndexScan{Index: "t_name", Column: "name", Ranges: []storage.KeyRange{point("a_b")}}},
+		// the point lookup wins over the prefix range
+		{"name LIKE 'ab%' AND id = 1", &IndexScan{Index: "t_id", Column: "id", Ranges: []storage.KeyRange{point(int64(1))}}},
+		{"name LIKE '%b'", nil},
+		{"name ILIKE 'ab%'", nil},
+		{"name NOT LIKE 'ab%'", nil},
+		{"id NOT IN (1, 2)", nil},
+		{"id NOT BETWEEN 2 AND 5", nil},
+		{"id = 1 OR id = 2", nil},
+		{"n = 1", nil},
+		{"id = 'x'", nil},
+	} {
+		stmt, err := parser.Parse("SELECT * FROM t WHERE " + tc.where)
+		if err != nil {
+			t.Fatal(err)
+		}
+		plan, err := NewPlanner(st, nil).Plan(stmt)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.where, err)
+		}
+		ps := plan.(*PlanSelect)
+		if got := ps.Access[ps.Stmt]; !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("%s: access %+v, want %+v", tc.where, got, tc.want)
+		}
+	}
+}
+
+func TestPrefixSuccessor(t *testing.T) {
+	for _, tc := range []struct {
+		prefix, want string
+		ok           bool
+	}{
+		{"ab", "ac", true},
+		{"a\xff", "b", true},
+		{"\xff\xff", "", false},
+	} {
+		if got, ok := prefixSuccessor(tc.prefix); got != tc.want || ok != tc.ok {
+			t.Errorf("prefixSuccessor(%q) = %q, %v", tc.prefix, got, ok)
+		}
+	}
+}
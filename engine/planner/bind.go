@@ -0,0 +1,617 @@
+package planner
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// Binding checks that every column a statement references exists, so
+// mistakes are reported before the statement modifies anything.
+
+// scope lists the tables visible to an expression. Unqualified names
+// resolve against def; an empty def means no columns are in scope. The
+// scope of a subquery has the scope of its enclosing query as parent.
+type scope struct {
+	def    string
+	tables map[string]storage.TableMeta
+	parent *scope
+	// correlated is set when an expression in this scope references a
+	// column of an enclosing scope
+	correlated bool
+	// ctes holds the WITH queries of a SELECT. Such a scope only names
+	// queries and is skipped when resolving columns.
+	ctes map[string]*parser.CTE
+	// cteMeta holds the result columns of each WITH query
+	cteMeta map[*parser.CTE]storage.TableMeta
+	// out lists the result columns of the SELECT bound in this scope
+	out []storage.ColumnDefinition
+}
+
+// cte finds the innermost WITH query called name
+func (sc *scope) cte(name string) (*parser.CTE, storage.TableMeta, bool) {
+	for s := sc; s != nil; s = s.parent {
+		if c, ok := s.ctes[name]; ok {
+			return c, s.cteMeta[c], true
+		}
+	}
+	return nil, storage.TableMeta{}, false
+}
+
+// resolve binds ref to the innermost scope that has its column and records
+// in ref.Level how many queries up that scope is. A qualified name that
+// matches a table of a scope is resolved there or not at all.
+func (sc *scope) resolve(ref *parser.ColumnRef) error {
+	var first error
+	level := 0
+	for s := sc; s != nil; s = s.parent {
+		if s.ctes != nil {
+			continue
+		}
+		err := s.lookup(ref)
+		if err == nil {
+			ref.Level = level
+			for c := sc; c != s; c = c.parent {
+				c.correlated = true
+			}
+			return nil
+		}
+		if first == nil {
+			first = err
+		}
+		if _, ok := s.tables[ref.Table]; ok {
+			return err
+		}
+		level++
+	}
+	return first
+}
+
+func (sc *scope) lookup(ref *parser.ColumnRef) error {
+	name := ref.Table
+	if name == "" {
+		if sc.def == "" {
+			return fmt.Errorf("column %s cannot be referenced here", ref.Name)
+		}
+		name = sc.def
+	}
+	meta, ok := sc.tables[name]
+	if !ok {
+		return fmt.Errorf("missing FROM-clause entry for table %s", name)
+	}
+	if _, ok := meta.Column(ref.Name); !ok {
+		return fmt.Errorf("unknown column %s in table %s", ref.Name, name)
+	}
+	return nil
+}
+
+// exprType returns the type of a result column where it is known without
+// evaluating it: the declared type of a column, the type of a number or
+// boolean literal or the target of a CAST; "" otherwise
+func (sc *scope) exprType(x parser.Expr) string {
+	switch n := x.(type) {
+	case *parser.ColumnRef:
+		name := n.Table
+		if name == "" {
+			name = sc.def
+		}
+		meta := sc.tables[name]
+		if c, ok := meta.Column(n.Name); ok && n.Level == 0 {
+			return c.Type
+		}
+	case *parser.CastExpr:
+		return n.Type
+	case *parser.Literal:
+		switch n.Value.(type) {
+		case int64:
+			return "INTEGER"
+		case float64:
+			return "REAL"
+		case bool:
+			return "BOOLEAN"
+		}
+	case *parser.CaseExpr:
+		for _, x := range caseResults(n) {
+			if t := sc.exprType(x); t != "" {
+				return t
+			}
+		}
+	}
+	return ""
+}
+
+// caseResults returns the expressions a CASE can evaluate to
+func caseResults(n *parser.CaseExpr) []parser.Expr {
+	out := make([]parser.Expr, 0, len(n.Whens)+1)
+	for _, w := range n.Whens {
+		out = append(out, w.Result)
+	}
+	if n.Else != nil {
+		out = append(out, n.Else)
+	}
+	return out
+}
+
+// typeClass groups types whose values can be mixed in one result column
+func typeClass(t string) string {
+	n, _ := parser.NormalizeType(t)
+	switch n {
+	case "INTEGER", "REAL":
+		return "number"
+	case "TIMESTAMP", "DATE":
+		return "datetime"
+	}
+	return n
+}
+
+// setColumns checks that the results of the two sides of a set operation
+// line up and returns the combined result columns, named after the left
+func setColumns(op string, left, right []storage.ColumnDefinition) ([]storage.ColumnDefinition, error) {
+	if len(left) != len(right) {
+		return nil, fmt.Errorf("each %s query must have the same number of columns", op)
+	}
+	out := make([]storage.ColumnDefinition, len(left))
+	for i, l := range left {
+		r := right[i]
+		lc, rc := typeClass(l.Type), typeClass(r.Type)
+		if lc != "" && rc != "" && lc != rc {
+			return nil, fmt.Errorf("%s types %s and %s cannot be matched", op, strings.ToLower(l.Type), strings.ToLower(r.Type))
+		}
+		out[i] = l
+		if lc == "" {
+			out[i].Type = r.Type
+		}
+	}
+	return out, nil
+}
+
+func (p *Planner) table(name string) (storage.TableMeta, error) {
+	meta, ok := p.store.Table(name)
+	if !ok {
+		return storage.TableMeta{}, fmt.Errorf("%w: %s", storage.ErrTableNotFound, name)
+	}
+	return meta, nil
+}
+
+func tableScope(meta storage.TableMeta) *scope {
+	return &scope{def: meta.Name, tables: map[string]storage.TableMeta{meta.Name: meta}}
+}
+
+func checkColumns(meta storage.TableMeta, cols []string) error {
+	for _, c := range cols {
+		if _, ok := meta.Column(c); !ok {
+			return fmt.Errorf("unknown column %s in table %s", c, meta.Name)
+		}
+	}
+	return nil
+}
+
+// checkExpr walks x and validates its column references against sc,
+// binding the subqueries it contains with sc as their parent scope
+func (p *Planner) checkExpr(x parser.Expr, sc *scope) error {
+	switch n := x.(type) {
+	case *parser.ColumnRef:
+		return sc.resolve(n)
+	case *parser.SubqueryExpr:
+		sub, err := p.bindSubquery(n.Select, sc, true)
+		if err != nil {
+			return err
+		}
+		n.Correlated = sub.correlated
+		return nil
+	case *parser.ExistsExpr:
+		sub, err := p.bindSubquery(n.Select, sc, false)
+		if err != nil {
+			return err
+		}
+		n.Correlated = sub.correlated
+		return nil
+	case *parser.InExpr:
+		if n.Select != nil {
+			sub, err := p.bindSubquery(n.Select, sc, true)
+			if err != nil {
+				return err
+			}
+			n.Correlated = sub.correlated
+		}
+	}
+	for _, sub := range parser.Subexprs(x) {
+		if err := p.checkExpr(*sub, sc); err != nil {
+			return err
+		}
+	}
+	if n, ok := x.(*parser.CaseExpr); ok {
+		return checkCaseTypes(n, sc)
+	}
+	return nil
+}
+
+// checkCaseTypes makes sure the results of a CASE can share a column
+func checkCaseTypes(n *parser.CaseExpr, sc *scope) error {
+	var first string
+	for _, x := range caseResults(n) {
+		t := sc.exprType(x)
+		switch {
+		case t == "":
+		case first == "":
+			first = t
+		case typeClass(t) != typeClass(first):
+			return fmt.Errorf("CASE types %s and %s cannot be matched", strings.ToLower(first), strings.ToLower(t))
+		}
+	}
+	return nil
+}
+
+// bindSubquery binds a subquery used in an expression; scalar and IN
+// subqueries must return a single column
+func (p *Planner) bindSubquery(s *parser.SelectStmt, parent *scope, single bool) (*scope, error) {
+	sc, err := p.bindSelect(s, parent)
+	if err != nil {
+		return nil, err
+	}
+	if single && len(sc.out) != 1 {
+		return nil, fmt.Errorf("subquery must return only one column")
+	}
+	return sc, nil
+}
+
+func (p *Planner) checkItems(items []parser.SelectItem, sc *scope) error {
+	for _, it := range items {
+		if it.Star {
+			continue
+		}
+		if err := p.checkExpr(it.Expr, sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (p *Planner) checkAssignments(set []parser.Assignment, meta storage.TableMeta, sc *scope) error {
+	for _, a := range set {
+		if err := checkColumns(meta, []string{a.Column}); err != nil {
+			return err
+		}
+		if generatedColumn(meta, a.Column) {
+			return fmt.Errorf("column %s can only be updated to DEFAULT", a.Column)
+		}
+		if err := p.checkExpr(a.Value, sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// generatedColumn reports whether name is a generated column of meta,
+// whose value is always computed from the rest of the row
+func generatedColumn(meta storage.TableMeta, name string) bool {
+	c, ok := meta.Column(name)
+	return ok && c.Generated != ""
+}
+
+// bindInsert binds s inside parent, the scope of the trigger it is part
+// of or nil; so do bindUpdate and bindDelete
+func (p *Planner) bindInsert(s *parser.InsertStmt, parent *scope) error {
+	meta, err := p.target(s.Table)
+	if err != nil {
+		return err
+	}
+	if err := checkColumns(meta, s.Columns); err != nil {
+		return err
+	}
+	for _, c := range s.Columns {
+		if generatedColumn(meta, c) {
+			return fmt.Errorf("cannot insert a non-DEFAULT value into column %s", c)
+		}
+	}
+	if s.Select != nil {
+		if _, err := p.bindSelect(s.Select, parent); err != nil {
+			return err
+		}
+	}
+	for _, vals := range s.Rows {
+		for _, x := range vals {
+			if err := p.checkExpr(x, &scope{parent: parent}); err != nil {
+				return err
+			}
+		}
+	}
+	if oc := s.OnConflict; oc != nil {
+		if err := checkColumns(meta, oc.Columns); err != nil {
+			return err
+		}
+		sc := targetScope(meta, parent)
+		sc.tables["excluded"] = meta
+		if err := p.checkAssignments(oc.Set, meta, sc); err != nil {
+			return err
+		}
+	}
+	return p.checkItems(s.Returning, targetScope(meta, parent))
+}
+
+// bindSelect binds s inside parent, nil for a top-level query, and
+// returns the scope of its FROM source
+func (p *Planner) bindSelect(s *parser.SelectStmt, parent *scope) (*scope, error) {
+	if s.With != nil {
+		var err error
+		if parent, err = p.bindWith(s.With, parent); err != nil {
+			return nil, err
+		}
+	}
+	var sc *scope
+	var err error
+	if s.Set != nil {
+		sc, err = p.bindSetOp(s.Set, parent)
+	} else {
+		sc, err = p.bindQuery(s, parent)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := p.bindOrderBy(s, sc); err != nil {
+		return nil, err
+	}
+	if err := p.checkWhere(s.Limit, &scope{}); err != nil {
+		return nil, err
+	}
+	if err := p.checkWhere(s.Offset, &scope{}); err != nil {
+		return nil, err
+	}
+	return sc, nil
+}
+
+// bindQuery binds a SELECT that is not a set operation
+func (p *Planner) bindQuery(s *parser.SelectStmt, parent *scope) (*scope, error) {
+	sc := &scope{parent: parent}
+	fromParent := parent
+	if _, _, isCTE := parent.cte(s.Table); s.Table != "" && !isCTE {
+		view, err := p.view(s.Table)
+		if err != nil {
+			return nil, err
+		}
+		if view != nil {
+			// a view is read like a derived table that only sees the catalog
+			s.Alias, s.From, s.Table = s.SourceName(), view, ""
+			fromParent = nil
+		}
+	}
+	switch {
+	case s.From != nil:
+		// a derived table cannot see the query it is the source of
+		from, err := p.bindSelect(s.From, fromParent)
+		if err != nil {
+			return nil, err
+		}
+		sc.correlated = from.correlated
+		meta := storage.TableMeta{Name: s.Alias, Columns: from.out}
+		sc.def, sc.tables = s.Alias, map[string]storage.TableMeta{s.Alias: meta}
+	case s.Table != "":
+		cte, meta, ok := parent.cte(s.Table)
+		s.CTE = cte
+		if !ok {
+			var err error
+			if meta, err = p.table(s.Table); err != nil {
+				return nil, err
+			}
+		}
+		meta.Name = s.SourceName()
+		sc.def, sc.tables = meta.Name, map[string]storage.TableMeta{meta.Name: meta}
+	default:
+		for _, it := range s.Items {
+			if it.Star {
+				return nil, fmt.Errorf("SELECT * with no tables specified is not valid")
+			}
+		}
+	}
+	if err := p.checkItems(s.Items, sc); err != nil {
+		return nil, err
+	}
+	for _, x := range s.GroupBy {
+		if err := p.checkExpr(x, sc); err != nil {
+			return nil, err
+		}
+	}
+	if err := p.checkWhere(s.Having, sc); err != nil {
+		return nil, err
+	}
+	if err := p.checkWhere(s.Where, sc); err != nil {
+		return nil, err
+	}
+
+	var star []string
+	for _, it := range s.Items {
+		if !it.Star {
+			sc.out = append(sc.out, storage.ColumnDefinition{Type: sc.exprType(it.Expr)})
+			continue
+		}
+		for _, c := range sc.tables[sc.def].Columns {
+			star = append(star, c.Name)
+			sc.out = append(sc.out, storage.ColumnDefinition{Type: c.Type})
+		}
+	}
+	for i, name := range parser.ColumnNames(s.Items, star) {
+		sc.out[i].Name = name
+	}
+	return sc, nil
+}
+
+// bindSetOp binds both sides of a set operation. The scope returned only
+// carries the combined result columns.
+func (p *Planner) bindSetOp(set *parser.SetOp, parent *scope) (*scope, error) {
+	left, err := p.bindSelect(set.Left, parent)
+	if err != nil {
+		return nil, err
+	}
+	right, err := p.bindSelect(set.Right, parent)
+	if err != nil {
+		return nil, err
+	}
+	out, err := setColumns(set.Op, left.out, right.out)
+	if err != nil {
+		return nil, err
+	}
+	return &scope{parent: parent, correlated: left.correlated || right.correlated, out: out}, nil
+}
+
+// bindOrderBy resolves the ORDER BY keys of s. A key naming a result
+// column, giving its position or repeating an item of the select list sorts
+// by that column; any other key is an expression over the FROM source, which
+// neither a set operation nor SELECT DISTINCT can sort by.
+func (p *Planner) bindOrderBy(s *parser.SelectStmt, sc *scope) error {
+	for i := range s.OrderBy {
+		it := &s.OrderBy[i]
+		switch n := it.Expr.(type) {
+		case *parser.ColumnRef:
+			for j, c := range sc.out {
+				if n.Table == "" && c.Name == n.Name {
+					it.Column = j + 1
+					break
+				}
+			}
+		case *parser.Literal:
+			if pos, ok := n.Value.(int64); ok {
+				if pos < 1 || pos > int64(len(sc.out)) {
+					return fmt.Errorf("ORDER BY position %d is not in select list", pos)
+				}
+				it.Column = int(pos)
+			}
+		}
+		if it.Column == 0 {
+			it.Column = itemColumn(s, sc, it.Expr)
+		}
+		if it.Column > 0 {
+			continue
+		}
+		if s.Set != nil {
+			return fmt.Errorf("invalid UNION/INTERSECT/EXCEPT ORDER BY clause")
+		}
+		if s.Distinct {
+			return fmt.Errorf("for SELECT DISTINCT, ORDER BY expressions must appear in select list")
+		}
+		if err := p.checkExpr(it.Expr, sc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// itemColumn returns the 1-based result column computed by the select item
+// that is the same expression as x, or 0
+func itemColumn(s *parser.SelectStmt, sc *scope, x parser.Expr) int {
+	col := 1
+	for _, it := range s.Items {
+		if it.Star {
+			col += len(sc.tables[sc.def].Columns)
+			continue
+		}
+		if reflect.DeepEqual(it.Expr, x) {
+			return col
+		}
+		col++
+	}
+	return 0
+}
+
+// bindWith binds the WITH queries in order, each seeing the ones before it,
+// and returns the scope that makes them visible to the SELECT. Under WITH
+// RECURSIVE the term after UNION [ALL] also sees the query itself.
+func (p *Planner) bindWith(with *parser.With, parent *scope) (*scope, error) {
+	ws := &scope{
+		parent:  parent,
+		ctes:    map[string]*parser.CTE{},
+		cteMeta: map[*parser.CTE]storage.TableMeta{},
+	}
+	for _, cte := range with.CTEs {
+		if _, dup := ws.ctes[cte.Name]; dup {
+			return nil, fmt.Errorf("WITH query name %s specified more than once", cte.Name)
+		}
+		sc, err := p.bindSelect(cte.Select, ws)
+		if err != nil {
+			return nil, err
+		}
+		cols := sc.out
+		if len(cte.Columns) > 0 {
+			if len(cte.Columns) != len(cols) {
+				return nil, fmt.Errorf("WITH query %s has %d columns available but %d columns specified",
+					cte.Name, len(cols), len(cte.Columns))
+			}
+			cols = slices.Clone(cols)
+			for i, name := range cte.Columns {
+				cols[i].Name = name
+			}
+		}
+		meta := storage.TableMeta{Name: cte.Name, Columns: cols}
+
+		if cte.Union != nil {
+			if with.Recursive {
+				ws.ctes[cte.Name], ws.cteMeta[cte] = cte, meta
+			}
+			sc, err := p.bindSelect(cte.Union, ws)
+			if err != nil {
+				return nil, err
+			}
+			if _, err := setColumns("UNION", cols, sc.out); err != nil {
+				return nil, err
+			}
+			cte.Recursive = with.Recursive && readsCTE(cte.Union, cte)
+		}
+		ws.ctes[cte.Name], ws.cteMeta[cte] = cte, meta
+	}
+	return ws, nil
+}
+
+// readsCTE reports whether s or one of its subqueries reads cte
+func readsCTE(s *parser.SelectStmt, cte *parser.CTE) bool {
+	if s.CTE == cte {
+		return true
+	}
+	for _, sub := range subqueries(s) {
+		if readsCTE(sub, cte) {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *Planner) bindUpdate(s *parser.UpdateStmt, parent *scope) error {
+	meta, err := p.target(s.Table)
+	if err != nil {
+		return err
+	}
+	if err := p.checkAssignments(s.Set, meta, targetScope(meta, parent)); err != nil {
+		return err
+	}
+	if err := p.checkWhere(s.Where, targetScope(meta, parent)); err != nil {
+		return err
+	}
+	return p.checkItems(s.Returning, targetScope(meta, parent))
+}
+
+func (p *Planner) bindDelete(s *parser.DeleteStmt, parent *scope) error {
+	meta, err := p.target(s.Table)
+	if err != nil {
+		return err
+	}
+	if err := p.checkWhere(s.Where, targetScope(meta, parent)); err != nil {
+		return err
+	}
+	return p.checkItems(s.Returning, targetScope(meta, parent))
+}
+
+// targetScope is the scope of the table a statement changes
+func targetScope(meta storage.TableMeta, parent *scope) *scope {
+	sc := tableScope(meta)
+	sc.parent = parent
+	return sc
+}
+
+func (p *Planner) checkWhere(where parser.Expr, sc *scope) error {
+	if where == nil {
+		return nil
+	}
+	return p.checkExpr(where, sc)
+}
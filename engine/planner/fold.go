@@ -0,0 +1,78 @@
+package planner
+
+import "github.com/Alwin18/nalarSQL/engine/parser"
+
+// ConstEvaluator evaluates constant expressions at plan time. The executor
+// implements it, since it owns the function registry.
+type ConstEvaluator interface {
+	// Deterministic reports whether the scalar function always returns the
+	// same result for the same arguments
+	Deterministic(name string) bool
+	EvalConst(x parser.Expr) (any, error)
+}
+
+// foldStmt replaces the constant subexpressions of a bound statement and
+// of its subqueries with their values, so they are computed once instead of
+// once per row. Calls of volatile functions such as NOW() and of aggregates
+// are left in place.
+func (p *Planner) foldStmt(stmt parser.Statement) error {
+	if p.consts == nil {
+		return nil
+	}
+	for _, x := range exprSlots(stmt) {
+		folded, _, err := p.fold(*x)
+		if err != nil {
+			return err
+		}
+		*x = folded
+	}
+	for _, sub := range subqueries(stmt) {
+		if err := p.foldStmt(sub); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fold folds x bottom-up and reports whether the result is a constant
+func (p *Planner) fold(x parser.Expr) (parser.Expr, bool, error) {
+	switch n := x.(type) {
+	case *parser.Literal:
+		return n, true, nil
+	case *parser.ColumnRef:
+		return n, false, nil
+	}
+
+	constant := true
+	for _, sub := range parser.Subexprs(x) {
+		folded, c, err := p.fold(*sub)
+		if err != nil {
+			return nil, false, err
+		}
+		*sub = folded
+		constant = constant && c
+	}
+	if !constant {
+		return x, false, nil
+	}
+	// operators over constants are constant; calls only when deterministic
+	switch n := x.(type) {
+	case *parser.BinaryExpr, *parser.UnaryExpr, *parser.CastExpr, *parser.LikeExpr,
+		*parser.BetweenExpr, *parser.IsNullExpr, *parser.CaseExpr:
+	case *parser.InExpr:
+		if n.Select != nil {
+			return x, false, nil
+		}
+	case *parser.FuncCall:
+		if n.Star || n.Distinct || n.Over != nil || !p.consts.Deterministic(n.Name) {
+			return x, false, nil
+		}
+	default:
+		return x, false, nil
+	}
+	v, err := p.consts.EvalConst(x)
+	if err != nil {
+		return nil, false, err
+	}
+	return &parser.Literal{Value: v, Name: parser.ExprName(x)}, true, nil
+}
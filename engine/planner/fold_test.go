@@ -0,0 +1,41 @@
+package planner
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// fakeConsts folds every call to the same value
+type fakeConsts struct{}
+
+func (fakeConsts) Deterministic(name string) bool       { return name != "NOW" }
+func (fakeConsts) EvalConst(x parser.Expr) (any, error) { return "v", nil }
+
+func TestFoldKeepsColumnNames(t *testing.T) {
+	st, err := storage.NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer st.Close()
+	stmt, err := parser.Parse("SELECT UPPER('q'), CAST(1 AS INT), ROUND(3.4, 0), EXTRACT(YEAR FROM DATE '2024-01-02'), 1 + 2, UPPER('a') AS u, NOW()")
+	if err != nil {
+		t.Fatal(err)
+	}
+	plan, err := NewPlanner(st, fakeConsts{}).Plan(stmt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := plan.(*PlanSelect).Stmt.Items
+	for i, it := range items[:6] {
+		if _, ok := it.Expr.(*parser.Literal); !ok {
+			t.Fatalf("item %d not folded: %#v", i, it.Expr)
+		}
+	}
+	want := []string{"upper", "integer", "round", "extract", "?column?", "u", "now"}
+	if got := parser.ColumnNames(items, nil); !reflect.DeepEqual(got, want) {
+		t.Fatalf("names = %v, want %v", got, want)
+	}
+}
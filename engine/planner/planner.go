@@ -8,10 +8,14 @@ import (
 // Planner converts AST -> Plan (very small abstraction)
 
 type Planner struct {
-	store *storage.Store
+	store  *storage.Store
+	consts ConstEvaluator
 }
 
-func NewPlanner(s *storage.Store) *Planner { return &Planner{store: s} }
+// NewPlanner creates a planner; consts may be nil to disable constant folding
+func NewPlanner(s *storage.Store, consts ConstEvaluator) *Planner {
+	return &Planner{store: s, consts: consts}
+}
 
 type Plan interface{}
 
@@ -19,35 +23,220 @@ type PlanCreateTable struct {
 	Stmt *parser.CreateTableStmt
 }
 
+type PlanCreateIndex struct {
+	Stmt *parser.CreateIndexStmt
+}
+
 type PlanInsert struct {
-	Stmt *parser.InsertStmt
+	Stmt     *parser.InsertStmt
+	Access   AccessPaths // of INSERT ... SELECT and subqueries
+	Triggers Triggers    // including UPDATE triggers for ON CONFLICT DO UPDATE
 }
 
 type PlanSelect struct {
-	Stmt *parser.SelectStmt
+	Stmt   *parser.SelectStmt
+	Access AccessPaths
 }
 
 type PlanUpdate struct {
-	Stmt *parser.UpdateStmt
+	Stmt     *parser.UpdateStmt
+	Access   AccessPaths // of subqueries
+	Triggers Triggers
 }
 
 type PlanDelete struct {
-	Stmt *parser.DeleteStmt
+	Stmt     *parser.DeleteStmt
+	Access   AccessPaths // of subqueries
+	Triggers Triggers
+}
+
+type PlanCreateView struct {
+	Stmt    *parser.CreateViewStmt
+	Columns []storage.ColumnDefinition
+	Access  AccessPaths // of the query of a materialized view
+}
+
+type PlanDropView struct {
+	Stmt *parser.DropViewStmt
+}
+
+type PlanRefreshView struct {
+	Stmt   *parser.RefreshViewStmt
+	Select *parser.SelectStmt // the view's query
+	Access AccessPaths
+}
+
+type PlanCreateSequence struct {
+	Stmt *parser.CreateSequenceStmt
+}
+
+type PlanDropSequence struct {
+	Stmt *parser.DropSequenceStmt
+}
+
+type PlanCreateTrigger struct {
+	Stmt *parser.CreateTriggerStmt
+}
+
+type PlanDropTrigger struct {
+	Stmt *parser.DropTriggerStmt
+}
+
+// PlanSetNew changes the row of the BEFORE trigger it is part of
+type PlanSetNew struct {
+	Stmt *parser.SetNewStmt
+}
+
+// PlanListen and PlanUnlisten change the channels of the session that
+// runs them and are handled by the engine
+type PlanListen struct {
+	Stmt *parser.ListenStmt
+}
+
+type PlanUnlisten struct {
+	Stmt *parser.UnlistenStmt
+}
+
+type PlanNotify struct {
+	Stmt   *parser.NotifyStmt
+	Access AccessPaths // of subqueries of the payload
+}
+
+// PlanVacuum compacts table files; it runs outside of a transaction
+type PlanVacuum struct {
+	Stmt *parser.VacuumStmt
+}
+
+type PlanShowTables struct{}
+
+type PlanDescribe struct {
+	Stmt *parser.DescribeStmt
 }
 
 func (p *Planner) Plan(stmt parser.Statement) (Plan, error) {
 	switch s := stmt.(type) {
 	case *parser.CreateTableStmt:
+		if err := p.bindCreateTable(s); err != nil {
+			return nil, err
+		}
 		return &PlanCreateTable{Stmt: s}, nil
+	case *parser.CreateIndexStmt:
+		return &PlanCreateIndex{Stmt: s}, nil
+	case *parser.InsertStmt, *parser.SelectStmt, *parser.UpdateStmt, *parser.DeleteStmt, *parser.NotifyStmt:
+		return p.planRows(stmt, nil, map[string]*PlanTrigger{})
+	case *parser.CreateViewStmt:
+		cols, err := p.bindCreateView(s)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.foldStmt(s.Select); err != nil {
+			return nil, err
+		}
+		return &PlanCreateView{Stmt: s, Columns: cols, Access: p.planAccess(s.Select)}, nil
+	case *parser.DropViewStmt:
+		if err := p.bindDropView(s); err != nil {
+			return nil, err
+		}
+		return &PlanDropView{Stmt: s}, nil
+	case *parser.RefreshViewStmt:
+		sel, err := p.bindRefreshView(s)
+		if err != nil {
+			return nil, err
+		}
+		if err := p.foldStmt(sel); err != nil {
+			return nil, err
+		}
+		return &PlanRefreshView{Stmt: s, Select: sel, Access: p.planAccess(sel)}, nil
+	case *parser.CreateSequenceStmt:
+		return &PlanCreateSequence{Stmt: s}, nil
+	case *parser.DropSequenceStmt:
+		return &PlanDropSequence{Stmt: s}, nil
+	case *parser.CreateTriggerStmt:
+		if err := p.bindCreateTrigger(s); err != nil {
+			return nil, err
+		}
+		return &PlanCreateTrigger{Stmt: s}, nil
+	case *parser.DropTriggerStmt:
+		return &PlanDropTrigger{Stmt: s}, nil
+	case *parser.ListenStmt:
+		return &PlanListen{Stmt: s}, nil
+	case *parser.UnlistenStmt:
+		return &PlanUnlisten{Stmt: s}, nil
+	case *parser.VacuumStmt:
+		return &PlanVacuum{Stmt: s}, nil
+	case *parser.ShowTablesStmt:
+		return &PlanShowTables{}, nil
+	case *parser.DescribeStmt:
+		return &PlanDescribe{Stmt: s}, nil
+	default:
+		return nil, ErrUnsupportedPlan
+	}
+}
+
+// planRows plans a statement that reads or changes rows. parent is the
+// scope of the trigger the statement is part of, nil at the top level;
+// planned holds the triggers planned so far for the top-level statement,
+// so that triggers firing each other are planned once.
+func (p *Planner) planRows(stmt parser.Statement, parent *scope, planned map[string]*PlanTrigger) (Plan, error) {
+	switch s := stmt.(type) {
 	case *parser.InsertStmt:
-		return &PlanInsert{Stmt: s}, nil
+		if err := p.bindInsert(s, parent); err != nil {
+			return nil, err
+		}
+		if err := p.foldStmt(s); err != nil {
+			return nil, err
+		}
+		events := []string{"INSERT"}
+		if s.OnConflict != nil && !s.OnConflict.DoNothing {
+			events = append(events, "UPDATE")
+		}
+		trigs, err := p.triggers(s.Table, events, planned)
+		if err != nil {
+			return nil, err
+		}
+		return &PlanInsert{Stmt: s, Access: p.planAccess(s), Triggers: trigs}, nil
 	case *parser.SelectStmt:
-		return &PlanSelect{Stmt: s}, nil
+		if _, err := p.bindSelect(s, parent); err != nil {
+			return nil, err
+		}
+		if err := p.foldStmt(s); err != nil {
+			return nil, err
+		}
+		return &PlanSelect{Stmt: s, Access: p.planAccess(s)}, nil
 	case *parser.UpdateStmt:
-		return &PlanUpdate{Stmt: s}, nil
+		if err := p.bindUpdate(s, parent); err != nil {
+			return nil, err
+		}
+		if err := p.foldStmt(s); err != nil {
+			return nil, err
+		}
+		trigs, err := p.triggers(s.Table, []string{"UPDATE"}, planned)
+		if err != nil {
+			return nil, err
+		}
+		return &PlanUpdate{Stmt: s, Access: p.planAccess(s), Triggers: trigs}, nil
 	case *parser.DeleteStmt:
-		return &PlanDelete{Stmt: s}, nil
-	default:
-		return nil, ErrUnsupportedPlan
+		if err := p.bindDelete(s, parent); err != nil {
+			return nil, err
+		}
+		if err := p.foldStmt(s); err != nil {
+			return nil, err
+		}
+		trigs, err := p.triggers(s.Table, []string{"DELETE"}, planned)
+		if err != nil {
+			return nil, err
+		}
+		return &PlanDelete{Stmt: s, Access: p.planAccess(s), Triggers: trigs}, nil
+	case *parser.NotifyStmt:
+		if s.Payload != nil {
+			if err := p.checkExpr(s.Payload, &scope{parent: parent}); err != nil {
+				return nil, err
+			}
+		}
+		if err := p.foldStmt(s); err != nil {
+			return nil, err
+		}
+		return &PlanNotify{Stmt: s, Access: p.planAccess(s)}, nil
 	}
+	return nil, ErrUnsupportedPlan
 }
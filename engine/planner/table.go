@@ -0,0 +1,117 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// bindCreateTable validates the expressions a new table stores: column
+// defaults, which may not read columns, CHECK constraints, which may only
+// read columns of the table itself, and generation expressions, which may
+// only read its plain columns
+func (p *Planner) bindCreateTable(s *parser.CreateTableStmt) error {
+	meta := storage.TableMeta{Name: s.TableName}
+	plain := storage.TableMeta{Name: s.TableName}
+	generated := map[string]bool{}
+	for _, c := range s.Columns {
+		col := storage.ColumnDefinition{Name: c.Name, Type: c.Type}
+		meta.Columns = append(meta.Columns, col)
+		if c.Generated != nil {
+			generated[c.Name] = true
+		} else {
+			plain.Columns = append(plain.Columns, col)
+		}
+	}
+	checks := s.Checks
+	for _, c := range s.Columns {
+		if c.Default != nil {
+			if err := p.checkTableExpr(c.Default, "DEFAULT expression", nil); err != nil {
+				return err
+			}
+		}
+		if c.Generated != nil {
+			if c.Default != nil || c.AutoIncrement {
+				return fmt.Errorf("both default and generation expression specified for column %s", c.Name)
+			}
+			if err := p.checkGenerated(c.Generated, plain, generated); err != nil {
+				return err
+			}
+		}
+		checks = append(checks, c.Checks...)
+	}
+	for _, c := range checks {
+		if err := p.checkTableExpr(c.Expr, "check constraint", tableScope(meta)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkGenerated validates a generation expression: its value may only
+// depend on the plain columns of the row
+func (p *Planner) checkGenerated(x parser.Expr, plain storage.TableMeta, generated map[string]bool) error {
+	var err error
+	var walk func(x parser.Expr)
+	walk = func(x parser.Expr) {
+		switch n := x.(type) {
+		case *parser.ColumnRef:
+			if generated[n.Name] {
+				err = fmt.Errorf("cannot use generated column %s in column generation expression", n.Name)
+			}
+		case *parser.FuncCall:
+			if p.consts != nil && !p.consts.Deterministic(n.Name) {
+				err = fmt.Errorf("generation expression is not immutable")
+			}
+		}
+		for _, sub := range parser.Subexprs(x) {
+			if err == nil {
+				walk(*sub)
+			}
+		}
+	}
+	if walk(x); err != nil {
+		return err
+	}
+	return p.checkTableExpr(x, "column generation expression", tableScope(plain))
+}
+
+// checkTableExpr validates an expression of a table definition against
+// sc, nil when it may not reference columns. Stored expressions are
+// evaluated for a single row, so they cannot run queries or window
+// functions.
+func (p *Planner) checkTableExpr(x parser.Expr, what string, sc *scope) error {
+	var err error
+	var walk func(x parser.Expr)
+	walk = func(x parser.Expr) {
+		switch n := x.(type) {
+		case *parser.SubqueryExpr, *parser.ExistsExpr:
+			err = fmt.Errorf("cannot use subquery in %s", what)
+		case *parser.InExpr:
+			if n.Select != nil {
+				err = fmt.Errorf("cannot use subquery in %s", what)
+			}
+		case *parser.FuncCall:
+			if n.Over != nil {
+				err = fmt.Errorf("cannot use window function in %s", what)
+			}
+		case *parser.ColumnRef:
+			if sc == nil {
+				err = fmt.Errorf("cannot use column reference in %s", what)
+			}
+		}
+		for _, sub := range parser.Subexprs(x) {
+			if err == nil {
+				walk(*sub)
+			}
+		}
+	}
+	if walk(x); err != nil {
+		return err
+	}
+	if sc == nil {
+		sc = &scope{}
+	}
+	return p.checkExpr(x, sc)
+}
@@ -0,0 +1,115 @@
+package planner
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// PlanTrigger is a row trigger with its body planned: the executor runs
+// the body once for every row the firing statement changes
+type PlanTrigger struct {
+	Name string
+	Body []Plan
+}
+
+// Triggers holds the row triggers a statement fires, keyed by timing and
+// event, e.g. "BEFORE INSERT", in the order they fire
+type Triggers map[string][]*PlanTrigger
+
+// Fire returns the triggers to run at timing for event
+func (t Triggers) Fire(timing, event string) []*PlanTrigger {
+	return t[timing+" "+event]
+}
+
+// triggerScope is the scope of a trigger body on meta: the row it fires
+// for is visible as new and, for updates and deletes, its old version as
+// old. Columns must be qualified.
+func triggerScope(meta storage.TableMeta, event string) *scope {
+	sc := &scope{tables: map[string]storage.TableMeta{}}
+	if event != "DELETE" {
+		sc.tables["new"] = meta
+	}
+	if event != "INSERT" {
+		sc.tables["old"] = meta
+	}
+	return sc
+}
+
+// bindCreateTrigger checks the table of a new trigger and binds its body
+func (p *Planner) bindCreateTrigger(s *parser.CreateTriggerStmt) error {
+	meta, err := p.target(s.Table)
+	if err != nil {
+		return err
+	}
+	_, err = p.planTriggerBody(s.Body, meta, s.Timing, s.Event, map[string]*PlanTrigger{})
+	return err
+}
+
+// triggers plans the triggers on table that fire for one of events
+func (p *Planner) triggers(table string, events []string, planned map[string]*PlanTrigger) (Triggers, error) {
+	var out Triggers
+	for _, t := range p.store.Triggers(table) {
+		if !slices.Contains(events, t.Event) {
+			continue
+		}
+		pt, err := p.planTrigger(t, planned)
+		if err != nil {
+			return nil, err
+		}
+		if out == nil {
+			out = Triggers{}
+		}
+		key := t.Timing + " " + t.Event
+		out[key] = append(out[key], pt)
+	}
+	return out, nil
+}
+
+// planTrigger parses and plans the body of a stored trigger. A trigger
+// whose body fires it again reuses the plan that is being built.
+func (p *Planner) planTrigger(t storage.TriggerMeta, planned map[string]*PlanTrigger) (*PlanTrigger, error) {
+	if pt, ok := planned[t.Name]; ok {
+		return pt, nil
+	}
+	pt := &PlanTrigger{Name: t.Name}
+	planned[t.Name] = pt
+	body, err := parser.ParseTriggerBody(t.Body)
+	if err != nil {
+		return nil, fmt.Errorf("trigger %s: %w", t.Name, err)
+	}
+	meta, err := p.table(t.Table)
+	if err != nil {
+		return nil, err
+	}
+	if pt.Body, err = p.planTriggerBody(body, meta, t.Timing, t.Event, planned); err != nil {
+		return nil, fmt.Errorf("trigger %s: %w", t.Name, err)
+	}
+	return pt, nil
+}
+
+// planTriggerBody binds and plans the statements of a trigger on meta
+func (p *Planner) planTriggerBody(body []parser.Statement, meta storage.TableMeta, timing, event string, planned map[string]*PlanTrigger) ([]Plan, error) {
+	sc := triggerScope(meta, event)
+	plans := make([]Plan, len(body))
+	for i, stmt := range body {
+		if s, ok := stmt.(*parser.SetNewStmt); ok {
+			if timing != "BEFORE" || event == "DELETE" {
+				return nil, fmt.Errorf("SET NEW is only allowed in BEFORE INSERT and BEFORE UPDATE triggers")
+			}
+			if err := p.checkAssignments([]parser.Assignment{{Column: s.Column, Value: s.Value}}, meta, sc); err != nil {
+				return nil, err
+			}
+			plans[i] = &PlanSetNew{Stmt: s}
+			continue
+		}
+		plan, err := p.planRows(stmt, sc, planned)
+		if err != nil {
+			return nil, err
+		}
+		plans[i] = plan
+	}
+	return plans, nil
+}
@@ -0,0 +1,125 @@
+package planner
+
+import (
+	"fmt"
+
+	"github.com/Alwin18/nalarSQL/engine/parser"
+	"github.com/Alwin18/nalarSQL/engine/storage"
+)
+
+// view returns the query of name, parsed afresh, when name is a plain
+// view; nil otherwise. Materialized views are read as tables.
+func (p *Planner) view(name string) (*parser.SelectStmt, error) {
+	v, ok := p.store.ViewDef(name)
+	if !ok || v.Materialized {
+		return nil, nil
+	}
+	return parseView(v)
+}
+
+func parseView(v storage.ViewMeta) (*parser.SelectStmt, error) {
+	stmt, err := parser.Parse(v.Query)
+	if err != nil {
+		return nil, fmt.Errorf("view %s: %w", v.Name, err)
+	}
+	sel, ok := stmt.(*parser.SelectStmt)
+	if !ok {
+		return nil, fmt.Errorf("view %s: query is not a SELECT", v.Name)
+	}
+	return sel, nil
+}
+
+// bindCreateView binds the query of a new view and returns its columns
+func (p *Planner) bindCreateView(s *parser.CreateViewStmt) ([]storage.ColumnDefinition, error) {
+	sc, err := p.bindSelect(s.Select, nil)
+	if err != nil {
+		return nil, err
+	}
+	seen := map[string]bool{}
+	for _, c := range sc.out {
+		if seen[c.Name] {
+			return nil, fmt.Errorf("column %s specified more than once", c.Name)
+		}
+		seen[c.Name] = true
+	}
+	return sc.out, nil
+}
+
+// bindDropView checks that name is a view of the kind the statement drops
+// and that no other view reads it
+func (p *Planner) bindDropView(s *parser.DropViewStmt) error {
+	v, ok := p.store.ViewDef(s.Name)
+	switch {
+	case !ok:
+		if _, isTable := p.store.Table(s.Name); isTable {
+			return fmt.Errorf("%s is not a view", s.Name)
+		}
+		return nil // IF EXISTS is applied by the executor
+	case v.Materialized && !s.Materialized:
+		return fmt.Errorf("%s is not a view, use DROP MATERIALIZED VIEW", s.Name)
+	case !v.Materialized && s.Materialized:
+		return fmt.Errorf("%s is not a materialized view", s.Name)
+	}
+	for _, other := range p.store.Views() {
+		if other.Name == s.Name {
+			continue
+		}
+		sel, err := parseView(other)
+		if err != nil {
+			return err
+		}
+		if readsTable(sel, s.Name) {
+			return fmt.Errorf("cannot drop view %s because view %s depends on it", s.Name, other.Name)
+		}
+	}
+	return nil
+}
+
+// bindRefreshView binds the query of a materialized view for recomputing it
+func (p *Planner) bindRefreshView(s *parser.RefreshViewStmt) (*parser.SelectStmt, error) {
+	v, ok := p.store.ViewDef(s.Name)
+	if !ok || !v.Materialized {
+		return nil, fmt.Errorf("%s is not a materialized view", s.Name)
+	}
+	sel, err := parseView(v)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := p.bindSelect(sel, nil); err != nil {
+		return nil, fmt.Errorf("view %s: %w", s.Name, err)
+	}
+	return sel, nil
+}
+
+// readsTable reports whether an unbound query reads the table or view
+// name anywhere, not counting WITH queries of that name
+func readsTable(s *parser.SelectStmt, name string) bool {
+	if s.With != nil {
+		for _, cte := range s.With.CTEs {
+			if cte.Name == name {
+				return false
+			}
+		}
+	}
+	if s.Table == name {
+		return true
+	}
+	for _, sub := range subqueries(s) {
+		if readsTable(sub, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// target returns the table written by INSERT, UPDATE or DELETE, rejecting
+// views
+func (p *Planner) target(name string) (storage.TableMeta, error) {
+	if v, ok := p.store.ViewDef(name); ok {
+		if v.Materialized {
+			return storage.TableMeta{}, fmt.Errorf("cannot change materialized view %s", name)
+		}
+		return storage.TableMeta{}, fmt.Errorf("cannot change view %s", name)
+	}
+	return p.table(name)
+}
@@ -0,0 +1,110 @@
+package planner
+
+import "github.com/Alwin18/nalarSQL/engine/parser"
+
+// exprSlots returns pointers to the top-level expressions of a statement,
+// so that walkers can inspect or replace them. Nested SELECTs are not
+// included, see subqueries.
+func exprSlots(stmt parser.Statement) []*parser.Expr {
+	var slots []*parser.Expr
+	add := func(x *parser.Expr) {
+		if *x != nil {
+			slots = append(slots, x)
+		}
+	}
+	addItems := func(items []parser.SelectItem) {
+		for i := range items {
+			if !items[i].Star {
+				add(&items[i].Expr)
+			}
+		}
+	}
+	addSet := func(set []parser.Assignment) {
+		for i := range set {
+			add(&set[i].Value)
+		}
+	}
+
+	switch s := stmt.(type) {
+	case *parser.SelectStmt:
+		addItems(s.Items)
+		add(&s.Where)
+		for i := range s.GroupBy {
+			add(&s.GroupBy[i])
+		}
+		add(&s.Having)
+		for i := range s.OrderBy {
+			add(&s.OrderBy[i].Expr)
+		}
+		add(&s.Limit)
+		add(&s.Offset)
+	case *parser.InsertStmt:
+		for _, vals := range s.Rows {
+			for i := range vals {
+				add(&vals[i])
+			}
+		}
+		if s.OnConflict != nil {
+			addSet(s.OnConflict.Set)
+		}
+		addItems(s.Returning)
+	case *parser.UpdateStmt:
+		addSet(s.Set)
+		add(&s.Where)
+		addItems(s.Returning)
+	case *parser.DeleteStmt:
+		add(&s.Where)
+		addItems(s.Returning)
+	case *parser.NotifyStmt:
+		add(&s.Payload)
+	}
+	return slots
+}
+
+// subqueries returns the SELECTs nested directly in a statement: its WITH
+// queries, derived table, set operation sides, INSERT ... SELECT source and
+// expression subqueries
+func subqueries(stmt parser.Statement) []*parser.SelectStmt {
+	var subs []*parser.SelectStmt
+	switch s := stmt.(type) {
+	case *parser.SelectStmt:
+		if s.With != nil {
+			for _, cte := range s.With.CTEs {
+				subs = append(subs, cte.Select)
+				if cte.Union != nil {
+					subs = append(subs, cte.Union)
+				}
+			}
+		}
+		if s.From != nil {
+			subs = append(subs, s.From)
+		}
+		if s.Set != nil {
+			subs = append(subs, s.Set.Left, s.Set.Right)
+		}
+	case *parser.InsertStmt:
+		if s.Select != nil {
+			subs = append(subs, s.Select)
+		}
+	}
+	var walk func(x parser.Expr)
+	walk = func(x parser.Expr) {
+		switch n := x.(type) {
+		case *parser.SubqueryExpr:
+			subs = append(subs, n.Select)
+		case *parser.ExistsExpr:
+			subs = append(subs, n.Select)
+		case *parser.InExpr:
+			if n.Select != nil {
+				subs = append(subs, n.Select)
+			}
+		}
+		for _, sub := range parser.Subexprs(x) {
+			walk(*sub)
+		}
+	}
+	for _, x := range exprSlots(stmt) {
+		walk(*x)
+	}
+	return subs
+}
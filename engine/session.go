@@ -0,0 +1,223 @@
+package engine
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/Alwin18/nalarSQL/engine/executor"
+	"github.com/Alwin18/nalarSQL/engine/planner"
+)
+
+// ErrSessionClosed is returned by the statements of a closed session
+var ErrSessionClosed = errors.New("session is closed")
+
+// Notification is a message sent with NOTIFY to the sessions listening on
+// its channel
+type Notification struct {
+	Channel string
+	Payload string
+}
+
+// listeners routes notifications to the sessions listening on a channel
+type listeners struct {
+	mu       sync.Mutex
+	sessions map[*Session]bool
+	channels map[string]map[*Session]bool
+}
+
+func newListeners() *listeners {
+	return &listeners{sessions: map[*Session]bool{}, channels: map[string]map[*Session]bool{}}
+}
+
+// Notify queues a notification for every session listening on channel
+func (l *listeners) Notify(channel, payload string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	for s := range l.channels[channel] {
+		s.push(Notification{Channel: channel, Payload: payload})
+	}
+}
+
+// Session runs statements like Engine.ExecSQL and can also LISTEN for
+// notifications, which are delivered on Notifications once the statement
+// that sent them committed. A network front end serves each connection
+// with a session of its own.
+type Session struct {
+	e    *Engine
+	sess *executor.Session
+	out  chan Notification
+	wake chan struct{}
+	done chan struct{}
+	once sync.Once
+
+	mu     sync.Mutex
+	queue  []Notification // waiting for room in out, oldest first
+	closed bool
+}
+
+// NewSession starts a session; Close ends it
+func (e *Engine) NewSession() *Session {
+	s := &Session{
+		e:    e,
+		sess: executor.NewSession(),
+		out:  make(chan Notification, 64),
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+	e.listeners.mu.Lock()
+	e.listeners.sessions[s] = true
+	e.listeners.mu.Unlock()
+	go s.run()
+	return s
+}
+
+// ExecSQL parses, plans and executes a single SQL statement, including
+// LISTEN and UNLISTEN
+func (s *Session) ExecSQL(sql string) (any, error) {
+	select {
+	case <-s.done:
+		return nil, ErrSessionClosed
+	default:
+	}
+	return s.e.exec(sql, s)
+}
+
+// Notifications returns the channel notifications are delivered on, in the
+// order they were sent. Those the reader is not ready for wait in a queue
+// of the session, so a slow reader never holds up writers. The channel is
+// closed when the session is.
+func (s *Session) Notifications() <-chan Notification {
+	return s.out
+}
+
+// Close stops listening on every channel and closes Notifications
+func (s *Session) Close() error {
+	s.once.Do(func() {
+		l := s.e.listeners
+		l.mu.Lock()
+		delete(l.sessions, s)
+		for ch := range l.channels {
+			s.unlistenLocked(ch)
+		}
+		l.mu.Unlock()
+
+		s.mu.Lock()
+		s.closed = true
+		s.mu.Unlock()
+		close(s.done)
+	})
+	return nil
+}
+
+func (s *Session) listen(channel string) {
+	l := s.e.listeners
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.channels[channel] == nil {
+		l.channels[channel] = map[*Session]bool{}
+	}
+	l.channels[channel][s] = true
+}
+
+// unlisten stops listening on channel, or on every channel when it is ""
+func (s *Session) unlisten(channel string) {
+	l := s.e.listeners
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if channel != "" {
+		s.unlistenLocked(channel)
+		return
+	}
+	for ch := range l.channels {
+		s.unlistenLocked(ch)
+	}
+}
+
+// unlistenLocked is unlisten of a single channel; listeners.mu must be held
+func (s *Session) unlistenLocked(channel string) {
+	l := s.e.listeners
+	delete(l.channels[channel], s)
+	if len(l.channels[channel]) == 0 {
+		delete(l.channels, channel)
+	}
+}
+
+// push delivers n right away when out has room and nothing is queued
+// before it, and queues it for run otherwise
+func (s *Session) push(n Notification) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return
+	}
+	if len(s.queue) == 0 {
+		select {
+		case s.out <- n:
+			return
+		default:
+		}
+	}
+	s.queue = append(s.queue, n)
+	select {
+	case s.wake <- struct{}{}:
+	default:
+	}
+}
+
+// run moves queued notifications to out as the reader makes room. The
+// head of the queue is removed only once sent, so push never overtakes it.
+func (s *Session) run() {
+	defer func() {
+		s.mu.Lock()
+		close(s.out)
+		s.mu.Unlock()
+	}()
+	for {
+		s.mu.Lock()
+		if len(s.queue) == 0 {
+			s.mu.Unlock()
+			select {
+			case <-s.wake:
+				continue
+			case <-s.done:
+				return
+			}
+		}
+		n := s.queue[0]
+		s.mu.Unlock()
+		select {
+		case s.out <- n:
+		case <-s.done:
+			return
+		}
+		s.mu.Lock()
+		s.queue = s.queue[1:]
+		s.mu.Unlock()
+	}
+}
+
+// exec runs a statement for s, nil for Engine.ExecSQL
+func (e *Engine) exec(sql string, s *Session) (any, error) {
+	plan, err := e.plan(sql)
+	if err != nil {
+		return nil, err
+	}
+	switch p := plan.(type) {
+	case *planner.PlanListen:
+		if s == nil {
+			return nil, errors.New("LISTEN requires a session, see Engine.NewSession")
+		}
+		s.listen(p.Stmt.Channel)
+		return nil, nil
+	case *planner.PlanUnlisten:
+		if s != nil {
+			s.unlisten(p.Stmt.Channel)
+		}
+		return nil, nil
+	}
+	sess := e.sess
+	if s != nil {
+		sess = s.sess
+	}
+	return e.ex.Execute(plan, sess)
+}
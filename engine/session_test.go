@@ -0,0 +1,158 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func listen(t *testing.T, e *Engine, channels ...string) *Session {
+	t.Helper()
+	s := e.NewSession()
+	t.Cleanup(func() { s.Close() })
+	for _, ch := range channels {
+		if _, err := s.ExecSQL("LISTEN " + ch); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+// expectNotes reads the next notifications of s, given as "channel:payload"
+func expectNotes(t *testing.T, s *Session, want ...string) {
+	t.Helper()
+	for _, w := range want {
+		select {
+		case n, ok := <-s.Notifications():
+			if !ok {
+				t.Fatalf("notifications closed, want %s", w)
+			}
+			if got := n.Channel + ":" + n.Payload; got != w {
+				t.Fatalf("notification %s, want %s", got, w)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("no notification, want %s", w)
+		}
+	}
+}
+
+func TestListenNotify(t *testing.T) {
+	e := openTest(t)
+	a := listen(t, e, "orders", "stock")
+	b := listen(t, e, "orders")
+
+	mustExec(t, e, "NOTIFY orders, 'one'")
+	mustExec(t, e, "NOTIFY stock")
+	mustExec(t, e, "NOTIFY other, 'nobody listens'")
+	mustExec(t, e, "NOTIFY orders, 'two' || 1")
+	expectNotes(t, a, "orders:one", "stock:", "orders:two1")
+	expectNotes(t, b, "orders:one", "orders:two1")
+
+	// a session hears its own notifications
+	if _, err := b.ExecSQL("NOTIFY orders, 'from b'"); err != nil {
+		t.Fatal(err)
+	}
+	expectNotes(t, a, "orders:from b")
+	expectNotes(t, b, "orders:from b")
+
+	if _, err := a.ExecSQL("UNLISTEN orders"); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, e, "NOTIFY orders, 'three'")
+	mustExec(t, e, "NOTIFY stock, 'four'")
+	expectNotes(t, a, "stock:four")
+	expectNotes(t, b, "orders:three")
+
+	if _, err := b.ExecSQL("UNLISTEN *"); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, e, "NOTIFY orders, 'five'")
+	if _, err := b.ExecSQL("LISTEN stock"); err != nil {
+		t.Fatal(err)
+	}
+	mustExec(t, e, "NOTIFY stock, 'six'")
+	expectNotes(t, b, "stock:six")
+}
+
+func TestListenNeedsSession(t *testing.T) {
+	e := openTest(t)
+	if _, err := e.ExecSQL("LISTEN orders"); err == nil {
+		t.Fatal("LISTEN without a session succeeded")
+	}
+	mustExec(t, e, "UNLISTEN orders")
+	s := listen(t, e)
+	if _, err := s.ExecSQL("NOTIFY orders, '" + strings.Repeat("x", 8000) + "'"); err == nil {
+		t.Fatal("sent a payload of 8000 bytes")
+	}
+	s.Close()
+	if _, err := s.ExecSQL("SELECT 1"); !errors.Is(err, ErrSessionClosed) {
+		t.Fatalf("statement of a closed session: %v", err)
+	}
+	if _, ok := <-s.Notifications(); ok {
+		t.Fatal("notification after Close")
+	}
+}
+
+func TestNotifyFromTrigger(t *testing.T) {
+	e := openTest(t)
+	mustExec(t, e, "CREATE TABLE orders (id INT PRIMARY KEY, kind TEXT)")
+	mustExec(t, e, "CREATE TRIGGER orders_new AFTER INSERT ON orders FOR EACH ROW BEGIN NOTIFY orders, NEW.kind; NOTIFY ids, 'order ' || NEW.id; END")
+	s := listen(t, e, "orders", "ids")
+
+	// the same channel and payload twice in a statement arrive once
+	mustExec(t, e, "INSERT INTO orders (id, kind) VALUES (1, 'a'), (2, 'b'), (3, 'a')")
+	expectNotes(t, s, "orders:a", "ids:order 1", "orders:b", "ids:order 2", "ids:order 3")
+
+	// a failing statement sends none
+	if _, err := e.ExecSQL("INSERT INTO orders (id, kind) VALUES (4, 'c'), (1, 'd')"); err == nil {
+		t.Fatal("inserted a duplicate key")
+	}
+	mustExec(t, e, "NOTIFY orders, 'after'")
+	expectNotes(t, s, "orders:after")
+}
+
+func TestNotifyWaitsForCommit(t *testing.T) {
+	dir := t.TempDir()
+	e, err := NewEngine(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	mustExec(t, e, "CREATE TABLE orders (id INT PRIMARY KEY)")
+	mustExec(t, e, "CREATE TRIGGER orders_new AFTER INSERT ON orders FOR EACH ROW BEGIN NOTIFY orders, 'order ' || NEW.id; END")
+	s := listen(t, e, "orders")
+
+	// the change log cannot be written, so the insert fails to commit
+	log := filepath.Join(dir, "changes.log")
+	os.Remove(log)
+	if err := os.Mkdir(log, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := e.ExecSQL("INSERT INTO orders (id) VALUES (1)"); err == nil {
+		t.Fatal("insert committed without its change log")
+	}
+	if err := os.Remove(log); err != nil {
+		t.Fatal(err)
+	}
+	if got := mustExec(t, e, "SELECT id FROM orders"); len(got.([]map[string]any)) != 0 {
+		t.Fatalf("rows after the failed commit: %v", got)
+	}
+
+	mustExec(t, e, "INSERT INTO orders (id) VALUES (2)")
+	expectNotes(t, s, "orders:order 2")
+}
+
+func TestSlowListener(t *testing.T) {
+	e := openTest(t)
+	s := listen(t, e, "c")
+	var want []string
+	for i := range 200 {
+		mustExec(t, e, fmt.Sprintf("NOTIFY c, '%d'", i))
+		want = append(want, fmt.Sprintf("c:%d", i))
+	}
+	expectNotes(t, s, want...)
+}
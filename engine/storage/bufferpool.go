@@ -0,0 +1,315 @@
+package storage
+
+import (
+	"errors"
+	"io"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Table files are read through a buffer pool that keeps recently used
+// pages of them in memory, so queries over a table that fits in the pool
+// do not touch the disk. Pages are evicted with the clock algorithm: a page
+// used since the hand last passed it gets a second chance. Pinned pages,
+// which a reader is copying from, and dirty pages, which an append changed
+// and has not written yet, are never evicted. When all pages are in use
+// the pool grows past its budget and shrinks back once they are released.
+
+// pageSize is the size of the blocks table files are cached in
+const pageSize = 4096
+
+// DefaultCacheSize is the memory budget of the buffer pool in bytes
+const DefaultCacheSize = 32 << 20
+
+// CacheStats reports the state of the buffer pool. Hits and Misses count
+// page reads served from memory and from disk.
+type CacheStats struct {
+	Capacity  int // pages the budget allows
+	Pages     int // pages cached
+	Dirty     int // pages changed and not written yet
+	Hits      uint64
+	Misses    uint64
+	Evictions uint64
+}
+
+type pageKey struct {
+	file string
+	page int64
+}
+
+// frame holds a cached page
+type frame struct {
+	key   pageKey
+	data  []byte // shorter than pageSize for the last page of a file
+	pins  int
+	dirty bool
+	used  bool // set on every use and cleared by the clock hand
+}
+
+type bufferPool struct {
+	mu       sync.Mutex
+	capacity int
+	frames   []*frame // the clock
+	hand     int
+	pages    map[pageKey]*frame
+
+	hits, misses, evictions uint64
+}
+
+func newBufferPool(size int64) *bufferPool {
+	if size <= 0 {
+		size = DefaultCacheSize
+	}
+	return &bufferPool{capacity: int(max(size/pageSize, 1)), pages: map[pageKey]*frame{}}
+}
+
+// pin returns the page of file at index page, pinned, reading it with
+// load on a miss. It returns nil past the end of the file unless create is
+// set, in which case a new empty page is cached.
+func (bp *bufferPool) pin(file string, page int64, load func(buf []byte, off int64) (int, error), create bool) (*frame, error) {
+	key := pageKey{file, page}
+	bp.mu.Lock()
+	if fr, ok := bp.pages[key]; ok {
+		fr.pins++
+		fr.used = true
+		bp.hits++
+		bp.mu.Unlock()
+		return fr, nil
+	}
+	bp.misses++
+	bp.mu.Unlock()
+
+	buf := make([]byte, pageSize)
+	n, err := load(buf, page*pageSize)
+	if err != nil && !errors.Is(err, io.EOF) {
+		return nil, err
+	}
+	if n == 0 && !create {
+		return nil, nil
+	}
+
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	if fr, ok := bp.pages[key]; ok {
+		// loaded by another reader in the meantime
+		fr.pins++
+		fr.used = true
+		return fr, nil
+	}
+	bp.evictLocked(1)
+	fr := &frame{key: key, data: buf[:n], pins: 1, used: true}
+	bp.pages[key] = fr
+	bp.frames = append(bp.frames, fr)
+	return fr, nil
+}
+
+func (bp *bufferPool) unpin(fr *frame) {
+	bp.mu.Lock()
+	fr.pins--
+	if fr.pins == 0 && len(bp.frames) > bp.capacity {
+		bp.evictLocked(0)
+	}
+	bp.mu.Unlock()
+}
+
+// evictLocked evicts pages until room more fit in the budget or none of
+// the cached ones can go, which shrinks a pool that grew past its budget
+// once its pages are released
+func (bp *bufferPool) evictLocked(room int) {
+	for len(bp.frames)+room > bp.capacity && bp.evictOneLocked() {
+	}
+}
+
+// evictOneLocked evicts the page the clock hand finds first and reports
+// whether there was one
+func (bp *bufferPool) evictOneLocked() bool {
+	// two rounds clear every used flag on the way
+	for range 2 * len(bp.frames) {
+		if bp.hand >= len(bp.frames) {
+			bp.hand = 0
+		}
+		fr := bp.frames[bp.hand]
+		switch {
+		case fr.pins > 0 || fr.dirty:
+			bp.hand++
+		case fr.used:
+			fr.used = false
+			bp.hand++
+		default:
+			bp.removeLocked(bp.hand)
+			bp.evictions++
+			return true
+		}
+	}
+	return false
+}
+
+// removeLocked takes the frame at position i out of the clock
+func (bp *bufferPool) removeLocked(i int) {
+	delete(bp.pages, bp.frames[i].key)
+	last := len(bp.frames) - 1
+	bp.frames[i] = bp.frames[last]
+	bp.frames[last] = nil
+	bp.frames = bp.frames[:last]
+}
+
+// write changes the cached pages of file from off on to hold b, loading
+// pages not cached yet with load, and marks them dirty for flush. Readers
+// of file must be excluded by the store's write lock.
+func (bp *bufferPool) write(file string, off int64, b []byte, load func(buf []byte, off int64) (int, error)) error {
+	for len(b) > 0 {
+		page, pos := off/pageSize, int(off%pageSize)
+		fr, err := bp.pin(file, page, load, true)
+		if err != nil {
+			return err
+		}
+		if len(fr.data) < pos {
+			bp.unpin(fr)
+			return errors.New("buffer pool: write past the end of a page")
+		}
+		n := min(len(b), pageSize-pos)
+		fr.data = append(fr.data[:pos], b[:n]...)
+		bp.mu.Lock()
+		fr.dirty = true
+		fr.pins--
+		bp.mu.Unlock()
+		b, off = b[n:], off+int64(n)
+	}
+	return nil
+}
+
+// flush writes the dirty pages of file to f in order and marks them clean
+func (bp *bufferPool) flush(file string, f *os.File) error {
+	bp.mu.Lock()
+	var dirty []*frame
+	for _, fr := range bp.frames {
+		if fr.key.file == file && fr.dirty {
+			dirty = append(dirty, fr)
+		}
+	}
+	bp.mu.Unlock()
+	sort.Slice(dirty, func(i, j int) bool { return dirty[i].key.page < dirty[j].key.page })
+	for _, fr := range dirty {
+		if _, err := f.WriteAt(fr.data, fr.key.page*pageSize); err != nil {
+			return err
+		}
+	}
+	bp.mu.Lock()
+	for _, fr := range dirty {
+		fr.dirty = false
+	}
+	bp.evictLocked(0)
+	bp.mu.Unlock()
+	return nil
+}
+
+// drop forgets the pages of a file that was replaced or removed, or whose
+// write failed
+func (bp *bufferPool) drop(file string) {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	for i := 0; i < len(bp.frames); {
+		if bp.frames[i].key.file == file {
+			bp.removeLocked(i)
+			continue
+		}
+		i++
+	}
+	bp.hand = 0
+}
+
+func (bp *bufferPool) stats() CacheStats {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	st := CacheStats{
+		Capacity:  bp.capacity,
+		Pages:     len(bp.frames),
+		Hits:      bp.hits,
+		Misses:    bp.misses,
+		Evictions: bp.evictions,
+	}
+	for _, fr := range bp.frames {
+		if fr.dirty {
+			st.Dirty++
+		}
+	}
+	return st
+}
+
+// CacheStats returns the counters of the buffer pool
+func (s *Store) CacheStats() CacheStats {
+	return s.pool.stats()
+}
+
+// pageReader reads a table file through the buffer pool. The file is only
+// opened when a page is missing from the pool.
+type pageReader struct {
+	pool *bufferPool
+	path string
+	f    *os.File
+	off  int64
+}
+
+func (s *Store) readPages(table string) *pageReader {
+	return &pageReader{pool: s.pool, path: s.tablePath(table)}
+}
+
+func (r *pageReader) load(buf []byte, off int64) (int, error) {
+	if r.f == nil {
+		f, err := os.Open(r.path)
+		if err != nil {
+			return 0, err
+		}
+		r.f = f
+	}
+	return r.f.ReadAt(buf, off)
+}
+
+// ReadAt reads len(b) bytes at off, failing with io.EOF when the file is
+// shorter
+func (r *pageReader) ReadAt(b []byte, off int64) (int, error) {
+	read := 0
+	for read < len(b) {
+		fr, err := r.pool.pin(r.path, off/pageSize, r.load, false)
+		if err != nil {
+			return read, err
+		}
+		if fr == nil {
+			return read, io.EOF
+		}
+		pos := int(off % pageSize)
+		n := 0
+		if pos < len(fr.data) {
+			n = copy(b[read:], fr.data[pos:])
+		}
+		full := len(fr.data) == pageSize
+		r.pool.unpin(fr)
+		read, off = read+n, off+int64(n)
+		if read < len(b) && (n == 0 || !full) {
+			return read, io.EOF
+		}
+	}
+	return read, nil
+}
+
+func (r *pageReader) Read(b []byte) (int, error) {
+	if len(b) == 0 {
+		return 0, nil
+	}
+	// stay within one page so short reads do not wait for the next
+	n := min(len(b), pageSize-int(r.off%pageSize))
+	n, err := r.ReadAt(b[:n], r.off)
+	r.off += int64(n)
+	if n > 0 && errors.Is(err, io.EOF) {
+		err = nil
+	}
+	return n, err
+}
+
+func (r *pageReader) Close() error {
+	if r.f == nil {
+		return nil
+	}
+	return r.f.Close()
+}
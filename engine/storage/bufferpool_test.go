@@ -0,0 +1,296 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+)
+
+// fakeFile serves pages of size bytes filled with the page number and
+// counts the loads
+func fakeFile(size int64, loads *atomic.Int64) func(buf []byte, off int64) (int, error) {
+	return func(buf []byte, off int64) (int, error) {
+		loads.Add(1)
+		if off >= size {
+			return 0, nil
+		}
+		n := int(min(int64(len(buf)), size-off))
+		for i := range n {
+			buf[i] = byte(off / pageSize)
+		}
+		return n, nil
+	}
+}
+
+// touch pins and releases a page
+func touch(t *testing.T, bp *bufferPool, file string, page int64, load func([]byte, int64) (int, error)) {
+	t.Helper()
+	fr, err := bp.pin(file, page, load, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fr == nil {
+		t.Fatalf("page %d of %s missing", page, file)
+	}
+	if fr.data[0] != byte(page) {
+		t.Fatalf("page %d holds the data of page %d", page, fr.data[0])
+	}
+	bp.unpin(fr)
+}
+
+func cached(bp *bufferPool, file string, page int64) bool {
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	_, ok := bp.pages[pageKey{file, page}]
+	return ok
+}
+
+func expectStats(t *testing.T, got, want CacheStats) {
+	t.Helper()
+	if got != want {
+		t.Fatalf("stats = %+v, want %+v", got, want)
+	}
+}
+
+func TestBufferPoolClock(t *testing.T) {
+	var loads atomic.Int64
+	load := fakeFile(10*pageSize, &loads)
+	bp := newBufferPool(3 * pageSize)
+	for p := range int64(3) {
+		touch(t, bp, "f", p, load)
+	}
+	touch(t, bp, "f", 0, load)
+	expectStats(t, bp.stats(), CacheStats{Capacity: 3, Pages: 3, Hits: 1, Misses: 3})
+
+	// every page was used, so the hand clears them all and takes the first
+	touch(t, bp, "f", 3, load)
+	expectStats(t, bp.stats(), CacheStats{Capacity: 3, Pages: 3, Hits: 1, Misses: 4, Evictions: 1})
+	if cached(bp, "f", 0) {
+		t.Fatal("page 0 was not evicted")
+	}
+
+	// a page used since the hand cleared it gets a second chance
+	touch(t, bp, "f", 2, load)
+	touch(t, bp, "f", 4, load)
+	if !cached(bp, "f", 2) || cached(bp, "f", 1) {
+		t.Fatal("clock evicted the page used since the hand passed it")
+	}
+	if loads.Load() != 5 {
+		t.Fatalf("%d loads for 5 misses", loads.Load())
+	}
+
+	// past the end of the file there is no page
+	fr, err := bp.pin("f", 10, load, false)
+	if err != nil || fr != nil {
+		t.Fatalf("page past the end: %v, %v", fr, err)
+	}
+}
+
+func TestBufferPoolKeepsPinnedAndDirtyPages(t *testing.T) {
+	var loads atomic.Int64
+	load := fakeFile(10*pageSize, &loads)
+	bp := newBufferPool(2 * pageSize)
+
+	pinned, err := bp.pin("f", 0, load, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bp.write("g", 0, []byte("new row\n"), fakeFile(0, &loads)); err != nil {
+		t.Fatal(err)
+	}
+	// neither page can go, so the pool grows past its budget
+	second, err := bp.pin("f", 1, load, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expectStats(t, bp.stats(), CacheStats{Capacity: 2, Pages: 3, Dirty: 1, Misses: 3})
+	if !cached(bp, "f", 0) || !cached(bp, "g", 0) {
+		t.Fatal("evicted a pinned or dirty page")
+	}
+
+	// and shrinks back as they are released
+	bp.unpin(second)
+	bp.unpin(pinned)
+	expectStats(t, bp.stats(), CacheStats{Capacity: 2, Pages: 2, Dirty: 1, Misses: 3, Evictions: 1})
+	f, err := os.Create(filepath.Join(t.TempDir(), "g"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if err := bp.flush("g", f); err != nil {
+		t.Fatal(err)
+	}
+	if b, err := os.ReadFile(f.Name()); err != nil || string(b) != "new row\n" {
+		t.Fatalf("flushed %q, %v", b, err)
+	}
+	if st := bp.stats(); st.Dirty != 0 {
+		t.Fatalf("dirty pages after flush: %+v", st)
+	}
+	touch(t, bp, "f", 2, load)
+	if st := bp.stats(); st.Evictions != 2 || st.Pages != 2 {
+		t.Fatalf("stats = %+v, want the flushed page evictable", st)
+	}
+	if !cached(bp, "f", 2) {
+		t.Fatal("page 2 not cached")
+	}
+
+	bp.drop("f")
+	if cached(bp, "f", 2) || bp.stats().Pages != 1 {
+		t.Fatal("drop kept pages of the file or removed others")
+	}
+}
+
+func TestBufferPoolConcurrentPins(t *testing.T) {
+	var loads atomic.Int64
+	load := fakeFile(8*pageSize, &loads)
+	bp := newBufferPool(4 * pageSize)
+	var wg sync.WaitGroup
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 500 {
+				p := int64((g + i) % 8)
+				fr, err := bp.pin("f", p, load, false)
+				if err != nil || fr == nil || fr.data[0] != byte(p) {
+					t.Errorf("page %d: %v, %v", p, fr, err)
+					return
+				}
+				bp.unpin(fr)
+			}
+		}()
+	}
+	wg.Wait()
+	st := bp.stats()
+	if st.Hits+st.Misses != 8*500 || st.Misses != uint64(loads.Load()) {
+		t.Fatalf("stats = %+v after %d loads", st, loads.Load())
+	}
+	if st.Pages > st.Capacity {
+		t.Fatalf("%d pages cached with nothing pinned, budget %d", st.Pages, st.Capacity)
+	}
+	bp.mu.Lock()
+	defer bp.mu.Unlock()
+	for _, fr := range bp.frames {
+		if fr.pins != 0 {
+			t.Fatalf("page %d left with %d pins", fr.key.page, fr.pins)
+		}
+	}
+}
+
+func cacheStore(t *testing.T, size int64) *Store {
+	t.Helper()
+	s, err := OpenStore(t.TempDir(), Options{CacheSize: size})
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.CreateTable("t", []ColumnDefinition{{Name: "id", Type: "INTEGER"}, {Name: "s", Type: "TEXT"}}); err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func fillRows(t *testing.T, s *Store, from, n int) {
+	t.Helper()
+	rows := make([]map[string]any, n)
+	for i := range rows {
+		rows[i] = map[string]any{"id": int64(from + i), "s": strings.Repeat("x", 100)}
+	}
+	mustAppend(t, s, "t", rows...)
+}
+
+func TestStoreCacheStats(t *testing.T) {
+	s := cacheStore(t, 0)
+	fillRows(t, s, 0, 100)
+	if st := s.CacheStats(); st.Capacity != DefaultCacheSize/pageSize || st.Dirty != 0 {
+		t.Fatalf("stats after writing = %+v", st)
+	}
+	if _, err := s.ScanTable("t"); err != nil {
+		t.Fatal(err)
+	}
+	before := s.CacheStats()
+	rows, err := s.ScanTable("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 100 {
+		t.Fatalf("%d rows", len(rows))
+	}
+	after := s.CacheStats()
+	if after.Misses != before.Misses || after.Hits <= before.Hits {
+		t.Fatalf("second scan: stats %+v, before %+v", after, before)
+	}
+}
+
+func TestStoreSmallCache(t *testing.T) {
+	s := cacheStore(t, 2*pageSize)
+	fillRows(t, s, 0, 500) // about 15 pages
+	for range 2 {
+		rows, err := s.ScanTable("t")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(rows) != 500 || rows[499]["id"] != int64(499) {
+			t.Fatalf("%d rows", len(rows))
+		}
+	}
+	st := s.CacheStats()
+	if st.Evictions == 0 || st.Pages > st.Capacity || st.Misses == 0 {
+		t.Fatalf("stats = %+v", st)
+	}
+
+	// a rewritten file is read again, not served from stale pages
+	if _, err := s.DeleteRows("t", func(r map[string]any) (bool, error) { return r["id"].(int64)%2 == 0, nil }); err != nil {
+		t.Fatal(err)
+	}
+	rows, err := s.ScanTable("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 250 || rows[0]["id"] != int64(1) {
+		t.Fatalf("%d rows after delete, first %v", len(rows), rows[0])
+	}
+}
+
+func TestStoreCacheConcurrentReaders(t *testing.T) {
+	s := cacheStore(t, 3*pageSize)
+	fillRows(t, s, 0, 200)
+	var wg sync.WaitGroup
+	for range 4 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for range 20 {
+				rows, err := s.ScanTable("t")
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				for i, r := range rows {
+					if r["id"] != int64(i) {
+						t.Errorf("row %d = %v", i, r)
+						return
+					}
+				}
+				if len(rows) < 200 || len(rows)%50 != 0 {
+					t.Errorf("scan saw %d rows", len(rows))
+					return
+				}
+			}
+		}()
+	}
+	for i := range 10 {
+		fillRows(t, s, 200+50*i, 50)
+	}
+	wg.Wait()
+	rows, err := s.ScanTable("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 700 {
+		t.Fatalf("%d rows", len(rows))
+	}
+}
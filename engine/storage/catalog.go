@@ -0,0 +1,231 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+const catalogFile = "catalog.json"
+
+// Catalog is the persisted registry of schema objects. It mirrors the header
+// of every .tbl file so schema can be listed without opening each table.
+type Catalog struct {
+	Tables    map[string]*TableMeta    `json:"tables"`
+	Views     map[string]*ViewMeta     `json:"views,omitempty"`
+	Sequences map[string]*SequenceMeta `json:"sequences,omitempty"`
+	Triggers  map[string]*TriggerMeta  `json:"triggers,omitempty"`
+}
+
+// TableMeta describes a single user table
+type TableMeta struct {
+	Name        string             `json:"name"`
+	Columns     []ColumnDefinition `json:"columns"`
+	Indexes     []IndexMeta        `json:"indexes,omitempty"`
+	ForeignKeys []ForeignKey       `json:"foreign_keys,omitempty"`
+	Checks      []CheckConstraint  `json:"checks,omitempty"`
+}
+
+// IndexMeta describes an index defined on a table
+type IndexMeta struct {
+	Name    string   `json:"name"`
+	Table   string   `json:"table"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique,omitempty"`
+}
+
+func (s *Store) catalogPath() string {
+	return filepath.Join(s.baseDir, catalogFile)
+}
+
+// loadCatalog reads catalog.json and reconciles it with the .tbl files on disk,
+// so data directories created before the catalog existed are picked up too.
+func (s *Store) loadCatalog() error {
+	cat := &Catalog{
+		Tables:    map[string]*TableMeta{},
+		Views:     map[string]*ViewMeta{},
+		Sequences: map[string]*SequenceMeta{},
+		Triggers:  map[string]*TriggerMeta{},
+	}
+
+	b, err := os.ReadFile(s.catalogPath())
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(b, cat); err != nil {
+			return fmt.Errorf("catalog: %w", err)
+		}
+		if cat.Tables == nil {
+			cat.Tables = map[string]*TableMeta{}
+		}
+		if cat.Views == nil {
+			cat.Views = map[string]*ViewMeta{}
+		}
+		if cat.Sequences == nil {
+			cat.Sequences = map[string]*SequenceMeta{}
+		}
+		if cat.Triggers == nil {
+			cat.Triggers = map[string]*TriggerMeta{}
+		}
+	case errors.Is(err, os.ErrNotExist):
+	default:
+		return err
+	}
+
+	files, err := filepath.Glob(filepath.Join(s.baseDir, "*.tbl"))
+	if err != nil {
+		return err
+	}
+	onDisk := map[string]bool{}
+	dirty := false
+	for _, f := range files {
+		name := strings.TrimSuffix(filepath.Base(f), ".tbl")
+		onDisk[name] = true
+		if _, ok := cat.Tables[name]; ok {
+			continue
+		}
+		meta, err := s.readHeader(name)
+		if err != nil {
+			return err
+		}
+		cat.Tables[name] = meta
+		dirty = true
+	}
+	for name := range cat.Tables {
+		if !onDisk[name] {
+			delete(cat.Tables, name)
+			dirty = true
+		}
+	}
+	for name, t := range cat.Triggers {
+		if !onDisk[t.Table] {
+			delete(cat.Triggers, name)
+			dirty = true
+		}
+	}
+	for name, v := range cat.Views {
+		// the result of a materialized view lives in its table
+		if v.Materialized && !onDisk[name] {
+			delete(cat.Views, name)
+			dirty = true
+		}
+	}
+
+	s.catalog = cat
+	recovered, err := s.recoverSequences()
+	if err != nil {
+		return err
+	}
+	// a read-only store reconciles the catalog in memory only
+	if (dirty || recovered) && !s.readOnly {
+		return s.saveCatalog()
+	}
+	return nil
+}
+
+// saveCatalog atomically replaces catalog.json with the in-memory catalog
+func (s *Store) saveCatalog() error {
+	// a sequence is saved at the end of its reserved block, see NextVal
+	for seq, bound := range s.seqReserved {
+		last := seq.Last
+		seq.Last = bound
+		defer func() { seq.Last = last }()
+	}
+	b, err := json.MarshalIndent(s.catalog, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmpPath := s.catalogPath() + ".tmp"
+	if err := os.WriteFile(tmpPath, b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(tmpPath, s.catalogPath())
+}
+
+// readHeader decodes the schema stored in a table file header
+func (s *Store) readHeader(table string) (*TableMeta, error) {
+	f, err := os.Open(s.tablePath(table))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var header struct {
+		Columns     []ColumnDefinition `json:"columns"`
+		Indexes     []IndexMeta        `json:"indexes"`
+		ForeignKeys []ForeignKey       `json:"foreign_keys"`
+		Checks      []CheckConstraint  `json:"checks"`
+	}
+	if err := json.NewDecoder(f).Decode(&header); err != nil {
+		return nil, fmt.Errorf("table %s: bad header: %w", table, err)
+	}
+	return &TableMeta{Name: table, Columns: header.Columns, Indexes: header.Indexes, ForeignKeys: header.ForeignKeys, Checks: header.Checks}, nil
+}
+
+// Column returns the definition of the named column
+func (t *TableMeta) Column(name string) (ColumnDefinition, bool) {
+	for _, c := range t.Columns {
+		if c.Name == name {
+			return c, true
+		}
+	}
+	return ColumnDefinition{}, false
+}
+
+// validateIndexes makes sure every index refers to existing columns
+// and that index names are unique within the table
+func (t *TableMeta) validateIndexes() error {
+	seen := map[string]bool{}
+	for _, idx := range t.Indexes {
+		if seen[idx.Name] {
+			return fmt.Errorf("duplicate index name %s on table %s", idx.Name, t.Name)
+		}
+		seen[idx.Name] = true
+		for _, c := range idx.Columns {
+			col, ok := t.Column(c)
+			if !ok {
+				return fmt.Errorf("index %s: unknown column %s in table %s", idx.Name, c, t.Name)
+			}
+			if col.Virtual() {
+				return fmt.Errorf("index %s: cannot index virtual generated column %s", idx.Name, c)
+			}
+		}
+	}
+	return nil
+}
+
+// Tables returns metadata of all user tables sorted by name
+func (s *Store) Tables() []TableMeta {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tablesUnlocked()
+}
+
+func (s *Store) tablesUnlocked() []TableMeta {
+	out := make([]TableMeta, 0, len(s.catalog.Tables))
+	for _, t := range s.catalog.Tables {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// Table returns metadata of a single user or system table
+func (s *Store) Table(name string) (TableMeta, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.tableUnlocked(name)
+}
+
+func (s *Store) tableUnlocked(name string) (TableMeta, bool) {
+	if t, ok := s.catalog.Tables[name]; ok {
+		return *t, true
+	}
+	if t, ok := systemTables[strings.ToLower(name)]; ok {
+		return t, true
+	}
+	return TableMeta{}, false
+}
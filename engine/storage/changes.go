@@ -0,0 +1,457 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+const changesFile = "changes.log"
+
+// Operations of a ChangeEvent
+const (
+	OpInsert = "INSERT"
+	OpUpdate = "UPDATE"
+	OpDelete = "DELETE"
+)
+
+// ErrChangesTrimmed is returned when subscribing from a position whose
+// events were discarded by TrimChanges
+var ErrChangesTrimmed = errors.New("change log position is no longer available")
+
+// ChangeEvent is a row change recorded in the change log. LSN numbers the
+// events of the store in the order they were written. Before is nil for
+// inserts and After for deletes.
+type ChangeEvent struct {
+	LSN    uint64         `json:"lsn"`
+	Table  string         `json:"table"`
+	Op     string         `json:"op"`
+	Before map[string]any `json:"before,omitempty"`
+	After  map[string]any `json:"after,omitempty"`
+}
+
+// changeLog appends the row changes of every write to changes.log, a JSON
+// lines file whose header records the LSN it starts after, and wakes the
+// subscriptions reading it
+type changeLog struct {
+	mu   sync.Mutex
+	path string
+	base uint64 // events up to base were trimmed
+	last uint64 // LSN of the last event written
+	gen  int    // bumped when the file is rewritten by trim
+	subs map[*Subscription]bool
+}
+
+type changesHeader struct {
+	Base uint64 `json:"base"`
+}
+
+// openChanges reads the header and the last LSN of an existing change log.
+// The last line is the remains of an append cut short by a crash when it
+// lacks its newline or does not decode; a writable store cuts it off
+// before appending after it, a read-only one ignores it like scan does. A
+// damaged line with events after it is an error.
+func (s *Store) openChanges() error {
+	c := &changeLog{path: filepath.Join(s.baseDir, changesFile), subs: map[*Subscription]bool{}}
+	s.changes = c
+	f, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	var good int64 // offset after the last intact line
+	for n := 1; ; n++ {
+		line, err := r.ReadBytes('\n')
+		if err != nil && !errors.Is(err, io.EOF) {
+			return err
+		}
+		if len(line) == 0 {
+			return nil
+		}
+		var lsn uint64
+		intact := line[len(line)-1] == '\n'
+		if intact && n == 1 {
+			var h changesHeader
+			intact = json.Unmarshal(line, &h) == nil
+			c.base, lsn = h.Base, h.Base
+		} else if intact {
+			var ev struct {
+				LSN uint64 `json:"lsn"`
+			}
+			intact = json.Unmarshal(line, &ev) == nil
+			lsn = ev.LSN
+		}
+		if !intact {
+			if _, perr := r.Peek(1); perr == nil {
+				return fmt.Errorf("%s: line %d is damaged and events follow it", changesFile, n)
+			}
+			if s.readOnly {
+				return nil
+			}
+			if n == 1 {
+				// cut short while the file was created: start it again
+				return os.Remove(c.path)
+			}
+			return os.Truncate(c.path, good)
+		}
+		good += int64(len(line))
+		c.last = lsn
+	}
+}
+
+// logChanges records the events of a write. It is called with the store
+// locked for writing, after the table files were changed. Inside Update
+// they are written when it succeeds.
+func (s *Store) logChanges(events []ChangeEvent) error {
+	if s.undo != nil {
+		s.undo.events = append(s.undo.events, events...)
+		return nil
+	}
+	return s.writeChanges(events)
+}
+
+// writeChanges numbers events and appends them to the change log with a
+// single write
+func (s *Store) writeChanges(events []ChangeEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	c := s.changes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var buf []byte
+	if _, err := os.Stat(c.path); errors.Is(err, os.ErrNotExist) {
+		b, err := json.Marshal(changesHeader{Base: c.base})
+		if err != nil {
+			return err
+		}
+		buf = append(b, '\n')
+	}
+	last := c.last
+	for i := range events {
+		last++
+		events[i].LSN = last
+		b, err := json.Marshal(events[i])
+		if err != nil {
+			return err
+		}
+		buf = append(buf, b...)
+		buf = append(buf, '\n')
+	}
+	f, err := os.OpenFile(c.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(buf); err != nil {
+		return err
+	}
+	c.last = last
+	for sub := range c.subs {
+		sub.wakeUp()
+	}
+	return nil
+}
+
+// insertChanges returns the events of rows appended to table
+func insertChanges(table string, rows []map[string]any) []ChangeEvent {
+	events := make([]ChangeEvent, len(rows))
+	for i, row := range rows {
+		events[i] = ChangeEvent{Table: table, Op: OpInsert, After: row}
+	}
+	return events
+}
+
+// LastLSN returns the LSN of the last change written, 0 if there is none
+func (s *Store) LastLSN() uint64 {
+	c := s.changes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.last
+}
+
+// TrimChanges discards the events up to and including upTo, which no
+// subscriber needs any more. Later events keep their LSNs.
+func (s *Store) TrimChanges(upTo uint64) error {
+	if s.readOnly {
+		return ErrReadOnlyStore
+	}
+	c := s.changes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if upTo <= c.base {
+		return nil
+	}
+	upTo = min(upTo, c.last)
+
+	var keep [][]byte
+	if _, err := c.scan(0, func(lsn uint64, line []byte) bool {
+		if lsn > upTo {
+			keep = append(keep, line)
+		}
+		return true
+	}); err != nil {
+		return err
+	}
+	b, err := json.Marshal(changesHeader{Base: upTo})
+	if err != nil {
+		return err
+	}
+	buf := append(b, '\n')
+	for _, line := range keep {
+		buf = append(buf, line...)
+	}
+	tmpPath := c.path + ".tmp"
+	if err := os.WriteFile(tmpPath, buf, 0o644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, c.path); err != nil {
+		return err
+	}
+	c.base = upTo
+	c.gen++
+	return nil
+}
+
+// scan calls fn for the events of the log file from byte offset off, 0 for
+// the first one, until fn returns false or the file ends. It returns the
+// offset after the last event passed to fn. c.mu must be held.
+func (c *changeLog) scan(off int64, fn func(lsn uint64, line []byte) bool) (int64, error) {
+	f, err := os.Open(c.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return off, nil
+	}
+	if err != nil {
+		return off, err
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	if off == 0 {
+		header, err := r.ReadBytes('\n')
+		if err != nil {
+			return off, nil // no complete header yet
+		}
+		off = int64(len(header))
+	} else if _, err := f.Seek(off, io.SeekStart); err != nil {
+		return off, err
+	}
+	for {
+		line, err := r.ReadBytes('\n')
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				return off, nil // a line without newline was cut short
+			}
+			return off, err
+		}
+		var ev struct {
+			LSN uint64 `json:"lsn"`
+		}
+		if err := json.Unmarshal(line, &ev); err != nil {
+			if _, perr := r.Peek(1); perr == io.EOF {
+				return off, nil // cut short, see openChanges
+			}
+			return off, fmt.Errorf("%s: %w", changesFile, err)
+		}
+		off += int64(len(line))
+		if !fn(ev.LSN, line) {
+			return off, nil
+		}
+	}
+}
+
+// Subscription delivers the change events of a table, or of all tables,
+// in LSN order on Events. A subscription that falls behind reads the log
+// file at its own pace and never blocks writers.
+type Subscription struct {
+	s      *Store
+	table  string
+	events chan ChangeEvent
+	wake   chan struct{}
+	done   chan struct{}
+	once   sync.Once
+	err    error // set before events is closed
+
+	pos uint64 // LSN of the last event read
+	off int64  // offset of the next event in the log file
+	gen int    // generation of the log file off belongs to
+}
+
+// subscriptionBatch is how many events a subscription reads at a time
+const subscriptionBatch = 256
+
+// Subscribe starts delivering the changes made to table, or to every table
+// when table is "", after fromLSN: events already in the log come first,
+// then new ones as they are written. A consumer that stores the LSN of the
+// last event it handled can resume from it after a restart.
+func (s *Store) Subscribe(table string, fromLSN uint64) (*Subscription, error) {
+	if table != "" {
+		if _, ok := s.Table(table); !ok || IsSystemTable(table) {
+			return nil, fmt.Errorf("%w: %s", ErrTableNotFound, table)
+		}
+	}
+	c := s.changes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if fromLSN < c.base {
+		return nil, fmt.Errorf("%w: %d (trimmed up to %d)", ErrChangesTrimmed, fromLSN, c.base)
+	}
+	sub := &Subscription{
+		s:      s,
+		table:  table,
+		events: make(chan ChangeEvent, 64),
+		wake:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		pos:    fromLSN,
+		gen:    c.gen,
+	}
+	c.subs[sub] = true
+	go sub.run()
+	return sub, nil
+}
+
+// Events returns the channel events are delivered on. It is closed when
+// the subscription ends; Err then tells why.
+func (sub *Subscription) Events() <-chan ChangeEvent {
+	return sub.events
+}
+
+// Err returns the error that ended the subscription once Events is
+// closed, nil if it was closed by Close
+func (sub *Subscription) Err() error {
+	return sub.err
+}
+
+// Close stops the subscription and closes its channel
+func (sub *Subscription) Close() error {
+	sub.once.Do(func() {
+		c := sub.s.changes
+		c.mu.Lock()
+		delete(c.subs, sub)
+		c.mu.Unlock()
+		close(sub.done)
+	})
+	return nil
+}
+
+func (sub *Subscription) wakeUp() {
+	select {
+	case sub.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (sub *Subscription) run() {
+	defer close(sub.events)
+	for {
+		events, err := sub.read()
+		if err != nil {
+			sub.err = err
+			sub.Close()
+			return
+		}
+		for _, ev := range events {
+			select {
+			case sub.events <- ev:
+			case <-sub.done:
+				return
+			}
+		}
+		if len(events) > 0 {
+			continue
+		}
+		select {
+		case <-sub.wake:
+		case <-sub.done:
+			return
+		}
+	}
+}
+
+// read returns the next events of the subscribed table; none when it has
+// caught up with the log
+func (sub *Subscription) read() ([]ChangeEvent, error) {
+	for {
+		lines, caughtUp, err := sub.readLines()
+		if err != nil {
+			return nil, err
+		}
+		var events []ChangeEvent
+		for _, line := range lines {
+			ev, err := sub.s.decodeChange(line)
+			if err != nil {
+				return nil, err
+			}
+			if sub.table == "" || ev.Table == sub.table {
+				events = append(events, ev)
+			}
+		}
+		if len(events) > 0 || caughtUp {
+			return events, nil
+		}
+	}
+}
+
+// readLines reads a batch of raw events after sub.pos and reports whether
+// the end of the log was reached
+func (sub *Subscription) readLines() ([][]byte, bool, error) {
+	c := sub.s.changes
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if sub.gen != c.gen {
+		// trimmed: find the position again in the rewritten file
+		sub.off, sub.gen = 0, c.gen
+	}
+	if sub.pos < c.base {
+		return nil, false, fmt.Errorf("%w: %d (trimmed up to %d)", ErrChangesTrimmed, sub.pos, c.base)
+	}
+	var lines [][]byte
+	off, err := c.scan(sub.off, func(lsn uint64, line []byte) bool {
+		if lsn > sub.pos {
+			lines = append(lines, line)
+			sub.pos = lsn
+		}
+		return len(lines) < subscriptionBatch
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	sub.off = off
+	return lines, sub.pos >= c.last, nil
+}
+
+// decodeChange decodes an event of the log file like a table row: numbers
+// become int64 or float64 and temporal columns of a table that still
+// exists time.Time values
+func (s *Store) decodeChange(line []byte) (ChangeEvent, error) {
+	var ev ChangeEvent
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	if err := dec.Decode(&ev); err != nil {
+		return ev, fmt.Errorf("%s: %w", changesFile, err)
+	}
+	meta, ok := s.Table(ev.Table)
+	for _, row := range []map[string]any{ev.Before, ev.After} {
+		for k, v := range row {
+			if n, ok := v.(json.Number); ok {
+				row[k] = normalizeNumber(n)
+			}
+		}
+		if ok && row != nil {
+			decodeTemporal(&meta, row)
+		}
+	}
+	return ev, nil
+}
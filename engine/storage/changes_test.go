@@ -0,0 +1,262 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func nextEvent(t *testing.T, sub *Subscription) ChangeEvent {
+	t.Helper()
+	select {
+	case ev, ok := <-sub.Events():
+		if !ok {
+			t.Fatalf("subscription ended: %v", sub.Err())
+		}
+		return ev
+	case <-time.After(5 * time.Second):
+		t.Fatal("no event")
+	}
+	return ChangeEvent{}
+}
+
+// expectEvents reads the next events of sub and compares their LSNs
+func expectEvents(t *testing.T, sub *Subscription, lsns ...uint64) []ChangeEvent {
+	t.Helper()
+	var evs []ChangeEvent
+	for _, want := range lsns {
+		ev := nextEvent(t, sub)
+		if ev.LSN != want {
+			t.Fatalf("event %+v, want LSN %d", ev, want)
+		}
+		evs = append(evs, ev)
+	}
+	return evs
+}
+
+func cdcStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	s := openStore(t, dir)
+	for _, table := range []string{"a", "b"} {
+		if err := s.CreateTable(table, []ColumnDefinition{{Name: "id", Type: "INTEGER"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return s
+}
+
+func TestSubscribe(t *testing.T) {
+	s := cdcStore(t, t.TempDir())
+	all, err := s.Subscribe("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer all.Close()
+	onlyB, err := s.Subscribe("b", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer onlyB.Close()
+
+	mustAppend(t, s, "a", map[string]any{"id": int64(1)})
+	mustAppend(t, s, "b", map[string]any{"id": int64(2)})
+	if _, err := s.UpdateRows("b", nil, func(r map[string]any) (map[string]any, error) {
+		r["id"] = int64(3)
+		return r, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.DeleteRows("a", nil); err != nil {
+		t.Fatal(err)
+	}
+
+	evs := expectEvents(t, all, 1, 2, 3, 4)
+	if ev := evs[2]; ev.Op != OpUpdate || ev.Before["id"] != int64(2) || ev.After["id"] != int64(3) {
+		t.Fatalf("update event = %+v", ev)
+	}
+	if ev := evs[3]; ev.Op != OpDelete || ev.Table != "a" || ev.Before["id"] != int64(1) || ev.After != nil {
+		t.Fatalf("delete event = %+v", ev)
+	}
+	for _, ev := range expectEvents(t, onlyB, 2, 3) {
+		if ev.Table != "b" {
+			t.Fatalf("event of table %s", ev.Table)
+		}
+	}
+
+	if _, err := s.Subscribe("missing", 0); !errors.Is(err, ErrTableNotFound) {
+		t.Fatalf("subscribe to a missing table: %v", err)
+	}
+	all.Close()
+	if _, ok := <-all.Events(); ok {
+		t.Fatal("events after Close")
+	}
+}
+
+func TestSubscribeResumesAfterRestart(t *testing.T) {
+	dir := t.TempDir()
+	s := cdcStore(t, dir)
+	for i := range 3 {
+		mustAppend(t, s, "a", map[string]any{"id": int64(i)})
+	}
+	s.Close()
+
+	s = openStore(t, dir)
+	if s.LastLSN() != 3 {
+		t.Fatalf("LastLSN = %d after reopening", s.LastLSN())
+	}
+	sub, err := s.Subscribe("a", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	mustAppend(t, s, "a", map[string]any{"id": int64(9)})
+	evs := expectEvents(t, sub, 3, 4)
+	if evs[1].After["id"] != int64(9) {
+		t.Fatalf("event = %+v", evs[1])
+	}
+}
+
+func TestTrimChanges(t *testing.T) {
+	dir := t.TempDir()
+	s := cdcStore(t, dir)
+	slow, err := s.Subscribe("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer slow.Close()
+	live, err := s.Subscribe("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer live.Close()
+	for i := range 4 {
+		mustAppend(t, s, "a", map[string]any{"id": int64(i)})
+	}
+	expectEvents(t, live, 1, 2, 3, 4)
+
+	if err := s.TrimChanges(2); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Subscribe("", 1); !errors.Is(err, ErrChangesTrimmed) {
+		t.Fatalf("subscribe before the trimmed position: %v", err)
+	}
+	sub, err := s.Subscribe("", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	expectEvents(t, sub, 3, 4)
+
+	// a subscriber that caught up keeps going in the rewritten file
+	mustAppend(t, s, "b", map[string]any{"id": int64(5)})
+	expectEvents(t, live, 5)
+	expectEvents(t, sub, 5)
+
+	// the trim and the LSNs survive a restart
+	s.Close()
+	s = openStore(t, dir)
+	if s.LastLSN() != 5 {
+		t.Fatalf("LastLSN = %d after reopening", s.LastLSN())
+	}
+	if _, err := s.Subscribe("", 0); !errors.Is(err, ErrChangesTrimmed) {
+		t.Fatalf("subscribe from 0 after the trim: %v", err)
+	}
+}
+
+func TestTrimEndsLaggingSubscription(t *testing.T) {
+	s := cdcStore(t, t.TempDir())
+	for i := range 3 {
+		mustAppend(t, s, "a", map[string]any{"id": int64(i)})
+	}
+	if err := s.TrimChanges(2); err != nil {
+		t.Fatal(err)
+	}
+	// a subscription that had not read past the trimmed events yet
+	s.changes.mu.Lock()
+	sub := &Subscription{s: s, events: make(chan ChangeEvent, 64), wake: make(chan struct{}, 1),
+		done: make(chan struct{}), pos: 1, gen: s.changes.gen}
+	s.changes.subs[sub] = true
+	s.changes.mu.Unlock()
+	go sub.run()
+	if _, ok := <-sub.Events(); ok || !errors.Is(sub.Err(), ErrChangesTrimmed) {
+		t.Fatalf("lagging subscription: %v", sub.Err())
+	}
+}
+
+// tornChanges writes two events and the start of a third, as a crash in
+// the middle of an append leaves them
+func tornChanges(t *testing.T, dir, tail string) {
+	t.Helper()
+	s := cdcStore(t, dir)
+	mustAppend(t, s, "a", map[string]any{"id": int64(1)}, map[string]any{"id": int64(2)})
+	s.Close()
+	f, err := os.OpenFile(filepath.Join(dir, changesFile), os.O_APPEND|os.O_WRONLY, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(tail); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestChangesTornTail(t *testing.T) {
+	for _, tail := range []string{`{"lsn":3,"table":"a","op":"INS`, `{"lsn":3,"table":"a","op":"INSERT","after":{"id":3}}`, "garbage\n"} {
+		dir := t.TempDir()
+		tornChanges(t, dir, tail)
+
+		ro, err := OpenStore(dir, Options{ReadOnly: true})
+		if err != nil {
+			t.Fatalf("read-only open with tail %q: %v", tail, err)
+		}
+		sub, err := ro.Subscribe("", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expectEvents(t, sub, 1, 2)
+		ro.Close()
+
+		s := openStore(t, dir)
+		if s.LastLSN() != 2 {
+			t.Fatalf("tail %q: LastLSN = %d", tail, s.LastLSN())
+		}
+		mustAppend(t, s, "a", map[string]any{"id": int64(3)})
+		sub, err = s.Subscribe("", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		evs := expectEvents(t, sub, 1, 2, 3)
+		if evs[2].After["id"] != int64(3) {
+			t.Fatalf("tail %q: event = %+v", tail, evs[2])
+		}
+		sub.Close()
+		s.Close()
+	}
+}
+
+func TestChangesTornHeader(t *testing.T) {
+	dir := t.TempDir()
+	s := cdcStore(t, dir)
+	s.Close()
+	if err := os.WriteFile(filepath.Join(dir, changesFile), []byte(`{"ba`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	s = openStore(t, dir)
+	mustAppend(t, s, "a", map[string]any{"id": int64(1)})
+	sub, err := s.Subscribe("", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	expectEvents(t, sub, 1)
+}
+
+func TestChangesDamagedBeforeEnd(t *testing.T) {
+	dir := t.TempDir()
+	tornChanges(t, dir, "garbage\n"+`{"lsn":3,"table":"a","op":"INSERT","after":{"id":3}}`+"\n")
+	if _, err := NewStore(dir); err == nil {
+		t.Fatal("opened a change log with a damaged line before its end")
+	}
+}
@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ConstraintError reports a row rejected by a table constraint
+type ConstraintError struct {
+	Table      string
+	Constraint string
+	Detail     string
+}
+
+func (e *ConstraintError) Error() string {
+	return fmt.Sprintf("%s violates constraint %s on table %s", e.Detail, e.Constraint, e.Table)
+}
+
+func (e *ConstraintError) Unwrap() error { return ErrConstraintViolation }
+
+// checkNotNull rejects rows that leave a NOT NULL column empty
+func checkNotNull(meta *TableMeta, row map[string]any) error {
+	for _, c := range meta.Columns {
+		if !c.NotNull && !c.PrimaryKey {
+			continue
+		}
+		if v, ok := row[c.Name]; !ok || v == nil {
+			return &ConstraintError{
+				Table:      meta.Name,
+				Constraint: meta.Name + "_" + c.Name + "_not_null",
+				Detail:     fmt.Sprintf("null value in column %s", c.Name),
+			}
+		}
+	}
+	return nil
+}
+
+// CheckConstraint is a CHECK constraint: Expr, in SQL, must not be false
+// for any row of the table
+type CheckConstraint struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}
+
+// Evaluator evaluates the SQL expressions kept in table headers for a row
+// of meta. Storage does not understand SQL, so the executor installs one
+// with SetEvaluator at startup.
+type Evaluator interface {
+	// EvalCheck reports whether row passes a CHECK constraint; a NULL
+	// result passes
+	EvalCheck(meta *TableMeta, expr string, row map[string]any) (bool, error)
+	// EvalGenerated computes the value of a generated column
+	EvalGenerated(meta *TableMeta, expr string, row map[string]any) (any, error)
+}
+
+// SetEvaluator installs the evaluator of CHECK constraints and generated
+// columns
+func (s *Store) SetEvaluator(ev Evaluator) {
+	s.eval = ev
+}
+
+// validateChecks makes sure constraint names are unique within the table
+func (t *TableMeta) validateChecks() error {
+	seen := map[string]bool{}
+	for _, fk := range t.ForeignKeys {
+		seen[fk.Name] = true
+	}
+	for _, c := range t.Checks {
+		if seen[c.Name] {
+			return fmt.Errorf("duplicate constraint name %s on table %s", c.Name, t.Name)
+		}
+		seen[c.Name] = true
+	}
+	return nil
+}
+
+// checkRow rejects rows that leave a NOT NULL column empty or fail a
+// CHECK constraint
+func (s *Store) checkRow(meta *TableMeta, row map[string]any) error {
+	if err := checkNotNull(meta, row); err != nil {
+		return err
+	}
+	for _, c := range meta.Checks {
+		if s.eval == nil {
+			return fmt.Errorf("cannot evaluate check constraint %s on table %s", c.Name, meta.Name)
+		}
+		ok, err := s.eval.EvalCheck(meta, c.Expr, row)
+		if err != nil {
+			return fmt.Errorf("check constraint %s: %w", c.Name, err)
+		}
+		if !ok {
+			return &ConstraintError{Table: meta.Name, Constraint: c.Name, Detail: "new row"}
+		}
+	}
+	return nil
+}
+
+// keyPart encodes a value so that equal SQL values produce equal keys,
+// regardless of whether numbers came from the parser or from JSON.
+// Integers keep all their digits and integral floats are written the same
+// way, so that 1 and 1.0 match.
+func keyPart(v any) string {
+	switch n := v.(type) {
+	case int:
+		return "n:" + strconv.FormatInt(int64(n), 10)
+	case int64:
+		return "n:" + strconv.FormatInt(n, 10)
+	case float64:
+		if n == math.Trunc(n) && n >= -(1<<63) && n < 1<<63 {
+			return "n:" + strconv.FormatInt(int64(n), 10)
+		}
+		return "n:" + strconv.FormatFloat(n, 'g', -1, 64)
+	case string:
+		return "s:" + n
+	case Timestamp:
+		return "t:" + n.Format(timestampLayout)
+	case Date:
+		return "t:" + n.Format(timestampLayout)
+	default:
+		return fmt.Sprintf("%T:%v", v, v)
+	}
+}
+
+// rowKey builds the index key of row over cols. It returns false when any
+// of the columns is NULL, since NULLs never collide in a unique index.
+func rowKey(row map[string]any, cols []string) (string, bool) {
+	parts := make([]string, len(cols))
+	for i, c := range cols {
+		v, ok := row[c]
+		if !ok || v == nil {
+			return "", false
+		}
+		parts[i] = keyPart(v)
+	}
+	return strings.Join(parts, "\x00"), true
+}
+
+// uniqueSet tracks the keys of one unique index while a write is applied
+type uniqueSet struct {
+	idx  IndexMeta
+	keys map[string]int // key -> row position
+}
+
+type uniqueSets []*uniqueSet
+
+// newUniqueSets indexes rows for every unique index of the table and fails
+// if the rows already contain a duplicate
+func newUniqueSets(meta *TableMeta, rows []map[string]any) (uniqueSets, error) {
+	var sets uniqueSets
+	for _, idx := range meta.Indexes {
+		if idx.Unique {
+			sets = append(sets, &uniqueSet{idx: idx, keys: map[string]int{}})
+		}
+	}
+	for pos, row := range rows {
+		if err := sets.add(row, pos); err != nil {
+			return nil, err
+		}
+	}
+	return sets, nil
+}
+
+// lookup returns the position of the row colliding with row on any index
+func (us uniqueSets) lookup(row map[string]any) (*uniqueSet, int, bool) {
+	for _, u := range us {
+		if k, ok := rowKey(row, u.idx.Columns); ok {
+			if pos, ok := u.keys[k]; ok {
+				return u, pos, true
+			}
+		}
+	}
+	return nil, 0, false
+}
+
+// add registers row at pos, failing on a duplicate key
+func (us uniqueSets) add(row map[string]any, pos int) error {
+	if u, _, ok := us.lookup(row); ok {
+		k, _ := rowKey(row, u.idx.Columns)
+		return &ConstraintError{
+			Table:      u.idx.Table,
+			Constraint: u.idx.Name,
+			Detail:     fmt.Sprintf("duplicate key (%s)=(%s)", strings.Join(u.idx.Columns, ", "), displayKey(k)),
+		}
+	}
+	for _, u := range us {
+		if k, ok := rowKey(row, u.idx.Columns); ok {
+			u.keys[k] = pos
+		}
+	}
+	return nil
+}
+
+// remove forgets the keys of row
+func (us uniqueSets) remove(row map[string]any) {
+	for _, u := range us {
+		if k, ok := rowKey(row, u.idx.Columns); ok {
+			delete(u.keys, k)
+		}
+	}
+}
+
+// find returns the set for the unique index covering exactly cols
+func (us uniqueSets) find(cols []string) (*uniqueSet, bool) {
+	for _, u := range us {
+		if sameColumns(u.idx.Columns, cols) {
+			return u, true
+		}
+	}
+	return nil, false
+}
+
+func sameColumns(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]bool{}
+	for _, c := range a {
+		seen[c] = true
+	}
+	for _, c := range b {
+		if !seen[c] {
+			return false
+		}
+	}
+	return true
+}
+
+func displayKey(k string) string {
+	parts := strings.Split(k, "\x00")
+	for i, p := range parts {
+		if _, v, ok := strings.Cut(p, ":"); ok {
+			parts[i] = v
+		}
+	}
+	return strings.Join(parts, ", ")
+}
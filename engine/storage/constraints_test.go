@@ -0,0 +1,18 @@
+package storage
+
+import "testing"
+
+func TestKeyPartIntegers(t *testing.T) {
+	if a, b := keyPart(int64(9007199254740992)), keyPart(int64(9007199254740993)); a == b {
+		t.Fatalf("distinct BIGINT keys collide: %s", a)
+	}
+	if got := keyPart(int64(9007199254740993)); got != "n:9007199254740993" {
+		t.Fatalf("keyPart = %s, want all digits", got)
+	}
+	if a, b := keyPart(int64(1)), keyPart(1.0); a != b {
+		t.Fatalf("1 and 1.0 differ: %s, %s", a, b)
+	}
+	if a, b := keyPart(1.5), keyPart(int64(1)); a == b {
+		t.Fatalf("1.5 and 1 collide: %s", a)
+	}
+}
@@ -0,0 +1,17 @@
+package storage
+
+import "errors"
+
+var (
+	ErrTableNotFound    = errors.New("table not found")
+	ErrSequenceNotFound = errors.New("sequence not found")
+	ErrTriggerNotFound  = errors.New("trigger not found")
+	ErrReadOnlyTable    = errors.New("system tables are read-only")
+	ErrLocked           = errors.New("database is locked")
+	ErrReadOnlyStore    = errors.New("database is open read-only")
+	ErrLockUnsupported  = errors.New("locking the data directory is not supported on this system")
+
+	ErrConstraintViolation = errors.New("constraint violation")
+	ErrNoConflictTarget    = errors.New("no unique constraint matches the ON CONFLICT target")
+	ErrRowAffectedTwice    = errors.New("ON CONFLICT DO UPDATE command cannot affect row a second time")
+)
@@ -0,0 +1,343 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Referential actions of a foreign key, applied when a referenced row is
+// deleted or its key is updated. NO ACTION and RESTRICT both reject the
+// change.
+const (
+	NoAction = "NO ACTION"
+	Restrict = "RESTRICT"
+	Cascade  = "CASCADE"
+	SetNull  = "SET NULL"
+)
+
+// ForeignKey is a FOREIGN KEY constraint: unless one of them is NULL, the
+// values of Columns must equal RefColumns of a row of RefTable
+type ForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefTable   string   `json:"ref_table"`
+	RefColumns []string `json:"ref_columns"`
+	OnDelete   string   `json:"on_delete"`
+	OnUpdate   string   `json:"on_update"`
+}
+
+// validateForeignKeys checks that every foreign key of a new table matches
+// a unique index of the table it references, which may be the table itself
+func (s *Store) validateForeignKeys(meta *TableMeta) error {
+	seen := map[string]bool{}
+	for _, fk := range meta.ForeignKeys {
+		if seen[fk.Name] {
+			return fmt.Errorf("duplicate constraint name %s on table %s", fk.Name, meta.Name)
+		}
+		seen[fk.Name] = true
+		if len(fk.Columns) != len(fk.RefColumns) {
+			return fmt.Errorf("foreign key %s: number of referencing and referenced columns disagree", fk.Name)
+		}
+		for _, c := range fk.Columns {
+			col, ok := meta.Column(c)
+			if !ok {
+				return fmt.Errorf("foreign key %s: unknown column %s in table %s", fk.Name, c, meta.Name)
+			}
+			// actions would overwrite the computed value
+			if col.Virtual() {
+				return fmt.Errorf("foreign key %s: virtual generated column %s cannot reference another table", fk.Name, c)
+			}
+			if col.Generated != "" && (fk.OnDelete == SetNull || fk.OnUpdate == SetNull || fk.OnUpdate == Cascade) {
+				return fmt.Errorf("foreign key %s: invalid action for a foreign key on generated column %s", fk.Name, c)
+			}
+		}
+		for _, a := range []string{fk.OnDelete, fk.OnUpdate} {
+			if a != NoAction && a != Restrict && a != Cascade && a != SetNull {
+				return fmt.Errorf("foreign key %s: unknown action %s", fk.Name, a)
+			}
+		}
+
+		ref := meta
+		if fk.RefTable != meta.Name {
+			var ok bool
+			if ref, ok = s.catalog.Tables[fk.RefTable]; !ok {
+				return fmt.Errorf("%w: %s", ErrTableNotFound, fk.RefTable)
+			}
+			if _, isView := s.catalog.Views[fk.RefTable]; isView {
+				return fmt.Errorf("foreign key %s: referenced relation %s is not a table", fk.Name, fk.RefTable)
+			}
+		}
+		for _, c := range fk.RefColumns {
+			if _, ok := ref.Column(c); !ok {
+				return fmt.Errorf("foreign key %s: unknown column %s in table %s", fk.Name, c, ref.Name)
+			}
+		}
+		unique := false
+		for _, idx := range ref.Indexes {
+			unique = unique || (idx.Unique && sameColumns(idx.Columns, fk.RefColumns))
+		}
+		if !unique {
+			return fmt.Errorf("there is no unique constraint matching given keys for referenced table %s", ref.Name)
+		}
+	}
+	return nil
+}
+
+// fkRef is a foreign key together with the table that declares it
+type fkRef struct {
+	table string
+	fk    ForeignKey
+}
+
+// referencing returns the foreign keys that point at table
+func (s *Store) referencing(table string) []fkRef {
+	var refs []fkRef
+	for _, t := range s.tablesUnlocked() {
+		for _, fk := range t.ForeignKeys {
+			if fk.RefTable == table {
+				refs = append(refs, fkRef{table: t.Name, fk: fk})
+			}
+		}
+	}
+	return refs
+}
+
+// fkWrite applies a write to a table together with the referential actions
+// it triggers. The new contents of every table involved are computed and
+// checked in memory first, and files are only rewritten once all of them
+// are valid, so a failing write leaves every table unchanged.
+type fkWrite struct {
+	s       *Store
+	rows    map[string][]map[string]any // contents of the tables involved
+	dirty   map[string]bool             // tables to rewrite
+	check   map[string][]map[string]any // written rows whose references must exist
+	changes []ChangeEvent               // logged once the tables are rewritten
+}
+
+func (s *Store) newFKWrite() *fkWrite {
+	return &fkWrite{
+		s:     s,
+		rows:  map[string][]map[string]any{},
+		dirty: map[string]bool{},
+		check: map[string][]map[string]any{},
+	}
+}
+
+// table returns the contents of name as changed so far
+func (w *fkWrite) table(name string) ([]map[string]any, error) {
+	if rows, ok := w.rows[name]; ok {
+		return rows, nil
+	}
+	rows, err := w.s.scanTableUnlocked(name)
+	if err != nil {
+		return nil, err
+	}
+	w.rows[name] = rows
+	return rows, nil
+}
+
+// record adds a row change to the events commit logs
+func (w *fkWrite) record(op, table string, before, after map[string]any) {
+	w.changes = append(w.changes, ChangeEvent{Table: table, Op: op, Before: before, After: after})
+}
+
+// set replaces the contents of name, to be written by commit
+func (w *fkWrite) set(name string, rows []map[string]any) {
+	w.rows[name] = rows
+	w.dirty[name] = true
+}
+
+// deleted applies the ON DELETE actions of the foreign keys referencing
+// table to the rows that referenced the deleted ones
+func (w *fkWrite) deleted(table string, deleted []map[string]any) error {
+	if len(deleted) == 0 {
+		return nil
+	}
+	for _, ref := range w.s.referencing(table) {
+		fk := ref.fk
+		gone := map[string]map[string]any{}
+		for _, row := range deleted {
+			if k, ok := rowKey(row, fk.RefColumns); ok {
+				gone[k] = row
+			}
+		}
+		child, err := w.table(ref.table)
+		if err != nil {
+			return err
+		}
+		var kept, removed, olds, news []map[string]any
+		for _, row := range child {
+			k, ok := rowKey(row, fk.Columns)
+			if !ok || gone[k] == nil {
+				kept = append(kept, row)
+				continue
+			}
+			switch fk.OnDelete {
+			case Cascade:
+				removed = append(removed, row)
+				w.record(OpDelete, ref.table, row, nil)
+			case SetNull:
+				newRow := copyRow(row)
+				for _, c := range fk.Columns {
+					newRow[c] = nil
+				}
+				if err := w.s.prepareRow(w.s.catalog.Tables[ref.table], newRow); err != nil {
+					return err
+				}
+				kept = append(kept, newRow)
+				olds, news = append(olds, row), append(news, newRow)
+				w.record(OpUpdate, ref.table, row, newRow)
+			default:
+				return referencedError(ref, "deleting", table, k)
+			}
+		}
+		if len(removed) == 0 && len(news) == 0 {
+			continue
+		}
+		w.set(ref.table, kept)
+		if err := w.deleted(ref.table, removed); err != nil {
+			return err
+		}
+		if err := w.updated(ref.table, olds, news); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// updated applies the ON UPDATE actions of the foreign keys referencing
+// table to the rows that referenced a key that olds[i] had and news[i] no
+// longer has
+func (w *fkWrite) updated(table string, olds, news []map[string]any) error {
+	if len(olds) == 0 {
+		return nil
+	}
+	for _, ref := range w.s.referencing(table) {
+		fk := ref.fk
+		current, err := w.table(table)
+		if err != nil {
+			return err
+		}
+		present := map[string]bool{}
+		for _, row := range current {
+			if k, ok := rowKey(row, fk.RefColumns); ok {
+				present[k] = true
+			}
+		}
+		changed := map[string]map[string]any{} // old key -> new row
+		for i, old := range olds {
+			k, ok := rowKey(old, fk.RefColumns)
+			if ok && !present[k] {
+				changed[k] = news[i]
+			}
+		}
+		if len(changed) == 0 {
+			continue
+		}
+
+		child, err := w.table(ref.table)
+		if err != nil {
+			return err
+		}
+		out := make([]map[string]any, len(child))
+		var cOlds, cNews []map[string]any
+		for i, row := range child {
+			out[i] = row
+			k, ok := rowKey(row, fk.Columns)
+			if !ok || changed[k] == nil {
+				continue
+			}
+			newRow := copyRow(row)
+			switch fk.OnUpdate {
+			case Cascade:
+				for j, c := range fk.Columns {
+					newRow[c] = changed[k][fk.RefColumns[j]]
+				}
+			case SetNull:
+				for _, c := range fk.Columns {
+					newRow[c] = nil
+				}
+			default:
+				return referencedError(ref, "updating", table, k)
+			}
+			if err := w.s.prepareRow(w.s.catalog.Tables[ref.table], newRow); err != nil {
+				return err
+			}
+			out[i] = newRow
+			cOlds, cNews = append(cOlds, row), append(cNews, newRow)
+			w.record(OpUpdate, ref.table, row, newRow)
+		}
+		if len(cNews) == 0 {
+			continue
+		}
+		w.set(ref.table, out)
+		if err := w.updated(ref.table, cOlds, cNews); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func referencedError(ref fkRef, change, table, key string) error {
+	return &ConstraintError{
+		Table:      ref.table,
+		Constraint: ref.fk.Name,
+		Detail:     fmt.Sprintf("%s key (%s)=(%s) of table %s", change, strings.Join(ref.fk.RefColumns, ", "), displayKey(key), table),
+	}
+}
+
+// verify checks that the rows in w.check reference existing rows
+func (w *fkWrite) verify() error {
+	for table, rows := range w.check {
+		meta := w.s.catalog.Tables[table]
+		for _, fk := range meta.ForeignKeys {
+			parent, err := w.table(fk.RefTable)
+			if err != nil {
+				return err
+			}
+			keys := make(map[string]bool, len(parent))
+			for _, row := range parent {
+				if k, ok := rowKey(row, fk.RefColumns); ok {
+					keys[k] = true
+				}
+			}
+			for _, row := range rows {
+				if k, ok := rowKey(row, fk.Columns); ok && !keys[k] {
+					return &ConstraintError{
+						Table:      table,
+						Constraint: fk.Name,
+						Detail:     fmt.Sprintf("key (%s)=(%s) not present in table %s", strings.Join(fk.Columns, ", "), displayKey(k), fk.RefTable),
+					}
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// commit verifies references, checks the unique constraints of every
+// changed table, rewrites them and logs the recorded changes. Rows changed
+// by referential actions were checked against the other constraints when
+// they were made.
+func (w *fkWrite) commit() error {
+	if err := w.verify(); err != nil {
+		return err
+	}
+	tables := make([]string, 0, len(w.dirty))
+	for t := range w.dirty {
+		tables = append(tables, t)
+	}
+	sort.Strings(tables)
+	for _, t := range tables {
+		if _, err := newUniqueSets(w.s.catalog.Tables[t], w.rows[t]); err != nil {
+			return err
+		}
+	}
+	for _, t := range tables {
+		if err := w.s.rewriteTable(t, w.rows[t]); err != nil {
+			return err
+		}
+	}
+	return w.s.logChanges(w.changes)
+}
@@ -0,0 +1,191 @@
+package storage
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+// fkStore creates users(id), coupons(code) and orders referencing both,
+// with the given actions for the foreign key on user_id
+func fkStore(t *testing.T, onDelete, onUpdate string) *Store {
+	t.Helper()
+	s := openStore(t, t.TempDir())
+	unique := func(table, col string) IndexMeta {
+		return IndexMeta{Name: table + "_" + col, Table: table, Columns: []string{col}, Unique: true}
+	}
+	err := s.Update(func(tx *Tx) error {
+		if err := tx.CreateTable("users", []ColumnDefinition{{Name: "id", Type: "INTEGER"}}, unique("users", "id")); err != nil {
+			return err
+		}
+		if err := tx.CreateTable("coupons", []ColumnDefinition{{Name: "code", Type: "INTEGER"}}, unique("coupons", "code")); err != nil {
+			return err
+		}
+		return tx.CreateTableMeta(TableMeta{
+			Name: "orders",
+			Columns: []ColumnDefinition{
+				{Name: "id", Type: "INTEGER"}, {Name: "user_id", Type: "INTEGER"}, {Name: "coupon", Type: "INTEGER"},
+			},
+			Indexes: []IndexMeta{unique("orders", "id")},
+			ForeignKeys: []ForeignKey{
+				{Name: "orders_user", Columns: []string{"user_id"}, RefTable: "users", RefColumns: []string{"id"},
+					OnDelete: onDelete, OnUpdate: onUpdate},
+				{Name: "orders_coupon", Columns: []string{"coupon"}, RefTable: "coupons", RefColumns: []string{"code"},
+					OnDelete: SetNull, OnUpdate: Cascade},
+			},
+		})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	mustAppend(t, s, "users", map[string]any{"id": int64(1)}, map[string]any{"id": int64(2)})
+	mustAppend(t, s, "coupons", map[string]any{"code": int64(10)})
+	mustAppend(t, s, "orders",
+		map[string]any{"id": int64(1), "user_id": int64(1), "coupon": int64(10)},
+		map[string]any{"id": int64(2), "user_id": int64(2), "coupon": nil},
+		map[string]any{"id": int64(3), "user_id": int64(1), "coupon": nil})
+	return s
+}
+
+func mustAppend(t *testing.T, s *Store, table string, rows ...map[string]any) {
+	t.Helper()
+	if _, err := s.AppendRows(table, rows); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func byID(id int64) RowFilter {
+	return func(r map[string]any) (bool, error) { return r["id"] == id, nil }
+}
+
+func expectOrders(t *testing.T, s *Store, want ...[3]any) {
+	t.Helper()
+	rows, err := s.ScanTable("orders")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := [][3]any{}
+	for _, r := range rows {
+		got = append(got, [3]any{r["id"], r["user_id"], r["coupon"]})
+	}
+	if want == nil {
+		want = [][3]any{}
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("orders = %v, want %v", got, want)
+	}
+}
+
+func TestForeignKeyCascade(t *testing.T) {
+	s := fkStore(t, Cascade, Cascade)
+	if _, err := s.UpdateRows("users", byID(1), func(r map[string]any) (map[string]any, error) {
+		r["id"] = int64(5)
+		return r, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expectOrders(t, s, [3]any{int64(1), int64(5), int64(10)}, [3]any{int64(2), int64(2), nil}, [3]any{int64(3), int64(5), nil})
+
+	if _, err := s.DeleteRows("users", byID(5)); err != nil {
+		t.Fatal(err)
+	}
+	expectOrders(t, s, [3]any{int64(2), int64(2), nil})
+
+	// the cascaded changes are logged too
+	var ops []string
+	sub, err := s.Subscribe("orders", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer sub.Close()
+	for len(ops) < 7 {
+		select {
+		case ev := <-sub.Events():
+			ops = append(ops, ev.Op)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("orders changes = %v, waiting for more", ops)
+		}
+	}
+	want := []string{OpInsert, OpInsert, OpInsert, OpUpdate, OpUpdate, OpDelete, OpDelete}
+	if !reflect.DeepEqual(ops, want) {
+		t.Fatalf("orders changes = %v, want %v", ops, want)
+	}
+}
+
+func TestForeignKeySetNull(t *testing.T) {
+	s := fkStore(t, SetNull, SetNull)
+	if _, err := s.DeleteRows("users", byID(1)); err != nil {
+		t.Fatal(err)
+	}
+	expectOrders(t, s, [3]any{int64(1), nil, int64(10)}, [3]any{int64(2), int64(2), nil}, [3]any{int64(3), nil, nil})
+
+	if _, err := s.UpdateRows("users", byID(2), func(r map[string]any) (map[string]any, error) {
+		r["id"] = int64(7)
+		return r, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	// the coupon key follows its ON UPDATE CASCADE, then ON DELETE SET NULL
+	if _, err := s.UpdateRows("coupons", nil, func(r map[string]any) (map[string]any, error) {
+		r["code"] = int64(11)
+		return r, nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	expectOrders(t, s, [3]any{int64(1), nil, int64(11)}, [3]any{int64(2), nil, nil}, [3]any{int64(3), nil, nil})
+	if _, err := s.DeleteRows("coupons", nil); err != nil {
+		t.Fatal(err)
+	}
+	expectOrders(t, s, [3]any{int64(1), nil, nil}, [3]any{int64(2), nil, nil}, [3]any{int64(3), nil, nil})
+}
+
+func TestForeignKeyRestrict(t *testing.T) {
+	for _, action := range []string{Restrict, NoAction} {
+		s := fkStore(t, action, action)
+		if _, err := s.DeleteRows("users", byID(1)); !errors.Is(err, ErrConstraintViolation) {
+			t.Fatalf("%s: delete of a referenced row: %v", action, err)
+		}
+		if _, err := s.UpdateRows("users", byID(2), func(r map[string]any) (map[string]any, error) {
+			r["id"] = int64(9)
+			return r, nil
+		}); !errors.Is(err, ErrConstraintViolation) {
+			t.Fatalf("%s: update of a referenced key: %v", action, err)
+		}
+		// unreferenced rows and keys that stay the same are fine
+		mustAppend(t, s, "users", map[string]any{"id": int64(3)})
+		if _, err := s.DeleteRows("users", byID(3)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := s.UpdateRows("users", byID(1), func(r map[string]any) (map[string]any, error) { return r, nil }); err != nil {
+			t.Fatal(err)
+		}
+		expectOrders(t, s, [3]any{int64(1), int64(1), int64(10)}, [3]any{int64(2), int64(2), nil}, [3]any{int64(3), int64(1), nil})
+	}
+}
+
+func TestForeignKeyChecksReferences(t *testing.T) {
+	s := fkStore(t, NoAction, NoAction)
+	if _, err := s.AppendRows("orders", []map[string]any{{"id": int64(4), "user_id": int64(9)}}); !errors.Is(err, ErrConstraintViolation) {
+		t.Fatalf("insert of a dangling reference: %v", err)
+	}
+	if _, err := s.UpdateRows("orders", byID(2), func(r map[string]any) (map[string]any, error) {
+		r["coupon"] = int64(99)
+		return r, nil
+	}); !errors.Is(err, ErrConstraintViolation) {
+		t.Fatalf("update to a dangling reference: %v", err)
+	}
+	// a failing cascade leaves every table unchanged
+	expectOrders(t, s, [3]any{int64(1), int64(1), int64(10)}, [3]any{int64(2), int64(2), nil}, [3]any{int64(3), int64(1), nil})
+
+	err := s.Update(func(tx *Tx) error {
+		return tx.CreateTableMeta(TableMeta{
+			Name:        "bad",
+			Columns:     []ColumnDefinition{{Name: "x", Type: "INTEGER"}},
+			ForeignKeys: []ForeignKey{{Name: "bad_x", Columns: []string{"x"}, RefTable: "orders", RefColumns: []string{"user_id"}}},
+		})
+	})
+	if err == nil {
+		t.Fatal("foreign key to a column without a unique index accepted")
+	}
+}
@@ -0,0 +1,61 @@
+package storage
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// prepareRow readies a row for writing: it coerces values to the column
+// types, computes the generated columns and checks the row constraints
+func (s *Store) prepareRow(meta *TableMeta, row map[string]any) error {
+	if err := coerceRow(meta, row); err != nil {
+		return err
+	}
+	if err := s.computeGenerated(meta, row, false); err != nil {
+		return err
+	}
+	return s.checkRow(meta, row)
+}
+
+// computeGenerated sets the generated columns of row, or only the virtual
+// ones when reading it back. Generation expressions read only plain
+// columns, so the order they are computed in does not matter.
+func (s *Store) computeGenerated(meta *TableMeta, row map[string]any, virtualOnly bool) error {
+	computed := false
+	for _, c := range meta.Columns {
+		if c.Generated == "" || (virtualOnly && !c.Virtual()) {
+			continue
+		}
+		if s.eval == nil {
+			return fmt.Errorf("cannot compute generated column %s of table %s", c.Name, meta.Name)
+		}
+		v, err := s.eval.EvalGenerated(meta, c.Generated, row)
+		if err != nil {
+			return fmt.Errorf("generated column %s: %w", c.Name, err)
+		}
+		row[c.Name] = v
+		computed = true
+	}
+	if !computed {
+		return nil
+	}
+	return coerceRow(meta, row)
+}
+
+// encodeRow encodes a row for the table file, leaving out virtual columns
+func encodeRow(meta *TableMeta, row map[string]any) ([]byte, error) {
+	var stored map[string]any
+	for _, c := range meta.Columns {
+		if !c.Virtual() {
+			continue
+		}
+		if stored == nil {
+			stored = copyRow(row)
+		}
+		delete(stored, c.Name)
+	}
+	if stored == nil {
+		return json.Marshal(row)
+	}
+	return json.Marshal(stored)
+}
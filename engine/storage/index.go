@@ -0,0 +1,301 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"cmp"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Indexes are not persisted as separate structures. The first lookup
+// through an index reads the table file once and keeps, in memory, the
+// values of the index's leading column in sorted order together with the
+// file offset of each row. Lookups then read only the matching lines.
+// Any write to the table drops its cached indexes.
+
+// KeyRange bounds the values of an index's leading column. A nil bound
+// leaves that side open.
+type KeyRange struct {
+	Low      any
+	High     any
+	LowIncl  bool
+	HighIncl bool
+}
+
+// memIndex is the in-memory form of an index over one column
+type memIndex struct {
+	entries []indexEntry // sorted by key
+}
+
+type indexEntry struct {
+	key any
+	off int64 // offset of the row line in the table file
+	n   int   // line length without the newline
+}
+
+// CreateIndex adds an index to an existing table. A unique index is
+// rejected if the current rows already contain a duplicate key.
+func (tx *Tx) CreateIndex(idx IndexMeta) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+
+	if IsSystemTable(idx.Table) {
+		return fmt.Errorf("%w: %s", ErrReadOnlyTable, idx.Table)
+	}
+	meta, ok := s.catalog.Tables[idx.Table]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrTableNotFound, idx.Table)
+	}
+	for _, t := range s.catalog.Tables {
+		for _, other := range t.Indexes {
+			if other.Name == idx.Name {
+				return fmt.Errorf("index %s already exists", idx.Name)
+			}
+		}
+	}
+
+	next := *meta
+	next.Indexes = append(slices.Clone(meta.Indexes), idx)
+	if err := next.validateIndexes(); err != nil {
+		return err
+	}
+	rows, err := s.scanTableUnlocked(idx.Table)
+	if err != nil {
+		return err
+	}
+	if _, err := newUniqueSets(&next, rows); err != nil {
+		return err
+	}
+
+	s.catalog.Tables[idx.Table] = &next
+	if err := s.rewriteTable(idx.Table, rows); err != nil {
+		s.catalog.Tables[idx.Table] = meta
+		return err
+	}
+	return s.saveCatalog()
+}
+
+// ScanIndex returns the rows whose value in column falls into any of the
+// ranges, in table order. column must be the leading column of an index.
+func (tx *Tx) ScanIndex(table, column string, ranges []KeyRange) ([]map[string]any, error) {
+	s := tx.s
+
+	meta, ok := s.catalog.Tables[table]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTableNotFound, table)
+	}
+	indexed := false
+	for _, idx := range meta.Indexes {
+		indexed = indexed || idx.Columns[0] == column
+	}
+	if !indexed {
+		return nil, fmt.Errorf("no index on %s.%s", table, column)
+	}
+
+	mi, err := s.memIndexUnlocked(meta, column)
+	if err != nil {
+		return nil, err
+	}
+	hits := map[int64]indexEntry{}
+	for _, r := range ranges {
+		for _, e := range mi.lookup(r) {
+			hits[e.off] = e
+		}
+	}
+	matched := make([]indexEntry, 0, len(hits))
+	for _, e := range hits {
+		matched = append(matched, e)
+	}
+	sort.Slice(matched, func(i, j int) bool { return matched[i].off < matched[j].off })
+
+	r := s.readPages(table)
+	defer r.Close()
+	rows := make([]map[string]any, 0, len(matched))
+	for _, e := range matched {
+		line := make([]byte, e.n)
+		if _, err := r.ReadAt(line, e.off); err != nil {
+			return nil, err
+		}
+		row, err := decodeRow(meta, line)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.computeGenerated(meta, row, true); err != nil {
+			return nil, err
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// memIndexUnlocked returns the cached index over column, building it on
+// first use. Callers hold s.mu at least for reading.
+func (s *Store) memIndexUnlocked(meta *TableMeta, column string) (*memIndex, error) {
+	key := meta.Name + "\x00" + column
+	s.idxMu.Lock()
+	defer s.idxMu.Unlock()
+	if mi, ok := s.indexCache[key]; ok {
+		return mi, nil
+	}
+
+	pr := s.readPages(meta.Name)
+	defer pr.Close()
+	r := bufio.NewReader(pr)
+	header, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+
+	mi := &memIndex{}
+	off := int64(len(header))
+	for {
+		line, err := r.ReadBytes('\n')
+		if len(line) > 0 {
+			body := bytes.TrimRight(line, "\n")
+			if len(bytes.TrimSpace(body)) > 0 {
+				row, derr := decodeRow(meta, body)
+				if derr != nil {
+					return nil, derr
+				}
+				// NULLs never satisfy a range, so they are not indexed
+				if v := row[column]; v != nil {
+					mi.entries = append(mi.entries, indexEntry{key: v, off: off, n: len(body)})
+				}
+			}
+			off += int64(len(line))
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+	sort.SliceStable(mi.entries, func(i, j int) bool {
+		return compareKeys(mi.entries[i].key, mi.entries[j].key) < 0
+	})
+	if s.indexCache == nil {
+		s.indexCache = map[string]*memIndex{}
+	}
+	s.indexCache[key] = mi
+	return mi, nil
+}
+
+// dropIndexCache forgets the cached indexes of a table after a write
+func (s *Store) dropIndexCache(table string) {
+	s.idxMu.Lock()
+	defer s.idxMu.Unlock()
+	for key := range s.indexCache {
+		if strings.HasPrefix(key, table+"\x00") {
+			delete(s.indexCache, key)
+		}
+	}
+}
+
+// lookup returns the entries inside r
+func (mi *memIndex) lookup(r KeyRange) []indexEntry {
+	start := 0
+	if r.Low != nil {
+		start = sort.Search(len(mi.entries), func(i int) bool {
+			c := compareKeys(mi.entries[i].key, r.Low)
+			return c > 0 || (c == 0 && r.LowIncl)
+		})
+	}
+	end := start
+	for end < len(mi.entries) {
+		if r.High != nil {
+			c := compareKeys(mi.entries[end].key, r.High)
+			if c > 0 || (c == 0 && !r.HighIncl) {
+				break
+			}
+		}
+		end++
+	}
+	return mi.entries[start:end]
+}
+
+// decodeRow decodes one row line the same way scans do
+func decodeRow(meta *TableMeta, line []byte) (map[string]any, error) {
+	dec := json.NewDecoder(bytes.NewReader(line))
+	dec.UseNumber()
+	var m map[string]any
+	if err := dec.Decode(&m); err != nil {
+		return nil, fmt.Errorf("table %s: bad row: %w", meta.Name, err)
+	}
+	for k, v := range m {
+		if n, ok := v.(json.Number); ok {
+			m[k] = normalizeNumber(n)
+		}
+	}
+	decodeTemporal(meta, m)
+	return m, nil
+}
+
+// compareKeys orders index keys. Numbers sort before strings, booleans and
+// temporal values; within a kind values compare naturally.
+func compareKeys(a, b any) int {
+	if c := cmp.Compare(keyRank(a), keyRank(b)); c != 0 {
+		return c
+	}
+	switch x := a.(type) {
+	case int64:
+		if y, ok := b.(int64); ok {
+			return cmp.Compare(x, y)
+		}
+	case string:
+		return strings.Compare(x, b.(string))
+	case bool:
+		y := b.(bool)
+		switch {
+		case x == y:
+			return 0
+		case !x:
+			return -1
+		}
+		return 1
+	case Timestamp, Date:
+		return keyTime(a).Compare(keyTime(b))
+	}
+	return cmp.Compare(keyFloat(a), keyFloat(b))
+}
+
+func keyRank(v any) int {
+	switch v.(type) {
+	case int64, int, float64:
+		return 0
+	case string:
+		return 1
+	case bool:
+		return 2
+	case Timestamp, Date:
+		return 3
+	}
+	return 4
+}
+
+func keyTime(v any) time.Time {
+	if d, ok := v.(Date); ok {
+		return d.Time
+	}
+	return v.(Timestamp).Time
+}
+
+func keyFloat(v any) float64 {
+	switch n := v.(type) {
+	case int64:
+		return float64(n)
+	case int:
+		return float64(n)
+	case float64:
+		return n
+	}
+	return 0
+}
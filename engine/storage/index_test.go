@@ -0,0 +1,77 @@
+package storage
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestScanIndex(t *testing.T) {
+	s := openStore(t, t.TempDir())
+	cols := []ColumnDefinition{{Name: "id", Type: "INTEGER"}, {Name: "name", Type: "TEXT"}}
+	if err := s.CreateTable("t", cols, IndexMeta{Name: "t_name", Table: "t", Columns: []string{"name"}}); err != nil {
+		t.Fatal(err)
+	}
+	var rows []map[string]any
+	for i, name := range []string{"bob", "abe", "ann", "carl", "abby", "ann"} {
+		rows = append(rows, map[string]any{"id": int64(i + 1), "name": name})
+	}
+	if _, err := s.AppendRows("t", rows); err != nil {
+		t.Fatal(err)
+	}
+
+	scan := func(ranges ...KeyRange) []int64 {
+		t.Helper()
+		var ids []int64
+		err := s.View(func(tx *Tx) error {
+			rows, err := tx.ScanIndex("t", "name", ranges)
+			for _, r := range rows {
+				ids = append(ids, r["id"].(int64))
+			}
+			return err
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return ids
+	}
+	point := func(v string) KeyRange { return KeyRange{Low: v, High: v, LowIncl: true, HighIncl: true} }
+
+	for _, tc := range []struct {
+		ranges []KeyRange
+		want   []int64
+	}{
+		// rows come back in table order, each once
+		{[]KeyRange{point("ann"), point("bob"), point("ann")}, []int64{1, 3, 6}},
+		{[]KeyRange{{Low: "ab", High: "ac", LowIncl: true}}, []int64{2, 5}},
+		{[]KeyRange{{Low: "ann", High: "carl"}}, []int64{1}},
+		{[]KeyRange{{Low: "ann", High: "carl", LowIncl: true, HighIncl: true}}, []int64{1, 3, 4, 6}},
+		{[]KeyRange{{High: "abe", HighIncl: true}}, []int64{2, 5}},
+		{[]KeyRange{point("zed")}, nil},
+	} {
+		if got := scan(tc.ranges...); !reflect.DeepEqual(got, tc.want) {
+			t.Errorf("ranges %+v: ids %v, want %v", tc.ranges, got, tc.want)
+		}
+	}
+
+	// writes drop the cached index
+	if _, err := s.AppendRows("t", []map[string]any{{"id": int64(7), "name": "ann"}}); err != nil {
+		t.Fatal(err)
+	}
+	if got := scan(point("ann")); !reflect.DeepEqual(got, []int64{3, 6, 7}) {
+		t.Fatalf("after append: %v", got)
+	}
+	if _, err := s.DeleteRows("t", func(r map[string]any) (bool, error) { return r["id"] == int64(3), nil }); err != nil {
+		t.Fatal(err)
+	}
+	if got := scan(point("ann")); !reflect.DeepEqual(got, []int64{6, 7}) {
+		t.Fatalf("after delete: %v", got)
+	}
+
+	err := s.View(func(tx *Tx) error {
+		_, err := tx.ScanIndex("t", "id", []KeyRange{point("x")})
+		return err
+	})
+	if err == nil {
+		t.Fatal("scanned a column without an index")
+	}
+}
@@ -0,0 +1,94 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// lockFileName is the file in the data directory processes lock to share
+// it: a read-write store holds an exclusive lock on it and read-only stores
+// shared ones, for as long as they are open
+const lockFileName = "nalar.lock"
+
+// busyPoll is how often a store waiting for the data directory retries
+const busyPoll = 10 * time.Millisecond
+
+// lockDir takes the lock on the data directory that opts ask for. A
+// read-only store does not create the lock file: a directory without one
+// was never opened for writing, for instance because it is on read-only
+// media. It takes a shared lock on the directory itself instead, which a
+// writer holds exclusively besides the lock file, so that one starting
+// meanwhile still waits for it.
+func (s *Store) lockDir(opts Options) error {
+	flag := os.O_RDWR | os.O_CREATE
+	if opts.ReadOnly {
+		flag = os.O_RDONLY
+	}
+	deadline := time.Now().Add(opts.BusyTimeout)
+	f, err := os.OpenFile(filepath.Join(s.baseDir, lockFileName), flag, 0o644)
+	if opts.ReadOnly && errors.Is(err, os.ErrNotExist) {
+		return s.lockPath(s.baseDir, false, deadline)
+	}
+	if err != nil {
+		return err
+	}
+	if err := s.lockOpen(f, !opts.ReadOnly, deadline); err != nil {
+		return err
+	}
+	if !opts.ReadOnly {
+		if err := s.lockPath(s.baseDir, true, deadline); err != nil {
+			s.unlockDir()
+			return err
+		}
+	}
+	return nil
+}
+
+// lockPath opens path and locks it like lockOpen
+func (s *Store) lockPath(path string, exclusive bool, deadline time.Time) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	return s.lockOpen(f, exclusive, deadline)
+}
+
+// lockOpen locks f, waiting for other processes until deadline, and keeps
+// it open until unlockDir; f is closed when it cannot be locked
+func (s *Store) lockOpen(f *os.File, exclusive bool, deadline time.Time) error {
+	for {
+		ok, err := lockFile(f, exclusive)
+		if err != nil {
+			f.Close()
+			return err
+		}
+		if ok {
+			s.locks = append(s.locks, f)
+			return nil
+		}
+		wait := time.Until(deadline)
+		if wait <= 0 {
+			f.Close()
+			return fmt.Errorf("%w: %s is in use by another process", ErrLocked, s.baseDir)
+		}
+		time.Sleep(min(busyPoll, wait))
+	}
+}
+
+// unlockDir releases the locks on the data directory
+func (s *Store) unlockDir() error {
+	var err error
+	for _, f := range s.locks {
+		if uerr := unlockFile(f); err == nil {
+			err = uerr
+		}
+		if cerr := f.Close(); err == nil {
+			err = cerr
+		}
+	}
+	s.locks = nil
+	return err
+}
@@ -0,0 +1,15 @@
+//go:build !unix
+
+package storage
+
+import "os"
+
+// lockFile fails on systems without flock rather than letting processes
+// share a data directory without keeping them apart
+func lockFile(f *os.File, exclusive bool) (bool, error) {
+	return false, ErrLockUnsupported
+}
+
+func unlockFile(f *os.File) error {
+	return nil
+}
@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestLockDir(t *testing.T) {
+	dir := t.TempDir()
+	w := openStore(t, dir)
+	if _, err := NewStore(dir); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second writer: %v", err)
+	}
+	if _, err := OpenStore(dir, Options{ReadOnly: true}); !errors.Is(err, ErrLocked) {
+		t.Fatalf("reader next to a writer: %v", err)
+	}
+	w.Close()
+
+	r1, err := OpenStore(dir, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2, err := OpenStore(dir, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r2.Close()
+	if _, err := NewStore(dir); !errors.Is(err, ErrLocked) {
+		t.Fatalf("writer next to a reader: %v", err)
+	}
+
+	// a writer with a busy timeout waits for the reader to close
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		r1.Close()
+	}()
+	w, err = OpenStore(dir, Options{BusyTimeout: 5 * time.Second})
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+}
+
+func TestReadOnlyWithoutLockFile(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateTable("t", []ColumnDefinition{{Name: "id", Type: "INTEGER"}}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	lock := filepath.Join(dir, lockFileName)
+	if err := os.Remove(lock); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = OpenStore(dir, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.ScanTable("t"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(lock); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("read-only store created %s: %v", lockFileName, err)
+	}
+
+	// the reader locks the directory itself, which readers share and
+	// writers wait for
+	r, err := OpenStore(dir, Options{ReadOnly: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	if _, err := NewStore(dir); !errors.Is(err, ErrLocked) {
+		t.Fatalf("writer next to a reader without lock file: %v", err)
+	}
+	s.Close()
+	w, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Close()
+}
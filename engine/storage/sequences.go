@@ -0,0 +1,236 @@
+package storage
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// SequenceMeta describes a sequence: a persisted counter handed out by
+// NextVal. Owner is table.column for the sequence of an AUTO_INCREMENT
+// column, which exists as long as its table.
+type SequenceMeta struct {
+	Name      string `json:"name"`
+	Start     int64  `json:"start"`
+	Increment int64  `json:"increment"`
+	Last      int64  `json:"last"`             // last value handed out or set
+	Called    bool   `json:"called,omitempty"` // false until Last is handed out
+	Owner     string `json:"owner,omitempty"`
+}
+
+// sequenceBlock is how many values NextVal reserves with one catalog
+// write
+const sequenceBlock = 64
+
+// OwnedSequence is the name of the sequence of an AUTO_INCREMENT column
+func OwnedSequence(table, column string) string {
+	return table + "_" + column + "_seq"
+}
+
+// Sequence returns the current state of the named sequence
+func (tx *Tx) Sequence(name string) (SequenceMeta, bool) {
+	s := tx.s
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+	if seq, ok := s.catalog.Sequences[name]; ok {
+		return *seq, true
+	}
+	return SequenceMeta{}, false
+}
+
+// Sequences returns all sequences sorted by name
+func (tx *Tx) Sequences() []SequenceMeta {
+	return tx.s.sequencesUnlocked()
+}
+
+func (s *Store) sequencesUnlocked() []SequenceMeta {
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+	out := make([]SequenceMeta, 0, len(s.catalog.Sequences))
+	for _, seq := range s.catalog.Sequences {
+		out = append(out, *seq)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// CreateSequence registers a new sequence, whose first value is its start
+func (tx *Tx) CreateSequence(seq SequenceMeta) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+	if err := s.checkSequenceName(seq.Name); err != nil {
+		return err
+	}
+	if seq.Increment == 0 {
+		return fmt.Errorf("sequence %s: increment must not be zero", seq.Name)
+	}
+	seq.Last, seq.Called = seq.Start, false
+	s.catalog.Sequences[seq.Name] = &seq
+	return s.saveCatalog()
+}
+
+// checkSequenceName makes sure a new sequence does not take the name of
+// another schema object
+func (s *Store) checkSequenceName(name string) error {
+	if IsSystemTable(name) {
+		return fmt.Errorf("sequence name %s is reserved for system tables", name)
+	}
+	if _, ok := s.catalog.Sequences[name]; ok {
+		return fmt.Errorf("sequence %s already exists", name)
+	}
+	if _, ok := s.catalog.Views[name]; ok {
+		return fmt.Errorf("view %s already exists", name)
+	}
+	if _, ok := s.catalog.Tables[name]; ok {
+		return fmt.Errorf("table %s already exists", name)
+	}
+	return nil
+}
+
+// DropSequence removes a sequence that no column owns
+func (tx *Tx) DropSequence(name string) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+	seq, ok := s.catalog.Sequences[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSequenceNotFound, name)
+	}
+	if seq.Owner != "" {
+		table, column, _ := strings.Cut(seq.Owner, ".")
+		return fmt.Errorf("cannot drop sequence %s because column %s of table %s requires it", name, column, table)
+	}
+	delete(s.catalog.Sequences, name)
+	delete(s.seqReserved, seq)
+	return s.saveCatalog()
+}
+
+// NextVal advances the named sequence and returns its new value. Sequences
+// change outside of transactions: a value, once handed out, is never
+// handed out again, even to a read-only transaction or when the statement
+// that asked for it fails. Values are reserved sequenceBlock at a time by
+// persisting the last of them as if it had been handed out, so after a
+// crash the sequence continues past the block and the values left in it
+// are skipped; Close saves the value actually reached.
+func (tx *Tx) NextVal(name string) (int64, error) {
+	s := tx.s
+	if s.readOnly {
+		return 0, ErrReadOnlyStore
+	}
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	seq, ok := s.catalog.Sequences[name]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ErrSequenceNotFound, name)
+	}
+	next := seq.Last
+	if seq.Called {
+		if (seq.Increment > 0 && seq.Last > math.MaxInt64-seq.Increment) ||
+			(seq.Increment < 0 && seq.Last < math.MinInt64-seq.Increment) {
+			return 0, fmt.Errorf("sequence %s reached its limit", name)
+		}
+		next += seq.Increment
+	}
+	if bound, ok := s.seqReserved[seq]; ok && (seq.Increment > 0 && next <= bound || seq.Increment < 0 && next >= bound) {
+		seq.Last, seq.Called = next, true
+		return next, nil
+	}
+	bound := next
+	for range sequenceBlock - 1 {
+		if seq.Increment > 0 && bound > math.MaxInt64-seq.Increment ||
+			seq.Increment < 0 && bound < math.MinInt64-seq.Increment {
+			break
+		}
+		bound += seq.Increment
+	}
+	prev := *seq
+	seq.Last, seq.Called = next, true
+	s.seqReserved[seq] = bound
+	if err := s.saveCatalog(); err != nil {
+		*seq = prev
+		delete(s.seqReserved, seq)
+		return 0, err
+	}
+	return next, nil
+}
+
+// SetVal sets the last value of the named sequence. When called is false
+// the next NextVal returns v itself instead of the value after it.
+func (tx *Tx) SetVal(name string, v int64, called bool) error {
+	s := tx.s
+	if s.readOnly {
+		return ErrReadOnlyStore
+	}
+	s.seqMu.Lock()
+	defer s.seqMu.Unlock()
+
+	seq, ok := s.catalog.Sequences[name]
+	if !ok {
+		return fmt.Errorf("%w: %s", ErrSequenceNotFound, name)
+	}
+	prev := *seq
+	seq.Last, seq.Called = v, called
+	delete(s.seqReserved, seq)
+	if err := s.saveCatalog(); err != nil {
+		*seq = prev
+		return err
+	}
+	return nil
+}
+
+// createOwnedSequences registers the sequences of the AUTO_INCREMENT
+// columns of a new table
+func (s *Store) createOwnedSequences(meta *TableMeta) error {
+	for _, c := range meta.Columns {
+		if !c.AutoIncrement {
+			continue
+		}
+		name := OwnedSequence(meta.Name, c.Name)
+		if err := s.checkSequenceName(name); err != nil {
+			return err
+		}
+	}
+	for _, c := range meta.Columns {
+		if c.AutoIncrement {
+			name := OwnedSequence(meta.Name, c.Name)
+			s.catalog.Sequences[name] = &SequenceMeta{
+				Name: name, Start: 1, Increment: 1, Last: 1, Owner: meta.Name + "." + c.Name,
+			}
+		}
+	}
+	return nil
+}
+
+// recoverSequences recreates owned sequences missing from the catalog,
+// for instance after it was rebuilt from the table headers, continuing
+// after the largest value of their column. It reports whether any was
+// recreated.
+func (s *Store) recoverSequences() (bool, error) {
+	recovered := false
+	for _, t := range s.catalog.Tables {
+		for _, c := range t.Columns {
+			name := OwnedSequence(t.Name, c.Name)
+			if !c.AutoIncrement || s.catalog.Sequences[name] != nil {
+				continue
+			}
+			rows, err := s.readRowsUnlocked(t.Name)
+			if err != nil {
+				return false, err
+			}
+			seq := &SequenceMeta{Name: name, Start: 1, Increment: 1, Last: 1, Owner: t.Name + "." + c.Name}
+			for _, row := range rows {
+				if n, ok := row[c.Name].(int64); ok && (!seq.Called || n > seq.Last) {
+					seq.Last, seq.Called = n, true
+				}
+			}
+			s.catalog.Sequences[name] = seq
+			recovered = true
+		}
+	}
+	return recovered, nil
+}
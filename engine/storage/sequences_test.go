@@ -0,0 +1,97 @@
+package storage
+
+import "testing"
+
+func nextVals(t *testing.T, s *Store, name string, n int) []int64 {
+	t.Helper()
+	var vals []int64
+	err := s.Update(func(tx *Tx) error {
+		for range n {
+			v, err := tx.NextVal(name)
+			if err != nil {
+				return err
+			}
+			vals = append(vals, v)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return vals
+}
+
+func TestNextValReservesBlocks(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	err = s.Update(func(tx *Tx) error {
+		return tx.CreateSequence(SequenceMeta{Name: "s", Start: 1, Increment: 1})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nextVals(t, s, "s", 3); got[0] != 1 || got[2] != 3 {
+		t.Fatalf("got %v, want 1 to 3", got)
+	}
+	// another catalog change must not save the sequence below its block
+	err = s.Update(func(tx *Tx) error {
+		return tx.CreateSequence(SequenceMeta{Name: "t", Start: 1, Increment: 1})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nextVals(t, s, "s", 1); got[0] != 4 {
+		t.Fatalf("got %v, want 4", got)
+	}
+	// a crash: the directory is released without closing the store
+	if err := s.unlockDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err = NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if got := nextVals(t, s, "s", 1); got[0] != sequenceBlock+1 {
+		t.Fatalf("after reopening got %v, want %d", got, sequenceBlock+1)
+	}
+	err = s.Update(func(tx *Tx) error {
+		seq, _ := tx.Sequence("s")
+		if seq.Last != sequenceBlock+1 {
+			t.Errorf("Last = %d, want the value handed out", seq.Last)
+		}
+		return tx.SetVal("s", 10, true)
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nextVals(t, s, "s", 1); got[0] != 11 {
+		t.Fatalf("after setval got %v, want 11", got)
+	}
+}
+
+func TestCloseGivesBackReservedValues(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir)
+	err := s.Update(func(tx *Tx) error {
+		return tx.CreateSequence(SequenceMeta{Name: "down", Start: -1, Increment: -2})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := nextVals(t, s, "down", 2); got[1] != -3 {
+		t.Fatalf("got %v, want -1, -3", got)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openStore(t, dir)
+	if got := nextVals(t, s, "down", 1); got[0] != -5 {
+		t.Fatalf("after a clean restart got %v, want -5", got)
+	}
+}
@@ -1,6 +1,7 @@
 package storage
 
 import (
+	"bufio"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -8,22 +9,132 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 )
 
 type Store struct {
 	baseDir string
 	mu      sync.RWMutex
+	catalog *Catalog
+
+	idxMu      sync.Mutex
+	indexCache map[string]*memIndex // see index.go
+
+	eval Evaluator // evaluates stored expressions, see SetEvaluator
+
+	// seqMu serializes sequence changes, which read-only transactions
+	// make too, and guards seqReserved, the last value of the block of
+	// each sequence NextVal may hand out without a catalog write
+	seqMu       sync.Mutex
+	seqReserved map[*SequenceMeta]int64
+
+	changes *changeLog // row changes for subscribers, see changes.go
+
+	// vacMu serializes vacuums and guards vacuumed, the table files as the
+	// last vacuum left them, and autoVacuum, see vacuum.go
+	vacMu      sync.Mutex
+	vacuumed   map[string]os.FileInfo
+	autoVacuum *autoVacuum
+
+	readOnly bool       // see Options.ReadOnly
+	locks    []*os.File // locked while the store is open, see lock.go
+
+	pool *bufferPool // pages of table files, see bufferpool.go
+
+	undo *undoLog // of the running Update, see tx.go
+}
+
+// Options configure how OpenStore opens a data directory
+type Options struct {
+	// ReadOnly opens an existing data directory without changing it, next
+	// to other read-only stores
+	ReadOnly bool
+	// BusyTimeout is how long to wait for other processes to release the
+	// data directory before failing with ErrLocked; 0 fails right away
+	BusyTimeout time.Duration
+	// CacheSize is the memory budget of the buffer pool in bytes; 0 means
+	// DefaultCacheSize
+	CacheSize int64
 }
 
+// NewStore opens baseDir for reading and writing, creating it if needed
 func NewStore(baseDir string) (*Store, error) {
-	if err := os.MkdirAll(baseDir, 0o755); err != nil {
+	return OpenStore(baseDir, Options{})
+}
+
+// OpenStore opens baseDir as opts ask. It fails with ErrLocked when another
+// process has the directory open for writing, or for reading when opts ask
+// for writing, and does not let go for opts.BusyTimeout.
+func OpenStore(baseDir string, opts Options) (*Store, error) {
+	if opts.ReadOnly {
+		if _, err := os.Stat(baseDir); err != nil {
+			return nil, err
+		}
+	} else if err := os.MkdirAll(baseDir, 0o755); err != nil {
+		return nil, err
+	}
+	s := &Store{
+		baseDir:     filepath.Clean(baseDir),
+		seqReserved: map[*SequenceMeta]int64{},
+		vacuumed:    map[string]os.FileInfo{},
+		readOnly:    opts.ReadOnly,
+		pool:        newBufferPool(opts.CacheSize),
+	}
+	if err := s.lockDir(opts); err != nil {
+		return nil, err
+	}
+	if err := s.loadCatalog(); err != nil {
+		s.unlockDir()
 		return nil, err
 	}
-	return &Store{baseDir: filepath.Clean(baseDir)}, nil
+	if err := s.openChanges(); err != nil {
+		s.unlockDir()
+		return nil, err
+	}
+	if !s.readOnly {
+		if err := s.recoverUndo(); err != nil {
+			s.unlockDir()
+			return nil, err
+		}
+	}
+	s.SetAutoVacuum(DefaultAutoVacuumInterval)
+	return s, nil
+}
+
+// ReadOnly reports whether the store was opened read-only
+func (s *Store) ReadOnly() bool {
+	return s.readOnly
 }
 
+// Close stops automatic vacuuming, ends the subscriptions of the store,
+// gives back the unused values of reserved sequence blocks and releases the
+// data directory to other processes
 func (s *Store) Close() error {
-	return nil
+	s.SetAutoVacuum(0)
+	c := s.changes
+	c.mu.Lock()
+	subs := make([]*Subscription, 0, len(c.subs))
+	for sub := range c.subs {
+		subs = append(subs, sub)
+	}
+	c.mu.Unlock()
+	for _, sub := range subs {
+		sub.Close()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.seqMu.Lock()
+	var err error
+	if len(s.seqReserved) > 0 {
+		// save the values actually handed out; only a crash skips values
+		clear(s.seqReserved)
+		err = s.saveCatalog()
+	}
+	s.seqMu.Unlock()
+	if uerr := s.unlockDir(); err == nil {
+		err = uerr
+	}
+	return err
 }
 
 func (s *Store) tablePath(name string) string {
@@ -32,15 +143,76 @@ func (s *Store) tablePath(name string) string {
 
 // ColumnDefinition represents a column in the table schema
 type ColumnDefinition struct {
-	Name string
-	Type string
+	Name       string
+	Type       string
+	NotNull    bool   `json:"NotNull,omitempty"`
+	PrimaryKey bool   `json:"PrimaryKey,omitempty"`
+	Default    string `json:"Default,omitempty"` // SQL expression filling omitted values
+	// AutoIncrement columns default to the next value of the sequence
+	// OwnedSequence(table, column)
+	AutoIncrement bool `json:"AutoIncrement,omitempty"`
+	// Generated is the SQL expression of a generated column, whose value
+	// is computed when a row is written if Stored and else when it is read
+	Generated string `json:"Generated,omitempty"`
+	Stored    bool   `json:"Stored,omitempty"`
 }
 
-// CreateTable writes a schema file (very simple JSON header)
-func (s *Store) CreateTable(name string, cols []ColumnDefinition) error {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
+// Virtual reports whether the column is computed whenever it is read, and
+// never stored
+func (c ColumnDefinition) Virtual() bool {
+	return c.Generated != "" && !c.Stored
+}
+
+// CreateTable writes a schema file (very simple JSON header) and registers
+// the table in the catalog. Indexes describe the PRIMARY KEY/UNIQUE
+// constraints of the table and are enforced on every write.
+func (tx *Tx) CreateTable(name string, cols []ColumnDefinition, indexes ...IndexMeta) error {
+	return tx.CreateTableMeta(TableMeta{Name: name, Columns: cols, Indexes: indexes})
+}
+
+// CreateTableMeta creates a table from its complete schema, which may
+// include constraints beyond indexes, see CreateTable
+func (tx *Tx) CreateTableMeta(m TableMeta) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+	name := m.Name
 
+	if IsSystemTable(name) {
+		return fmt.Errorf("table name %s is reserved for system tables", name)
+	}
+	if _, ok := s.catalog.Views[name]; ok {
+		return fmt.Errorf("view %s already exists", name)
+	}
+	if _, ok := s.catalog.Sequences[name]; ok {
+		return fmt.Errorf("sequence %s already exists", name)
+	}
+	meta := &m
+	if err := meta.validateIndexes(); err != nil {
+		return err
+	}
+	if err := s.validateForeignKeys(meta); err != nil {
+		return err
+	}
+	if err := meta.validateChecks(); err != nil {
+		return err
+	}
+	if err := s.createTableFile(meta); err != nil {
+		return err
+	}
+	if err := s.createOwnedSequences(meta); err != nil {
+		s.pool.drop(s.tablePath(name))
+		os.Remove(s.tablePath(name))
+		return err
+	}
+	s.catalog.Tables[name] = meta
+	return s.saveCatalog()
+}
+
+// createTableFile creates the file of a new table holding just its header
+func (s *Store) createTableFile(meta *TableMeta) error {
+	name := meta.Name
 	p := s.tablePath(name)
 	f, err := os.OpenFile(p, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
 	if err != nil {
@@ -51,67 +223,165 @@ func (s *Store) CreateTable(name string, cols []ColumnDefinition) error {
 	}
 	defer f.Close()
 
-	head := map[string]any{"columns": cols}
+	return json.NewEncoder(f).Encode(tableHeader(meta))
+}
 
-	enc := json.NewEncoder(f)
-	if err := enc.Encode(head); err != nil {
-		return err
+// tableHeader is the first line of a table file, mirroring its catalog entry
+func tableHeader(meta *TableMeta) map[string]any {
+	h := map[string]any{"columns": meta.Columns, "indexes": meta.Indexes}
+	if len(meta.ForeignKeys) > 0 {
+		h["foreign_keys"] = meta.ForeignKeys
 	}
-
-	return nil
+	if len(meta.Checks) > 0 {
+		h["checks"] = meta.Checks
+	}
+	return h
 }
 
 // AppendRow appends a JSON-encoded row as a single line and returns the row ID
 func (s *Store) AppendRow(table string, row map[string]any) (int64, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
-
-	// Read current rows to get next ID
-	rows, err := s.scanTableUnlocked(table)
+	ids, err := s.AppendRows(table, []map[string]any{row})
 	if err != nil {
 		return 0, err
 	}
-	nextID := int64(len(rows) + 1)
+	return ids[0], nil
+}
+
+// AppendRows appends all rows with a single write and returns their row IDs
+func (tx *Tx) AppendRows(table string, rows []map[string]any) ([]int64, error) {
+	if err := tx.writable(); err != nil {
+		return nil, err
+	}
+	s := tx.s
+
+	if IsSystemTable(table) {
+		return nil, fmt.Errorf("%w: %s", ErrReadOnlyTable, table)
+	}
+
+	// Read current rows once to get the first ID and check constraints
+	existing, err := s.scanTableUnlocked(table)
+	if err != nil {
+		return nil, err
+	}
+	nextID := int64(len(existing) + 1)
 
+	meta := s.catalog.Tables[table]
+	sets, err := newUniqueSets(meta, existing)
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		if err := s.prepareRow(meta, row); err != nil {
+			return nil, err
+		}
+		if err := sets.add(row, len(existing)); err != nil {
+			return nil, err
+		}
+	}
+	if len(meta.ForeignKeys) > 0 {
+		w := s.newFKWrite()
+		w.rows[table] = append(existing, rows...)
+		w.check[table] = rows
+		if err := w.verify(); err != nil {
+			return nil, err
+		}
+	}
+
+	ids := make([]int64, len(rows))
+	for i := range rows {
+		ids[i] = nextID + int64(i)
+	}
+	if err := s.appendUnlocked(table, rows); err != nil {
+		return nil, err
+	}
+	return ids, s.logChanges(insertChanges(table, rows))
+}
+
+// appendUnlocked writes rows to the end of the table file with a single write
+func (s *Store) appendUnlocked(table string, rows []map[string]any) error {
+	if len(rows) == 0 {
+		return nil
+	}
+
+	// Encode everything up front so a bad row doesn't leave a partial write
+	meta := s.catalog.Tables[table]
+	var buf []byte
+	for _, row := range rows {
+		b, err := encodeRow(meta, row)
+		if err != nil {
+			return err
+		}
+		buf = append(buf, b...)
+		buf = append(buf, '\n')
+	}
+
+	if err := s.beforeWrite(table); err != nil {
+		return err
+	}
+	s.dropIndexCache(table)
 	p := s.tablePath(table)
-	f, err := os.OpenFile(p, os.O_APPEND|os.O_WRONLY, 0o644)
+	f, err := os.OpenFile(p, os.O_RDWR, 0o644)
 	if err != nil {
-		return 0, err
+		return err
 	}
 	defer f.Close()
-
-	b, err := json.Marshal(row)
+	fi, err := f.Stat()
 	if err != nil {
-		return 0, err
+		return err
 	}
-	if _, err := f.Write(append(b, '\n')); err != nil {
-		return 0, err
+
+	// the pages the rows land on are written back together
+	err = s.pool.write(p, fi.Size(), buf, f.ReadAt)
+	if err == nil {
+		err = s.pool.flush(p, f)
 	}
-	return nextID, nil
+	if err != nil {
+		s.pool.drop(p)
+	}
+	return err
 }
 
 // ScanTable naive reads and returns array of rows (as map[string]any)
-func (s *Store) ScanTable(table string) ([]map[string]any, error) {
-	s.mu.RLock()
-	defer s.mu.RUnlock()
-	return s.scanTableUnlocked(table)
+func (tx *Tx) ScanTable(table string) ([]map[string]any, error) {
+	return tx.s.scanTableUnlocked(table)
 }
 
 // scanTableUnlocked is the internal implementation without locking
 func (s *Store) scanTableUnlocked(table string) ([]map[string]any, error) {
-	p := s.tablePath(table)
-	f, err := os.Open(p)
+	if rows, ok := s.systemRowsUnlocked(table); ok {
+		return rows, nil
+	}
+	rows, err := s.readRowsUnlocked(table)
 	if err != nil {
 		return nil, err
 	}
-	defer f.Close()
+	meta := s.catalog.Tables[table]
+	for _, row := range rows {
+		if err := s.computeGenerated(meta, row, true); err != nil {
+			return nil, err
+		}
+	}
+	return rows, nil
+}
+
+// readRowsUnlocked returns the rows of a table as stored, without their
+// virtual columns
+func (s *Store) readRowsUnlocked(table string) ([]map[string]any, error) {
+	meta, ok := s.catalog.Tables[table]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ErrTableNotFound, table)
+	}
+	r := s.readPages(table)
+	defer r.Close()
 
-	dec := json.NewDecoder(f)
+	dec := json.NewDecoder(r)
 	// First token is header
 	var header map[string]any
 	if err := dec.Decode(&header); err != nil {
 		return nil, err
 	}
+	// Keep integers as int64 instead of float64 so arithmetic stays exact
+	dec.UseNumber()
 	rows := make([]map[string]any, 0)
 	for {
 		var m map[string]any
@@ -122,166 +392,208 @@ func (s *Store) scanTableUnlocked(table string) ([]map[string]any, error) {
 			}
 			break
 		}
+		decodeValues(meta, m)
 		rows = append(rows, m)
 	}
 	return rows, nil
 }
 
-// compareValues compares two values, handling type conversions (e.g., int64 vs float64 from JSON)
-func compareValues(a, b any) bool {
-	if a == b {
-		return true
-	}
-
-	// Handle numeric comparisons (JSON unmarshals numbers as float64)
-	switch v1 := a.(type) {
-	case float64:
-		switch v2 := b.(type) {
-		case int64:
-			return v1 == float64(v2)
-		case int:
-			return v1 == float64(v2)
-		}
-	case int64:
-		switch v2 := b.(type) {
-		case float64:
-			return float64(v1) == v2
-		case int:
-			return v1 == int64(v2)
-		}
-	case int:
-		switch v2 := b.(type) {
-		case float64:
-			return float64(v1) == v2
-		case int64:
-			return int64(v1) == v2
+// decodeValues gives the values of a row decoded with UseNumber the types
+// rows are used with
+func decodeValues(meta *TableMeta, row map[string]any) {
+	for k, v := range row {
+		if n, ok := v.(json.Number); ok {
+			row[k] = normalizeNumber(n)
 		}
 	}
+	decodeTemporal(meta, row)
+}
 
-	return false
+// normalizeNumber converts a decoded JSON number to int64 when it is
+// integral and to float64 otherwise
+func normalizeNumber(n json.Number) any {
+	if i, err := n.Int64(); err == nil {
+		return i
+	}
+	f, _ := n.Float64()
+	return f
 }
 
-// UpdateRows updates rows matching the where condition and returns count of updated rows
-func (s *Store) UpdateRows(table string, set map[string]any, whereCol string, whereVal any) (int, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// RowFilter decides whether a row takes part in an UPDATE or DELETE
+type RowFilter func(row map[string]any) (bool, error)
+
+// RowUpdate returns the new version of a matched row
+type RowUpdate func(row map[string]any) (map[string]any, error)
+
+// UpdateRows replaces every row accepted by match (all rows when match is
+// nil) with the result of update and returns the updated rows. Constraints
+// are checked against the final table before anything is written.
+func (tx *Tx) UpdateRows(table string, match RowFilter, update RowUpdate) ([]map[string]any, error) {
+	if err := tx.writable(); err != nil {
+		return nil, err
+	}
+	s := tx.s
+
+	if IsSystemTable(table) {
+		return nil, fmt.Errorf("%w: %s", ErrReadOnlyTable, table)
+	}
 
 	rows, err := s.scanTableUnlocked(table)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	updated := 0
-	for _, row := range rows {
-		// If no where clause, update all rows
-		if whereCol == "" {
-			for k, v := range set {
-				row[k] = v
+	var olds, updated []map[string]any
+	for i, row := range rows {
+		if match != nil {
+			ok, err := match(row)
+			if err != nil {
+				return nil, err
 			}
-			updated++
-		} else {
-			// Check if row matches where condition
-			if rowVal, ok := row[whereCol]; ok && compareValues(rowVal, whereVal) {
-				for k, v := range set {
-					row[k] = v
-				}
-				updated++
+			if !ok {
+				continue
 			}
 		}
+		newRow, err := update(copyRow(row))
+		if err != nil {
+			return nil, err
+		}
+		rows[i] = newRow
+		olds, updated = append(olds, row), append(updated, newRow)
 	}
 
-	// Rewrite the entire table file
-	if err := s.rewriteTable(table, rows); err != nil {
-		return 0, err
+	// Re-check constraints against the final table contents
+	meta := s.catalog.Tables[table]
+	for _, row := range updated {
+		if err := s.prepareRow(meta, row); err != nil {
+			return nil, err
+		}
+	}
+	if _, err := newUniqueSets(meta, rows); err != nil {
+		return nil, err
+	}
+
+	// Rewrite the table file, and those changed by foreign key actions
+	w := s.newFKWrite()
+	w.set(table, rows)
+	w.check[table] = updated
+	for i, row := range updated {
+		w.record(OpUpdate, table, olds[i], row)
+	}
+	if err := w.updated(table, olds, updated); err != nil {
+		return nil, err
+	}
+	if err := w.commit(); err != nil {
+		return nil, err
 	}
 
 	return updated, nil
 }
 
-// DeleteRows deletes rows matching the where condition and returns count of deleted rows
-func (s *Store) DeleteRows(table string, whereCol string, whereVal any) (int, error) {
-	s.mu.Lock()
-	defer s.mu.Unlock()
+// DeleteRows deletes every row accepted by match (all rows when match is
+// nil) and returns the deleted rows
+func (tx *Tx) DeleteRows(table string, match RowFilter) ([]map[string]any, error) {
+	if err := tx.writable(); err != nil {
+		return nil, err
+	}
+	s := tx.s
+
+	if IsSystemTable(table) {
+		return nil, fmt.Errorf("%w: %s", ErrReadOnlyTable, table)
+	}
 
 	rows, err := s.scanTableUnlocked(table)
 	if err != nil {
-		return 0, err
+		return nil, err
 	}
 
-	var newRows []map[string]any
-	deleted := 0
-
+	var newRows, deleted []map[string]any
 	for _, row := range rows {
-		// If no where clause, delete all rows
-		if whereCol == "" {
-			deleted++
-			continue
-		}
-		// Check if row matches where condition
-		if rowVal, ok := row[whereCol]; ok && compareValues(rowVal, whereVal) {
-			deleted++
-			continue
+		if match != nil {
+			ok, err := match(row)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				newRows = append(newRows, row)
+				continue
+			}
 		}
-		newRows = append(newRows, row)
+		deleted = append(deleted, row)
 	}
 
-	// Rewrite the entire table file
-	if err := s.rewriteTable(table, newRows); err != nil {
-		return 0, err
+	// Rewrite the table file, and those changed by foreign key actions
+	w := s.newFKWrite()
+	w.set(table, newRows)
+	for _, row := range deleted {
+		w.record(OpDelete, table, row, nil)
+	}
+	if err := w.deleted(table, deleted); err != nil {
+		return nil, err
+	}
+	if err := w.commit(); err != nil {
+		return nil, err
 	}
 
 	return deleted, nil
 }
 
-// rewriteTable rewrites the entire table file with new rows
+// rewriteTable rewrites the entire table file with new rows, taking the
+// header from the catalog
 func (s *Store) rewriteTable(table string, rows []map[string]any) error {
-	p := s.tablePath(table)
-
-	// Read the header first
-	f, err := os.Open(p)
-	if err != nil {
-		return err
-	}
-	dec := json.NewDecoder(f)
-	var header map[string]any
-	if err := dec.Decode(&header); err != nil {
-		f.Close()
+	if err := s.beforeWrite(table); err != nil {
 		return err
 	}
-	f.Close()
+	s.dropIndexCache(table)
+	p := s.tablePath(table)
 
 	// Write to temp file
 	tmpPath := p + ".tmp"
-	tmpFile, err := os.Create(tmpPath)
-	if err != nil {
+	if _, err := writeTableFile(tmpPath, s.catalog.Tables[table], rows); err != nil {
 		return err
 	}
 
-	enc := json.NewEncoder(tmpFile)
-	// Write header
-	if err := enc.Encode(header); err != nil {
-		tmpFile.Close()
-		os.Remove(tmpPath)
-		return err
-	}
+	// Replace original file with temp file
+	defer s.pool.drop(p)
+	return os.Rename(tmpPath, p)
+}
 
-	// Write all rows
+// writeTableFile writes the header of meta and rows to a new file at path
+// and returns its size. The file is removed again if writing fails.
+func writeTableFile(path string, meta *TableMeta, rows []map[string]any) (int64, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return 0, err
+	}
+	w := bufio.NewWriter(f)
+	var size int64
+	write := func(v []byte) {
+		if err == nil {
+			var n int
+			n, err = w.Write(append(v, '\n'))
+			size += int64(n)
+		}
+	}
+	header, err := json.Marshal(tableHeader(meta))
+	write(header)
 	for _, row := range rows {
-		b, err := json.Marshal(row)
 		if err != nil {
-			tmpFile.Close()
-			os.Remove(tmpPath)
-			return err
+			break
 		}
-		if _, err := tmpFile.Write(append(b, '\n')); err != nil {
-			tmpFile.Close()
-			os.Remove(tmpPath)
-			return err
+		var b []byte
+		if b, err = encodeRow(meta, row); err == nil {
+			write(b)
 		}
 	}
-
-	tmpFile.Close()
-
-	// Replace original file with temp file
-	return os.Rename(tmpPath, p)
+	if err == nil {
+		err = w.Flush()
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(path)
+		return 0, err
+	}
+	return size, nil
 }
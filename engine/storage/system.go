@@ -0,0 +1,169 @@
+package storage
+
+import "strings"
+
+// Virtual system tables exposing the catalog through regular SELECTs
+const (
+	SysTables    = "nalar_tables"
+	SysColumns   = "nalar_columns"
+	SysIndexes   = "nalar_indexes"
+	SysViews     = "nalar_views"
+	SysSequences = "nalar_sequences"
+	SysTriggers  = "nalar_triggers"
+	SysCache     = "nalar_cache"
+)
+
+// systemTables holds the schema of every virtual table
+var systemTables = map[string]TableMeta{
+	SysTables: {Name: SysTables, Columns: []ColumnDefinition{
+		{Name: "table_name", Type: "TEXT"},
+		{Name: "column_count", Type: "INTEGER"},
+	}},
+	SysColumns: {Name: SysColumns, Columns: []ColumnDefinition{
+		{Name: "table_name", Type: "TEXT"},
+		{Name: "column_name", Type: "TEXT"},
+		{Name: "ordinal_position", Type: "INTEGER"},
+		{Name: "data_type", Type: "TEXT"},
+		{Name: "not_null", Type: "BOOLEAN"},
+		{Name: "primary_key", Type: "BOOLEAN"},
+		{Name: "column_default", Type: "TEXT"},
+		{Name: "generation_expression", Type: "TEXT"},
+	}},
+	SysIndexes: {Name: SysIndexes, Columns: []ColumnDefinition{
+		{Name: "index_name", Type: "TEXT"},
+		{Name: "table_name", Type: "TEXT"},
+		{Name: "columns", Type: "TEXT"},
+		{Name: "is_unique", Type: "BOOLEAN"},
+	}},
+	SysViews: {Name: SysViews, Columns: []ColumnDefinition{
+		{Name: "view_name", Type: "TEXT"},
+		{Name: "definition", Type: "TEXT"},
+		{Name: "materialized", Type: "BOOLEAN"},
+	}},
+	SysSequences: {Name: SysSequences, Columns: []ColumnDefinition{
+		{Name: "sequence_name", Type: "TEXT"},
+		{Name: "start_value", Type: "INTEGER"},
+		{Name: "increment", Type: "INTEGER"},
+		{Name: "last_value", Type: "INTEGER"},
+		{Name: "owned_by", Type: "TEXT"},
+	}},
+	SysTriggers: {Name: SysTriggers, Columns: []ColumnDefinition{
+		{Name: "trigger_name", Type: "TEXT"},
+		{Name: "table_name", Type: "TEXT"},
+		{Name: "timing", Type: "TEXT"},
+		{Name: "event", Type: "TEXT"},
+		{Name: "body", Type: "TEXT"},
+	}},
+	SysCache: {Name: SysCache, Columns: []ColumnDefinition{
+		{Name: "capacity_pages", Type: "INTEGER"},
+		{Name: "cached_pages", Type: "INTEGER"},
+		{Name: "dirty_pages", Type: "INTEGER"},
+		{Name: "hits", Type: "INTEGER"},
+		{Name: "misses", Type: "INTEGER"},
+		{Name: "evictions", Type: "INTEGER"},
+	}},
+}
+
+// IsSystemTable reports whether name is reserved for catalog tables
+func IsSystemTable(name string) bool {
+	return strings.HasPrefix(strings.ToLower(name), "nalar_")
+}
+
+// systemRowsUnlocked materializes a system table from the catalog.
+// The second result is false when name is not a known system table.
+func (s *Store) systemRowsUnlocked(name string) ([]map[string]any, bool) {
+	tables := s.tablesUnlocked()
+	rows := make([]map[string]any, 0)
+
+	switch strings.ToLower(name) {
+	case SysTables:
+		for _, t := range tables {
+			rows = append(rows, map[string]any{
+				"table_name":   t.Name,
+				"column_count": int64(len(t.Columns)),
+			})
+		}
+	case SysColumns:
+		for _, t := range tables {
+			for i, c := range t.Columns {
+				row := map[string]any{
+					"table_name":            t.Name,
+					"column_name":           c.Name,
+					"ordinal_position":      int64(i + 1),
+					"data_type":             c.Type,
+					"not_null":              c.NotNull,
+					"primary_key":           c.PrimaryKey,
+					"column_default":        nil,
+					"generation_expression": nil,
+				}
+				if c.Default != "" {
+					row["column_default"] = c.Default
+				}
+				if c.Generated != "" {
+					row["generation_expression"] = c.Generated
+				}
+				rows = append(rows, row)
+			}
+		}
+	case SysIndexes:
+		for _, t := range tables {
+			for _, idx := range t.Indexes {
+				rows = append(rows, map[string]any{
+					"index_name": idx.Name,
+					"table_name": t.Name,
+					"columns":    strings.Join(idx.Columns, ","),
+					"is_unique":  idx.Unique,
+				})
+			}
+		}
+	case SysViews:
+		for _, v := range s.viewsUnlocked() {
+			rows = append(rows, map[string]any{
+				"view_name":    v.Name,
+				"definition":   v.Query,
+				"materialized": v.Materialized,
+			})
+		}
+	case SysSequences:
+		for _, seq := range s.sequencesUnlocked() {
+			// last_value stays NULL until the first value is handed out
+			row := map[string]any{
+				"sequence_name": seq.Name,
+				"start_value":   seq.Start,
+				"increment":     seq.Increment,
+				"last_value":    nil,
+				"owned_by":      nil,
+			}
+			if seq.Called {
+				row["last_value"] = seq.Last
+			}
+			if seq.Owner != "" {
+				row["owned_by"] = seq.Owner
+			}
+			rows = append(rows, row)
+		}
+	case SysTriggers:
+		for _, t := range s.triggersUnlocked() {
+			rows = append(rows, map[string]any{
+				"trigger_name": t.Name,
+				"table_name":   t.Table,
+				"timing":       t.Timing,
+				"event":        t.Event,
+				"body":         t.Body,
+			})
+		}
+	case SysCache:
+		st := s.pool.stats()
+		rows = append(rows, map[string]any{
+			"capacity_pages": int64(st.Capacity),
+			"cached_pages":   int64(st.Pages),
+			"dirty_pages":    int64(st.Dirty),
+			"hits":           int64(st.Hits),
+			"misses":         int64(st.Misses),
+			"evictions":      int64(st.Evictions),
+		})
+	default:
+		return nil, false
+	}
+	return rows, true
+}
@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func openStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestSystemTables(t *testing.T) {
+	s := openStore(t, t.TempDir())
+	cols := []ColumnDefinition{
+		{Name: "id", Type: "INTEGER", PrimaryKey: true, NotNull: true},
+		{Name: "name", Type: "TEXT", Default: "'x'"},
+	}
+	if err := s.CreateTable("users", cols, IndexMeta{Name: "users_pkey", Table: "users", Columns: []string{"id"}, Unique: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	rows, err := s.ScanTable(SysTables)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []map[string]any{{"table_name": "users", "column_count": int64(2)}}; !reflect.DeepEqual(rows, want) {
+		t.Fatalf("%s = %v, want %v", SysTables, rows, want)
+	}
+	rows, err = s.ScanTable(SysColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 2 || rows[1]["column_name"] != "name" || rows[1]["ordinal_position"] != int64(2) ||
+		rows[1]["column_default"] != "'x'" || rows[0]["primary_key"] != true {
+		t.Fatalf("%s = %v", SysColumns, rows)
+	}
+	rows, err = s.ScanTable(SysIndexes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := []map[string]any{{"index_name": "users_pkey", "table_name": "users", "columns": "id", "is_unique": true}}; !reflect.DeepEqual(rows, want) {
+		t.Fatalf("%s = %v, want %v", SysIndexes, rows, want)
+	}
+
+	if _, err := s.AppendRows(SysTables, []map[string]any{{"table_name": "x"}}); !errors.Is(err, ErrReadOnlyTable) {
+		t.Fatalf("writing a system table: err = %v, want %v", err, ErrReadOnlyTable)
+	}
+	if err := s.CreateTable("nalar_mine", cols); err == nil {
+		t.Fatal("created a table with the reserved nalar_ prefix")
+	}
+}
+
+func TestCatalogRebuiltFromHeaders(t *testing.T) {
+	dir := t.TempDir()
+	s, err := NewStore(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := s.CreateTable("t", []ColumnDefinition{{Name: "id", Type: "INTEGER"}, {Name: "v", Type: "TEXT"}}); err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+	if err := os.Remove(filepath.Join(dir, "catalog.json")); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openStore(t, dir)
+	err = s.View(func(tx *Tx) error {
+		meta, ok := tx.Table("t")
+		if !ok || len(meta.Columns) != 2 || meta.Columns[1].Name != "v" {
+			t.Fatalf("rebuilt table = %+v, %v", meta, ok)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "catalog.json")); err != nil {
+		t.Fatalf("catalog not saved again: %v", err)
+	}
+}
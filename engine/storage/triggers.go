@@ -0,0 +1,81 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+)
+
+// TriggerMeta describes a row trigger: statements, kept as SQL text, that
+// run for every row an INSERT, UPDATE or DELETE on Table changes
+type TriggerMeta struct {
+	Name   string `json:"name"`
+	Table  string `json:"table"`
+	Timing string `json:"timing"` // BEFORE or AFTER
+	Event  string `json:"event"`  // INSERT, UPDATE or DELETE
+	Body   string `json:"body"`
+}
+
+// Triggers returns the triggers defined on table sorted by name, which is
+// the order they fire in
+func (s *Store) Triggers(table string) []TriggerMeta {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var out []TriggerMeta
+	for _, t := range s.triggersUnlocked() {
+		if t.Table == table {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Trigger returns the named trigger
+func (tx *Tx) Trigger(name string) (TriggerMeta, bool) {
+	if t, ok := tx.s.catalog.Triggers[name]; ok {
+		return *t, true
+	}
+	return TriggerMeta{}, false
+}
+
+func (s *Store) triggersUnlocked() []TriggerMeta {
+	out := make([]TriggerMeta, 0, len(s.catalog.Triggers))
+	for _, t := range s.catalog.Triggers {
+		out = append(out, *t)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// CreateTrigger registers a trigger on an existing user table
+func (tx *Tx) CreateTrigger(t TriggerMeta) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+
+	if IsSystemTable(t.Table) {
+		return fmt.Errorf("%w: %s", ErrReadOnlyTable, t.Table)
+	}
+	if _, ok := s.catalog.Tables[t.Table]; !ok {
+		return fmt.Errorf("%w: %s", ErrTableNotFound, t.Table)
+	}
+	if _, ok := s.catalog.Triggers[t.Name]; ok {
+		return fmt.Errorf("trigger %s already exists", t.Name)
+	}
+	s.catalog.Triggers[t.Name] = &t
+	return s.saveCatalog()
+}
+
+// DropTrigger removes a trigger
+func (tx *Tx) DropTrigger(name string) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+
+	if _, ok := s.catalog.Triggers[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrTriggerNotFound, name)
+	}
+	delete(s.catalog.Triggers, name)
+	return s.saveCatalog()
+}
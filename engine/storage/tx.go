@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+)
+
+// ErrReadOnlyTx is returned by writes attempted inside View
+var ErrReadOnlyTx = errors.New("transaction is read-only")
+
+// Tx gives a statement access to the store while holding its lock: shared
+// for View, exclusive for Update. Everything a statement reads or writes
+// goes through its Tx, so callbacks running inside a write (WHERE clauses,
+// subqueries, conflict handlers) can read other tables without taking the
+// lock again.
+type Tx struct {
+	s        *Store
+	readOnly bool
+}
+
+// View runs fn with a read-only transaction
+func (s *Store) View(fn func(tx *Tx) error) error {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return fn(&Tx{s: s, readOnly: true})
+}
+
+// Update runs fn with a read-write transaction. When fn fails the rows
+// it wrote are taken back, so a statement, with the triggers it fires,
+// changes the tables completely or not at all; so are those of an Update
+// interrupted by a crash, the next time the store is opened for writing.
+// Schema changes and sequences are not taken back.
+func (s *Store) Update(fn func(tx *Tx) error) error {
+	if s.readOnly {
+		return ErrReadOnlyStore
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.undo = &undoLog{undoJournal: undoJournal{LSN: s.LastLSN(), Sizes: map[string]int64{}}}
+	defer func() { s.undo = nil }()
+	if err := fn(&Tx{s: s}); err != nil {
+		s.rollback()
+		return err
+	}
+	return s.commit()
+}
+
+// undoLog records what an Update needs to take back its writes. The first
+// time a table file is written to, its size is noted and a hard link to it
+// (a copy where links are not supported) is made next to it: appends grow
+// the linked file, which is truncated again, and rewrites replace the
+// file, which the link is renamed back over. Change events are held back
+// until the Update succeeds.
+type undoLog struct {
+	undoJournal
+	events []ChangeEvent
+}
+
+// undoJournal is saved as undoFile before an Update first writes to a
+// table and removed once its change events are logged, so that a crash in
+// between leaves what recoverUndo needs to take the writes back
+type undoJournal struct {
+	LSN   uint64           `json:"lsn"`   // of the last event before the Update
+	Sizes map[string]int64 `json:"sizes"` // table -> file size before the first write
+}
+
+const undoFile = "undo.json"
+
+func (s *Store) undoPath(table string) string {
+	return s.tablePath(table) + ".undo"
+}
+
+// beforeWrite is called before the file of table is written to
+func (s *Store) beforeWrite(table string) error {
+	if s.undo == nil {
+		return nil
+	}
+	if _, ok := s.undo.Sizes[table]; ok {
+		return nil
+	}
+	p := s.tablePath(table)
+	fi, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	s.undo.Sizes[table] = fi.Size()
+	if err := s.saveUndoJournal(); err != nil {
+		delete(s.undo.Sizes, table)
+		return err
+	}
+	undo := s.undoPath(table)
+	os.Remove(undo)
+	if err := os.Link(p, undo); err != nil {
+		if err := copyFile(p, undo); err != nil {
+			delete(s.undo.Sizes, table)
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *Store) saveUndoJournal() error {
+	b, err := json.Marshal(s.undo.undoJournal)
+	if err != nil {
+		return err
+	}
+	p := filepath.Join(s.baseDir, undoFile)
+	if err := os.WriteFile(p+".tmp", b, 0o644); err != nil {
+		return err
+	}
+	return os.Rename(p+".tmp", p)
+}
+
+// copyFile copies src to a new file dst
+func copyFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0o644)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		os.Remove(dst)
+		return err
+	}
+	return out.Close()
+}
+
+// rollback restores the table files an Update wrote to
+func (s *Store) rollback() {
+	s.restore(s.undo.Sizes)
+	os.Remove(filepath.Join(s.baseDir, undoFile))
+}
+
+// restore puts back the files of tables, given their sizes before an
+// Update, from their undo links
+func (s *Store) restore(sizes map[string]int64) {
+	for table, size := range sizes {
+		p, undo := s.tablePath(table), s.undoPath(table)
+		cur, err := os.Stat(p)
+		orig, uerr := os.Stat(undo)
+		switch {
+		case err == nil && uerr == nil && os.SameFile(cur, orig):
+			os.Remove(undo)
+		case uerr == nil:
+			os.Rename(undo, p)
+		}
+		os.Truncate(p, size)
+		s.dropIndexCache(table)
+		s.pool.drop(p)
+	}
+}
+
+// commit logs the change events of an Update and keeps its writes
+func (s *Store) commit() error {
+	if err := s.writeChanges(s.undo.events); err != nil {
+		s.rollback()
+		return err
+	}
+	if len(s.undo.Sizes) == 0 {
+		return nil
+	}
+	os.Remove(filepath.Join(s.baseDir, undoFile))
+	for table := range s.undo.Sizes {
+		os.Remove(s.undoPath(table))
+	}
+	return nil
+}
+
+// recoverUndo finishes an Update that a crash interrupted: if its change
+// events were logged it had committed and only its undo links are left
+// over, otherwise its writes are taken back. Called when the store is
+// opened for writing, after the change log.
+func (s *Store) recoverUndo() error {
+	p := filepath.Join(s.baseDir, undoFile)
+	b, err := os.ReadFile(p)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	var j undoJournal
+	if err := json.Unmarshal(b, &j); err != nil {
+		return fmt.Errorf("%s: %w", undoFile, err)
+	}
+	if s.changes.last > j.LSN {
+		for table := range j.Sizes {
+			os.Remove(s.undoPath(table))
+		}
+	} else {
+		s.restore(j.Sizes)
+	}
+	return os.Remove(p)
+}
+
+func (tx *Tx) writable() error {
+	if tx.readOnly {
+		return ErrReadOnlyTx
+	}
+	return nil
+}
+
+// Table returns metadata of a single user or system table
+func (tx *Tx) Table(name string) (TableMeta, bool) {
+	return tx.s.tableUnlocked(name)
+}
+
+// Tables returns metadata of all user tables sorted by name
+func (tx *Tx) Tables() []TableMeta {
+	return tx.s.tablesUnlocked()
+}
+
+// The Store methods below run a single operation in its own transaction.
+
+// CreateTable creates a table, see Tx.CreateTable
+func (s *Store) CreateTable(name string, cols []ColumnDefinition, indexes ...IndexMeta) error {
+	return s.Update(func(tx *Tx) error {
+		return tx.CreateTable(name, cols, indexes...)
+	})
+}
+
+// AppendRows appends rows, see Tx.AppendRows
+func (s *Store) AppendRows(table string, rows []map[string]any) (ids []int64, err error) {
+	err = s.Update(func(tx *Tx) error {
+		ids, err = tx.AppendRows(table, rows)
+		return err
+	})
+	return ids, err
+}
+
+// ScanTable reads all rows of a table, see Tx.ScanTable
+func (s *Store) ScanTable(table string) (rows []map[string]any, err error) {
+	err = s.View(func(tx *Tx) error {
+		rows, err = tx.ScanTable(table)
+		return err
+	})
+	return rows, err
+}
+
+// UpdateRows updates matching rows, see Tx.UpdateRows
+func (s *Store) UpdateRows(table string, match RowFilter, update RowUpdate) (rows []map[string]any, err error) {
+	err = s.Update(func(tx *Tx) error {
+		rows, err = tx.UpdateRows(table, match, update)
+		return err
+	})
+	return rows, err
+}
+
+// DeleteRows deletes matching rows, see Tx.DeleteRows
+func (s *Store) DeleteRows(table string, match RowFilter) (rows []map[string]any, err error) {
+	err = s.Update(func(tx *Tx) error {
+		rows, err = tx.DeleteRows(table, match)
+		return err
+	})
+	return rows, err
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func txStore(t *testing.T, dir string) *Store {
+	t.Helper()
+	s := openStore(t, dir)
+	cols := []ColumnDefinition{{Name: "id", Type: "INTEGER"}}
+	for _, table := range []string{"a", "b"} {
+		if err := s.CreateTable(table, cols); err != nil {
+			t.Fatal(err)
+		}
+		mustAppend(t, s, table, map[string]any{"id": int64(1)})
+	}
+	return s
+}
+
+// writeBoth appends to a and rewrites b
+func writeBoth(tx *Tx) error {
+	if _, err := tx.AppendRows("a", []map[string]any{{"id": int64(2)}}); err != nil {
+		return err
+	}
+	_, err := tx.DeleteRows("b", nil)
+	return err
+}
+
+func expectIDs(t *testing.T, s *Store, table string, want ...int64) {
+	t.Helper()
+	rows, err := s.ScanTable(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []int64
+	for _, r := range rows {
+		got = append(got, r["id"].(int64))
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("%s ids = %v, want %v", table, got, want)
+	}
+}
+
+func expectNoUndo(t *testing.T, dir string) {
+	t.Helper()
+	for _, pattern := range []string{"*.undo", undoFile} {
+		if m, _ := filepath.Glob(filepath.Join(dir, pattern)); len(m) > 0 {
+			t.Fatalf("left behind: %v", m)
+		}
+	}
+}
+
+func TestUpdateRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	s := txStore(t, dir)
+	fail := errors.New("fail")
+	err := s.Update(func(tx *Tx) error {
+		if err := writeBoth(tx); err != nil {
+			return err
+		}
+		return fail
+	})
+	if err != fail {
+		t.Fatalf("Update = %v", err)
+	}
+	expectIDs(t, s, "a", 1)
+	expectIDs(t, s, "b", 1)
+	expectNoUndo(t, dir)
+	if s.LastLSN() != 2 {
+		t.Fatalf("LastLSN = %d, the failed writes were logged", s.LastLSN())
+	}
+
+	if err := s.Update(writeBoth); err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, s, "a", 1, 2)
+	expectIDs(t, s, "b")
+	expectNoUndo(t, dir)
+}
+
+// crash leaves the store as a process dying inside fn would
+func crash(t *testing.T, s *Store, fn func(tx *Tx) error) {
+	t.Helper()
+	func() {
+		defer func() { recover() }()
+		s.Update(func(tx *Tx) error {
+			if err := fn(tx); err != nil {
+				t.Fatal(err)
+			}
+			panic("crash")
+		})
+	}()
+	if err := s.unlockDir(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestOpenTakesBackInterruptedUpdate(t *testing.T) {
+	dir := t.TempDir()
+	s := txStore(t, dir)
+	crash(t, s, writeBoth)
+	if m, _ := filepath.Glob(filepath.Join(dir, "*.undo")); len(m) != 2 {
+		t.Fatalf("undo links after the crash: %v", m)
+	}
+
+	s = openStore(t, dir)
+	expectIDs(t, s, "a", 1)
+	expectIDs(t, s, "b", 1)
+	expectNoUndo(t, dir)
+	if err := s.Update(writeBoth); err != nil {
+		t.Fatal(err)
+	}
+	expectIDs(t, s, "a", 1, 2)
+}
+
+func TestOpenKeepsCommittedUpdate(t *testing.T) {
+	dir := t.TempDir()
+	s := txStore(t, dir)
+	// a crash after the events were logged, before the undo files were
+	// removed
+	j := undoJournal{LSN: s.LastLSN(), Sizes: map[string]int64{}}
+	for _, table := range []string{"a", "b"} {
+		fi, err := os.Stat(s.tablePath(table))
+		if err != nil {
+			t.Fatal(err)
+		}
+		j.Sizes[table] = fi.Size()
+		if err := copyFile(s.tablePath(table), s.undoPath(table)+".orig"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := s.Update(writeBoth); err != nil {
+		t.Fatal(err)
+	}
+	for _, table := range []string{"a", "b"} {
+		if err := os.Rename(s.undoPath(table)+".orig", s.undoPath(table)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	b, _ := json.Marshal(j)
+	if err := os.WriteFile(filepath.Join(dir, undoFile), b, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.unlockDir(); err != nil {
+		t.Fatal(err)
+	}
+
+	s = openStore(t, dir)
+	expectIDs(t, s, "a", 1, 2)
+	expectIDs(t, s, "b")
+	expectNoUndo(t, dir)
+}
@@ -0,0 +1,157 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Temporal values are kept in a canonical UTC form. On disk they are
+// fixed-width ISO-8601 strings, so the file order of equal-width values is
+// chronological as well; scans decode them back into Timestamp and Date.
+
+const (
+	timestampLayout = "2006-01-02T15:04:05.000000Z"
+	dateLayout      = "2006-01-02"
+)
+
+// Timestamp is the value of a TIMESTAMP column
+type Timestamp struct {
+	time.Time
+}
+
+// Date is the value of a DATE column (midnight UTC)
+type Date struct {
+	time.Time
+}
+
+// NewTimestamp normalizes t to UTC with microsecond precision
+func NewTimestamp(t time.Time) Timestamp {
+	return Timestamp{t.UTC().Truncate(time.Microsecond)}
+}
+
+// NewDate truncates t to its calendar day
+func NewDate(t time.Time) Date {
+	y, m, d := t.Date()
+	return Date{time.Date(y, m, d, 0, 0, 0, 0, time.UTC)}
+}
+
+func (t Timestamp) String() string {
+	if t.Nanosecond() == 0 {
+		return t.Format("2006-01-02 15:04:05")
+	}
+	return t.Format("2006-01-02 15:04:05.999999")
+}
+
+func (d Date) String() string {
+	return d.Format(dateLayout)
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + t.Format(timestampLayout) + `"`), nil
+}
+
+func (d Date) MarshalJSON() ([]byte, error) {
+	return []byte(`"` + d.Format(dateLayout) + `"`), nil
+}
+
+// accepted textual forms for timestamps, most specific first
+var timestampLayouts = []string{
+	time.RFC3339Nano,
+	timestampLayout,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05.999999999Z07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02 15:04",
+	dateLayout,
+}
+
+// ParseTimestamp parses the textual forms accepted for TIMESTAMP values
+func ParseTimestamp(s string) (Timestamp, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range timestampLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			return NewTimestamp(t), nil
+		}
+	}
+	return Timestamp{}, fmt.Errorf("invalid input syntax for type timestamp: %q", s)
+}
+
+// ParseDate parses a DATE value; a time of day, if present, is dropped
+func ParseDate(s string) (Date, error) {
+	if t, err := time.Parse(dateLayout, strings.TrimSpace(s)); err == nil {
+		return NewDate(t), nil
+	}
+	ts, err := ParseTimestamp(s)
+	if err != nil {
+		return Date{}, fmt.Errorf("invalid input syntax for type date: %q", s)
+	}
+	return NewDate(ts.Time), nil
+}
+
+// temporalKind classifies a column type as "TIMESTAMP", "DATE" or ""
+func temporalKind(typ string) string {
+	switch strings.ToUpper(typ) {
+	case "TIMESTAMP", "DATETIME", "TIMESTAMPTZ":
+		return "TIMESTAMP"
+	case "DATE":
+		return "DATE"
+	}
+	return ""
+}
+
+// coerceRow converts the values of temporal columns to their canonical
+// type, accepting strings and either temporal type as input
+func coerceRow(meta *TableMeta, row map[string]any) error {
+	for _, c := range meta.Columns {
+		kind := temporalKind(c.Type)
+		v, ok := row[c.Name]
+		if kind == "" || !ok || v == nil {
+			continue
+		}
+		var err error
+		switch x := v.(type) {
+		case string:
+			if kind == "DATE" {
+				row[c.Name], err = ParseDate(x)
+			} else {
+				row[c.Name], err = ParseTimestamp(x)
+			}
+		case Timestamp:
+			if kind == "DATE" {
+				row[c.Name] = NewDate(x.Time)
+			}
+		case Date:
+			if kind == "TIMESTAMP" {
+				row[c.Name] = NewTimestamp(x.Time)
+			}
+		default:
+			err = fmt.Errorf("column %s is of type %s and cannot hold %v", c.Name, c.Type, v)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeTemporal turns the canonical strings read from disk back into
+// Timestamp and Date values
+func decodeTemporal(meta *TableMeta, row map[string]any) {
+	for _, c := range meta.Columns {
+		s, ok := row[c.Name].(string)
+		if !ok {
+			continue
+		}
+		switch temporalKind(c.Type) {
+		case "TIMESTAMP":
+			if t, err := ParseTimestamp(s); err == nil {
+				row[c.Name] = t
+			}
+		case "DATE":
+			if d, err := ParseDate(s); err == nil {
+				row[c.Name] = d
+			}
+		}
+	}
+}
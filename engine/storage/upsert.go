@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ConflictFunc resolves an inserted row that collides with an existing one.
+// It receives a copy of the existing row and the proposed (excluded) row and
+// returns the row that replaces the existing one.
+type ConflictFunc func(existing, excluded map[string]any) (map[string]any, error)
+
+// UpsertResult summarizes an UpsertRows call
+type UpsertResult struct {
+	Inserted int
+	Updated  int
+	Rows     []map[string]any // inserted and updated rows, in input order
+	Olds     []map[string]any // the row each of Rows replaced, nil if inserted
+}
+
+// UpsertRows inserts rows, resolving collisions on the unique index that
+// covers target. With a nil onConflict colliding rows are skipped
+// (DO NOTHING); an empty target then matches any unique index. The batch is
+// applied atomically: on error nothing is written.
+func (tx *Tx) UpsertRows(table string, rows []map[string]any, target []string, onConflict ConflictFunc) (UpsertResult, error) {
+	if err := tx.writable(); err != nil {
+		return UpsertResult{}, err
+	}
+	s := tx.s
+
+	if IsSystemTable(table) {
+		return UpsertResult{}, fmt.Errorf("%w: %s", ErrReadOnlyTable, table)
+	}
+
+	existing, err := s.scanTableUnlocked(table)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+	meta := s.catalog.Tables[table]
+	sets, err := newUniqueSets(meta, existing)
+	if err != nil {
+		return UpsertResult{}, err
+	}
+
+	var targetSet *uniqueSet
+	if len(target) > 0 {
+		var ok bool
+		if targetSet, ok = sets.find(target); !ok {
+			return UpsertResult{}, fmt.Errorf("%w: %s (%s)", ErrNoConflictTarget, table, strings.Join(target, ", "))
+		}
+	} else if onConflict != nil {
+		return UpsertResult{}, fmt.Errorf("ON CONFLICT DO UPDATE requires a conflict target")
+	}
+
+	var res UpsertResult
+	all := existing
+	var appended, olds, news []map[string]any
+	// Positions of rows this batch inserted or updated; DO UPDATE may not
+	// change a row twice, the outcome would depend on the input order
+	touched := map[int]bool{}
+	for _, row := range rows {
+		if err := s.prepareRow(meta, row); err != nil {
+			return UpsertResult{}, err
+		}
+
+		pos, conflict := -1, false
+		if targetSet != nil {
+			if k, ok := rowKey(row, targetSet.idx.Columns); ok {
+				pos, conflict = targetSet.keys[k]
+			}
+		} else {
+			_, pos, conflict = sets.lookup(row)
+		}
+
+		if !conflict {
+			if err := sets.add(row, len(all)); err != nil {
+				return UpsertResult{}, err
+			}
+			touched[len(all)] = true
+			all = append(all, row)
+			appended = append(appended, row)
+			res.Rows, res.Olds = append(res.Rows, row), append(res.Olds, nil)
+			res.Inserted++
+			continue
+		}
+		if onConflict == nil {
+			continue
+		}
+		if touched[pos] {
+			return UpsertResult{}, ErrRowAffectedTwice
+		}
+		touched[pos] = true
+
+		current := all[pos]
+		newRow, err := onConflict(copyRow(current), row)
+		if err != nil {
+			return UpsertResult{}, err
+		}
+		if err := s.prepareRow(meta, newRow); err != nil {
+			return UpsertResult{}, err
+		}
+		sets.remove(current)
+		if err := sets.add(newRow, pos); err != nil {
+			return UpsertResult{}, err
+		}
+		all[pos] = newRow
+		olds, news = append(olds, current), append(news, newRow)
+		res.Rows, res.Olds = append(res.Rows, newRow), append(res.Olds, current)
+		res.Updated++
+	}
+
+	// Plain inserts only need an append; updates rewrite the table, and
+	// those changed by foreign key actions
+	w := s.newFKWrite()
+	w.rows[table] = all
+	w.check[table] = res.Rows
+	if res.Updated > 0 {
+		for i, row := range res.Rows {
+			if old := res.Olds[i]; old != nil {
+				w.record(OpUpdate, table, old, row)
+			} else {
+				w.record(OpInsert, table, nil, row)
+			}
+		}
+		w.set(table, all)
+		if err := w.updated(table, olds, news); err != nil {
+			return UpsertResult{}, err
+		}
+		return res, w.commit()
+	}
+	if err := w.verify(); err != nil {
+		return UpsertResult{}, err
+	}
+	if err := s.appendUnlocked(table, appended); err != nil {
+		return UpsertResult{}, err
+	}
+	return res, s.logChanges(insertChanges(table, appended))
+}
+
+func copyRow(row map[string]any) map[string]any {
+	out := make(map[string]any, len(row))
+	for k, v := range row {
+		out[k] = v
+	}
+	return out
+}
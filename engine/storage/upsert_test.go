@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestUpsertSameKeyTwice(t *testing.T) {
+	s := openStore(t, t.TempDir())
+	cols := []ColumnDefinition{{Name: "k", Type: "INTEGER"}, {Name: "v", Type: "TEXT"}}
+	if err := s.CreateTable("t", cols, IndexMeta{Name: "t_k", Table: "t", Columns: []string{"k"}, Unique: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AppendRows("t", []map[string]any{{"k": int64(1), "v": "a"}}); err != nil {
+		t.Fatal(err)
+	}
+	replace := func(existing, excluded map[string]any) (map[string]any, error) {
+		existing["v"] = excluded["v"]
+		return existing, nil
+	}
+	upsert := func(rows []map[string]any, fn ConflictFunc) error {
+		return s.Update(func(tx *Tx) error {
+			_, err := tx.UpsertRows("t", rows, []string{"k"}, fn)
+			return err
+		})
+	}
+
+	for _, rows := range [][]map[string]any{
+		{{"k": int64(1), "v": "b"}, {"k": int64(1), "v": "c"}},
+		{{"k": int64(2), "v": "b"}, {"k": int64(2), "v": "c"}},
+	} {
+		if err := upsert(rows, replace); !errors.Is(err, ErrRowAffectedTwice) {
+			t.Fatalf("upsert %v: %v, want %v", rows, err, ErrRowAffectedTwice)
+		}
+	}
+	rows, err := s.ScanTable("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 || rows[0]["v"] != "a" {
+		t.Fatalf("rows after failed upserts = %v", rows)
+	}
+
+	// DO NOTHING skips the repeated key instead
+	if err := upsert([]map[string]any{{"k": int64(2), "v": "b"}, {"k": int64(2), "v": "c"}}, nil); err != nil {
+		t.Fatal(err)
+	}
+	if rows, _ = s.ScanTable("t"); len(rows) != 2 || rows[1]["v"] != "b" {
+		t.Fatalf("rows after DO NOTHING = %v", rows)
+	}
+}
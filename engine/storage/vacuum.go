@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+)
+
+// Updates and deletes rewrite whole table files, so they leave no dead rows
+// behind and there is no dead space to track. What vacuuming reclaims are
+// the leftovers of a crash: the remains of an append cut short at the end
+// of the file, which reads stop at, and the temporary file or undo link of
+// a write that never completed. It writes the rows again next to the file
+// while readers go on and only takes the write lock to swap the files. The
+// cached indexes of a compacted table, which point at file offsets, are
+// dropped and rebuilt on their next use.
+
+// DefaultAutoVacuumInterval is how often tables changed since they were
+// last vacuumed are compacted in the background
+const DefaultAutoVacuumInterval = 10 * time.Minute
+
+// VacuumResult reports the space taken by the files of a table before and
+// after it was vacuumed
+type VacuumResult struct {
+	Table       string
+	BytesBefore int64
+	BytesAfter  int64
+}
+
+// Reclaimed returns the number of bytes vacuuming freed
+func (r VacuumResult) Reclaimed() int64 {
+	return r.BytesBefore - r.BytesAfter
+}
+
+// autoVacuum runs vacuums in the background, see SetAutoVacuum
+type autoVacuum struct {
+	stop chan struct{}
+	done chan struct{}
+}
+
+// Vacuum compacts the file of table, or of every table and materialized
+// view when table is "". It must not be called inside a transaction.
+func (s *Store) Vacuum(table string) ([]VacuumResult, error) {
+	if s.readOnly {
+		return nil, ErrReadOnlyStore
+	}
+	s.mu.RLock()
+	var names []string
+	if table != "" {
+		if _, ok := s.catalog.Tables[table]; !ok {
+			s.mu.RUnlock()
+			return nil, fmt.Errorf("%w: %s", ErrTableNotFound, table)
+		}
+		names = append(names, table)
+	} else {
+		for name := range s.catalog.Tables {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+	s.mu.RUnlock()
+
+	results := make([]VacuumResult, 0, len(names))
+	for _, name := range names {
+		r, ok, err := s.vacuumTable(name)
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			results = append(results, r)
+		}
+	}
+	return results, nil
+}
+
+// vacuumTable compacts the file of one table and reports false when the
+// table was dropped in the meantime. A table written to while its new file
+// was being built keeps its file and is vacuumed again next time.
+func (s *Store) vacuumTable(name string) (VacuumResult, bool, error) {
+	s.vacMu.Lock()
+	defer s.vacMu.Unlock()
+	r := VacuumResult{Table: name}
+	p := s.tablePath(name)
+	vacPath := p + ".vacuum"
+	os.Remove(vacPath) // left by a vacuum that did not finish
+
+	s.mu.RLock()
+	meta, ok := s.catalog.Tables[name]
+	if !ok {
+		s.mu.RUnlock()
+		return r, false, nil
+	}
+	// rewrites hold the write lock while their temporary file exists, and
+	// so do updates while their undo link does
+	if fi, err := os.Stat(p + ".tmp"); err == nil && os.Remove(p+".tmp") == nil {
+		r.BytesBefore += fi.Size()
+	}
+	os.Remove(s.undoPath(name))
+	before, err := os.Stat(p)
+	if err != nil {
+		s.mu.RUnlock()
+		return r, false, err
+	}
+	r.BytesBefore += before.Size()
+	r.BytesAfter = before.Size()
+	rows, err := s.vacuumRows(name, meta)
+	var size int64
+	if err == nil {
+		size, err = writeTableFile(vacPath, meta, rows)
+	}
+	s.mu.RUnlock()
+	if err != nil {
+		return r, false, err
+	}
+	if size >= before.Size() {
+		os.Remove(vacPath)
+		s.vacuumed[name] = before
+		return r, true, nil
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	cur, err := os.Stat(p)
+	if err != nil || !os.SameFile(cur, before) || cur.Size() != before.Size() {
+		os.Remove(vacPath)
+		if errors.Is(err, os.ErrNotExist) {
+			return r, false, nil
+		}
+		return r, true, err
+	}
+	if err := os.Rename(vacPath, p); err != nil {
+		os.Remove(vacPath)
+		return r, true, err
+	}
+	s.dropIndexCache(name)
+	s.pool.drop(p)
+	r.BytesAfter = size
+	if fi, err := os.Stat(p); err == nil {
+		s.vacuumed[name] = fi
+	}
+	return r, true, nil
+}
+
+// vacuumRows reads the rows of a table to write them again. A line that
+// does not decode is dropped only when no row follows it, as the remains
+// of an append cut short; otherwise the file is damaged in a way vacuuming
+// cannot repair without losing the rows after it, and it fails.
+func (s *Store) vacuumRows(name string, meta *TableMeta) ([]map[string]any, error) {
+	r := s.readPages(name)
+	defer r.Close()
+	br := bufio.NewReader(r)
+	if _, err := br.ReadBytes('\n'); err != nil {
+		return nil, fmt.Errorf("table %s: reading header: %w", name, err)
+	}
+	var rows []map[string]any
+	bad := 0 // the first line that does not decode
+	for n := 2; ; n++ {
+		line, err := br.ReadBytes('\n')
+		if len(bytes.TrimSpace(line)) > 0 {
+			if bad > 0 {
+				return nil, fmt.Errorf("table %s: line %d is damaged and rows follow it, not vacuuming", name, bad)
+			}
+			dec := json.NewDecoder(bytes.NewReader(line))
+			dec.UseNumber()
+			var row map[string]any
+			if dec.Decode(&row) != nil || dec.More() {
+				bad = n
+			} else {
+				decodeValues(meta, row)
+				rows = append(rows, row)
+			}
+		}
+		if errors.Is(err, io.EOF) {
+			return rows, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+// SetAutoVacuum sets how often the tables whose files changed since they
+// were last vacuumed are compacted in the background; interval <= 0 turns
+// automatic vacuuming off. A read-only store is never vacuumed.
+func (s *Store) SetAutoVacuum(interval time.Duration) {
+	s.vacMu.Lock()
+	av := s.autoVacuum
+	s.autoVacuum = nil
+	s.vacMu.Unlock()
+	if av != nil {
+		close(av.stop)
+		<-av.done
+	}
+	if interval <= 0 || s.readOnly {
+		return
+	}
+	av = &autoVacuum{stop: make(chan struct{}), done: make(chan struct{})}
+	s.vacMu.Lock()
+	s.autoVacuum = av
+	s.vacMu.Unlock()
+	go s.runAutoVacuum(av, interval)
+}
+
+func (s *Store) runAutoVacuum(av *autoVacuum, interval time.Duration) {
+	defer close(av.done)
+	t := time.NewTicker(interval)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-av.stop:
+			return
+		}
+		for _, name := range s.changedSinceVacuum() {
+			select {
+			case <-av.stop:
+				return
+			default:
+			}
+			// a failing table is tried again on the next round
+			s.vacuumTable(name)
+		}
+	}
+}
+
+// changedSinceVacuum returns the tables whose file is not the one their
+// last vacuum left
+func (s *Store) changedSinceVacuum() []string {
+	s.mu.RLock()
+	files := make(map[string]os.FileInfo, len(s.catalog.Tables))
+	for name := range s.catalog.Tables {
+		if fi, err := os.Stat(s.tablePath(name)); err == nil {
+			files[name] = fi
+		}
+	}
+	s.mu.RUnlock()
+
+	s.vacMu.Lock()
+	defer s.vacMu.Unlock()
+	var names []string
+	for name, fi := range files {
+		last, ok := s.vacuumed[name]
+		if !ok || !os.SameFile(fi, last) || fi.Size() != last.Size() || !fi.ModTime().Equal(last.ModTime()) {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
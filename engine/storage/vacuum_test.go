@@ -0,0 +1,63 @@
+package storage
+
+import (
+	"os"
+	"testing"
+)
+
+func vacuumStore(t *testing.T, tail string) (*Store, string) {
+	t.Helper()
+	s, err := NewStore(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { s.Close() })
+	if err := s.CreateTable("t", []ColumnDefinition{{Name: "id", Type: "INTEGER"}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.AppendRows("t", []map[string]any{{"id": int64(1)}, {"id": int64(2)}}); err != nil {
+		t.Fatal(err)
+	}
+	f, err := os.OpenFile(s.tablePath("t"), os.O_APPEND|os.O_WRONLY, 0o644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(tail); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	s.pool.drop(s.tablePath("t"))
+	return s, s.tablePath("t")
+}
+
+func TestVacuumTruncatesTornTail(t *testing.T) {
+	s, p := vacuumStore(t, `{"id":3`)
+	before, _ := os.ReadFile(p)
+	res, err := s.Vacuum("t")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res) != 1 || res[0].Reclaimed() != int64(len(`{"id":3`)) {
+		t.Fatalf("results = %+v, want the torn line reclaimed", res)
+	}
+	rows, err := s.ScanTable("t")
+	if err != nil || len(rows) != 2 {
+		t.Fatalf("rows = %v, %v; want 2 rows", rows, err)
+	}
+	after, _ := os.ReadFile(p)
+	if string(after) != string(before[:len(before)-len(`{"id":3`)]) {
+		t.Fatalf("file = %q", after)
+	}
+}
+
+func TestVacuumKeepsRowsAfterDamage(t *testing.T) {
+	s, p := vacuumStore(t, "{\"id\":3\n{\"id\":4}\n")
+	before, _ := os.ReadFile(p)
+	if _, err := s.Vacuum("t"); err == nil {
+		t.Fatal("vacuum dropped a damaged line with a row after it")
+	}
+	after, _ := os.ReadFile(p)
+	if string(after) != string(before) {
+		t.Fatalf("file changed to %q", after)
+	}
+}
@@ -0,0 +1,147 @@
+package storage
+
+import (
+	"fmt"
+	"os"
+	"sort"
+)
+
+// ViewMeta describes a view: a named query, kept as SQL text, that is
+// expanded wherever the view is read. A materialized view also stores its
+// result in a table of the same name, recomputed by RefreshView.
+type ViewMeta struct {
+	Name         string             `json:"name"`
+	Query        string             `json:"query"`
+	Columns      []ColumnDefinition `json:"columns"`
+	Materialized bool               `json:"materialized,omitempty"`
+}
+
+// ViewDef returns the definition of the named view
+func (s *Store) ViewDef(name string) (ViewMeta, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.viewUnlocked(name)
+}
+
+func (s *Store) viewUnlocked(name string) (ViewMeta, bool) {
+	if v, ok := s.catalog.Views[name]; ok {
+		return *v, true
+	}
+	return ViewMeta{}, false
+}
+
+// ViewDef returns the definition of the named view
+func (tx *Tx) ViewDef(name string) (ViewMeta, bool) {
+	return tx.s.viewUnlocked(name)
+}
+
+// Views returns all views sorted by name
+func (s *Store) Views() []ViewMeta {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.viewsUnlocked()
+}
+
+// Views returns all views sorted by name
+func (tx *Tx) Views() []ViewMeta {
+	return tx.s.viewsUnlocked()
+}
+
+func (s *Store) viewsUnlocked() []ViewMeta {
+	out := make([]ViewMeta, 0, len(s.catalog.Views))
+	for _, v := range s.catalog.Views {
+		out = append(out, *v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+// CreateView registers a view. rows is the current result of a
+// materialized view, which becomes the content of its table; it is ignored
+// for plain views.
+func (tx *Tx) CreateView(view ViewMeta, rows []map[string]any) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+
+	if IsSystemTable(view.Name) {
+		return fmt.Errorf("view name %s is reserved for system tables", view.Name)
+	}
+	if _, ok := s.catalog.Views[view.Name]; ok {
+		return fmt.Errorf("view %s already exists", view.Name)
+	}
+	if _, ok := s.catalog.Tables[view.Name]; ok {
+		return fmt.Errorf("table %s already exists", view.Name)
+	}
+	if _, ok := s.catalog.Sequences[view.Name]; ok {
+		return fmt.Errorf("sequence %s already exists", view.Name)
+	}
+	if view.Materialized {
+		meta := &TableMeta{Name: view.Name, Columns: view.Columns}
+		for _, row := range rows {
+			if err := coerceRow(meta, row); err != nil {
+				return err
+			}
+		}
+		if err := s.createTableFile(meta); err != nil {
+			return err
+		}
+		s.catalog.Tables[view.Name] = meta
+		if err := s.appendUnlocked(view.Name, rows); err != nil {
+			delete(s.catalog.Tables, view.Name)
+			s.pool.drop(s.tablePath(view.Name))
+			os.Remove(s.tablePath(view.Name))
+			return err
+		}
+	}
+	s.catalog.Views[view.Name] = &view
+	return s.saveCatalog()
+}
+
+// RefreshView replaces the content of a materialized view with rows, its
+// recomputed result. Unique indexes created on the view are checked.
+func (tx *Tx) RefreshView(name string, rows []map[string]any) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+
+	v, ok := s.catalog.Views[name]
+	if !ok || !v.Materialized {
+		return fmt.Errorf("%s is not a materialized view", name)
+	}
+	meta := s.catalog.Tables[name]
+	for _, row := range rows {
+		if err := coerceRow(meta, row); err != nil {
+			return err
+		}
+	}
+	if _, err := newUniqueSets(meta, rows); err != nil {
+		return err
+	}
+	return s.rewriteTable(name, rows)
+}
+
+// DropView removes a view, and the table of a materialized view
+func (tx *Tx) DropView(name string) error {
+	if err := tx.writable(); err != nil {
+		return err
+	}
+	s := tx.s
+
+	v, ok := s.catalog.Views[name]
+	if !ok {
+		return fmt.Errorf("view %s does not exist", name)
+	}
+	if v.Materialized {
+		s.dropIndexCache(name)
+		s.pool.drop(s.tablePath(name))
+		if err := os.Remove(s.tablePath(name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(s.catalog.Tables, name)
+	}
+	delete(s.catalog.Views, name)
+	return s.saveCatalog()
+}
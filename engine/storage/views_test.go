@@ -0,0 +1,51 @@
+package storage
+
+import (
+	"os"
+	"testing"
+)
+
+func TestViewsPersist(t *testing.T) {
+	dir := t.TempDir()
+	s := openStore(t, dir)
+	cols := []ColumnDefinition{{Name: "customer", Type: "TEXT"}, {Name: "total", Type: "INTEGER"}}
+	err := s.Update(func(tx *Tx) error {
+		if err := tx.CreateView(ViewMeta{Name: "v", Query: "SELECT 1 AS one", Columns: []ColumnDefinition{{Name: "one", Type: "INTEGER"}}}, nil); err != nil {
+			return err
+		}
+		return tx.CreateView(ViewMeta{Name: "m", Query: "SELECT customer, total FROM orders", Columns: cols, Materialized: true},
+			[]map[string]any{{"customer": "ann", "total": int64(3)}})
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Close()
+
+	s = openStore(t, dir)
+	if v, ok := s.ViewDef("v"); !ok || v.Query != "SELECT 1 AS one" || v.Materialized {
+		t.Fatalf("view v after reopen = %+v, %v", v, ok)
+	}
+	rows, err := s.ScanTable("m")
+	if err != nil || len(rows) != 1 || rows[0]["total"] != int64(3) {
+		t.Fatalf("materialized rows after reopen = %v, %v", rows, err)
+	}
+
+	err = s.Update(func(tx *Tx) error {
+		if err := tx.RefreshView("v", nil); err == nil {
+			t.Error("refreshed a plain view")
+		}
+		if err := tx.RefreshView("m", []map[string]any{{"customer": "bob", "total": int64(5)}}); err != nil {
+			return err
+		}
+		return tx.DropView("m")
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := s.ViewDef("m"); ok {
+		t.Fatal("view m still defined")
+	}
+	if _, err := os.Stat(s.tablePath("m")); !os.IsNotExist(err) {
+		t.Fatalf("table file of m left behind: %v", err)
+	}
+}
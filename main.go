@@ -2,6 +2,7 @@ package main
 
 import (
 	"bufio"
+	"flag"
 	"fmt"
 	"os"
 	"strings"
@@ -22,13 +23,22 @@ const (
 )
 
 func main() {
+	readOnly := flag.Bool("readonly", false, "open the database for queries only, next to other readers")
+	busyTimeout := flag.Duration("busy-timeout", 0, "how long to wait for another process to release the database")
+	flag.Parse()
+
 	// Initialize engine
-	e, err := engine.NewEngine(".data")
+	opts := []engine.Option{engine.BusyTimeout(*busyTimeout)}
+	if *readOnly {
+		opts = append(opts, engine.ReadOnly())
+	}
+	e, err := engine.NewEngine(".data", opts...)
 	if err != nil {
 		fmt.Printf("%s❌ Error initializing engine: %v%s\n", colorRed, err, colorReset)
 		os.Exit(1)
 	}
 	defer e.Close()
+	session := e.NewSession()
 
 	// Print welcome message
 	fmt.Println(colorCyan + "┌─────────────────────────────────────┐" + colorReset)
@@ -65,14 +75,14 @@ func main() {
 		}
 
 		// Execute SQL
-		res, err := e.ExecSQL(input)
+		res, err := session.ExecSQL(input)
 		if err != nil {
-			fmt.Printf("%s❌ ERROR: %v%s\n\n", colorRed, err, colorReset)
-			continue
+			fmt.Printf("%s❌ ERROR: %v%s\n", colorRed, err, colorReset)
+		} else {
+			// Print formatted result
+			printResult(res)
 		}
-
-		// Print formatted result
-		printResult(res)
+		printNotifications(session)
 		fmt.Println()
 	}
 
@@ -102,6 +112,22 @@ func printResult(result any) {
 	}
 }
 
+// printNotifications prints the notifications received on channels the
+// session listens on
+func printNotifications(s *engine.Session) {
+	for {
+		select {
+		case n, ok := <-s.Notifications():
+			if !ok {
+				return
+			}
+			fmt.Printf("%s🔔 Asynchronous notification \"%s\" with payload \"%s\" received%s\n", colorCyan, n.Channel, n.Payload, colorReset)
+		default:
+			return
+		}
+	}
+}
+
 // printTable prints a slice of maps as a formatted table
 func printTable(rows []map[string]any) {
 	if len(rows) == 0 {
@@ -130,7 +156,7 @@ func printTable(rows []map[string]any) {
 	}
 	for _, row := range rows {
 		for _, col := range columns {
-			valStr := fmt.Sprintf("%v", row[col])
+			valStr := formatValue(row[col])
 			if len(valStr) > widths[col] {
 				widths[col] = len(valStr)
 			}
@@ -169,8 +195,7 @@ func printTable(rows []map[string]any) {
 	for _, row := range rows {
 		fmt.Print(colorBlue + "│" + colorReset)
 		for _, col := range columns {
-			val := row[col]
-			valStr := fmt.Sprintf("%v", val)
+			valStr := formatValue(row[col])
 			fmt.Printf(" %-*s ", widths[col], valStr)
 			fmt.Print(colorBlue + "│" + colorReset)
 		}
@@ -196,6 +221,14 @@ func printTable(rows []map[string]any) {
 	fmt.Printf("%s%d %s returned%s\n", colorGray, rowCount, rowWord, colorReset)
 }
 
+// formatValue renders a single cell, showing SQL NULL as NULL
+func formatValue(v any) string {
+	if v == nil {
+		return "NULL"
+	}
+	return fmt.Sprintf("%v", v)
+}
+
 // printOperationResult prints results from INSERT/UPDATE/DELETE operations
 func printOperationResult(result map[string]any) {
 	if rowid, ok := result["rowid"]; ok {
@@ -203,6 +236,21 @@ func printOperationResult(result map[string]any) {
 		return
 	}
 
+	if inserted, ok := result["inserted"]; ok {
+		count := inserted.(int)
+		rowWord := "row"
+		if count != 1 {
+			rowWord = "rows"
+		}
+		if updated, ok := result["updated"]; ok {
+			// INSERT ... ON CONFLICT
+			fmt.Printf("%s✅ %d %s inserted, %d updated%s\n", colorGreen, count, rowWord, updated.(int), colorReset)
+			return
+		}
+		fmt.Printf("%s✅ %d %s inserted%s\n", colorGreen, count, rowWord, colorReset)
+		return
+	}
+
 	if updated, ok := result["updated"]; ok {
 		count := updated.(int)
 		rowWord := "row"